@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/klog/v2"
+
+	configv1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/config/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/config"
+	"k8s.io/cel-admission-webhook/pkg/controller/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+	"k8s.io/cel-admission-webhook/pkg/validatingadmissionpolicy"
+	"k8s.io/cel-admission-webhook/pkg/validator"
+)
+
+// configFlags are the flags which the configuration file supersedes.
+var configFlags = map[string]bool{
+	"cert": true, "key": true, "addr": true,
+	"namespace": true, "service-account": true, "exempt-namespaces": true,
+	"cel-libraries": true, "cel-compatibility-version": true,
+	"cel-policy-cost-budget": true, "cel-request-cost-budget": true,
+	"lookup-resources": true, "redaction-rules": true,
+	"leader-elect": true, "report-replica-status": true,
+	"tracing-endpoint": true,
+}
+
+// loadConfig loads the configuration file if there is one, or builds the
+// configuration from the flags otherwise.
+func loadConfig(path string, fromFlags func() (*configv1alpha1.CELWebhookConfiguration, error)) (*configv1alpha1.CELWebhookConfiguration, error) {
+	if len(path) == 0 {
+		cfg, err := fromFlags()
+		if err != nil {
+			return nil, err
+		}
+		configv1alpha1.SetDefaults(cfg)
+		if errs := configv1alpha1.Validate(cfg); len(errs) > 0 {
+			return nil, errs.ToAggregate()
+		}
+		return cfg, nil
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if configFlags[f.Name] {
+			klog.Warningf("Ignoring -%s, which the configuration file %s supersedes", f.Name, path)
+		}
+	})
+	return config.Load(path)
+}
+
+// newConfig returns an empty configuration of the current version.
+func newConfig() *configv1alpha1.CELWebhookConfiguration {
+	cfg := &configv1alpha1.CELWebhookConfiguration{}
+	cfg.APIVersion = configv1alpha1.GroupName + "/" + configv1alpha1.Version
+	cfg.Kind = configv1alpha1.Kind
+	return cfg
+}
+
+// libraryNames returns the CEL libraries of the configuration, all of them
+// unless configured otherwise.
+func libraryNames(cfg *configv1alpha1.CELWebhookConfiguration, registry *webhookcel.Libraries) []string {
+	if cfg.CEL.Libraries == nil {
+		return registry.Registered()
+	}
+	return cfg.CEL.Libraries
+}
+
+// compatibilityVersion returns the CEL compatibility version of the
+// configuration, the latest one unless configured otherwise.
+func compatibilityVersion(cfg *configv1alpha1.CELWebhookConfiguration) webhookcel.EnvironmentVersion {
+	if cfg.CEL.CompatibilityVersion == 0 {
+		return webhookcel.CurrentEnvironmentVersion
+	}
+	return webhookcel.EnvironmentVersion(cfg.CEL.CompatibilityVersion)
+}
+
+// lookupResources parses the lookup resources of the configuration.
+func lookupResources(cfg *configv1alpha1.CELWebhookConfiguration) ([]schema.GroupVersionResource, error) {
+	var gvrs []schema.GroupVersionResource
+	for _, resource := range cfg.CEL.LookupResources {
+		gvr, err := webhookcel.ParseResource(resource)
+		if err != nil {
+			return nil, err
+		}
+		if err := webhookcel.ValidateLookupResource(gvr); err != nil {
+			return nil, err
+		}
+		gvrs = append(gvrs, gvr)
+	}
+	return gvrs, nil
+}
+
+// pluginSettings returns the reloadable settings of the plugin.
+func pluginSettings(cfg *configv1alpha1.CELWebhookConfiguration) v1alpha1.Settings {
+	namespaces := cfg.Exemptions.Namespaces
+	if namespaces == nil {
+		namespaces = v1alpha1.DefaultExemptNamespaces
+	}
+	exemptions := v1alpha1.NewExemptions(cfg.Exemptions.Namespace, cfg.Exemptions.ServiceAccount, namespaces...)
+	if len(cfg.Exemptions.Namespace) > 0 {
+		for _, object := range cfg.Exemptions.Objects {
+			exemptions.Objects = append(exemptions.Objects, v1alpha1.ExemptObject{
+				Resource:  schema.ParseGroupResource(object.Resource),
+				Namespace: cfg.Exemptions.Namespace,
+				Name:      object.Name,
+			})
+		}
+	}
+	if cfg.LeaderElection.Enabled {
+		exemptions.Objects = append(exemptions.Objects, v1alpha1.ExemptObject{
+			Resource:  schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"},
+			Namespace: cfg.LeaderElection.LeaseNamespace,
+			Name:      cfg.LeaderElection.LeaseName,
+		})
+	}
+	return v1alpha1.Settings{
+		Exemptions: exemptions,
+		CostBudgets: validatingadmissionpolicy.CostBudgets{
+			Policy:  cfg.CEL.PolicyCostBudget,
+			Request: cfg.CEL.RequestCostBudget,
+		},
+		SyncTimeout: cfg.Admission.SyncTimeout.Duration,
+	}
+}
+
+// configuredValidators returns the validators evaluating requests, with their
+// configured failure policies and timeouts, and the options to evaluate them.
+func configuredValidators(cfg *configv1alpha1.CELWebhookConfiguration, byName map[string]admission.ValidationInterface) (validator.Options, []validator.Validator) {
+	configured := map[string]configv1alpha1.ValidatorConfiguration{}
+	for _, v := range cfg.Admission.Validators {
+		configured[v.Name] = v
+	}
+	var result []validator.Validator
+	for _, name := range configv1alpha1.ValidatorNames {
+		v := validator.Validator{Name: name, ValidationInterface: byName[name], FailurePolicy: validator.Fail}
+		if c, ok := configured[name]; ok {
+			v.FailurePolicy = validator.FailurePolicy(c.FailurePolicy)
+			v.Timeout = c.Timeout.Duration
+		}
+		result = append(result, v)
+	}
+	return validator.Options{Parallel: *cfg.Admission.Parallel}, result
+}
+
+// redactionRules returns the configured redaction rules, in addition to the
+// default ones.
+func redactionRules(cfg *configv1alpha1.CELWebhookConfiguration) []redaction.Rule {
+	rules := append([]redaction.Rule{}, redaction.DefaultRules...)
+	for _, rule := range cfg.Redaction.Rules {
+		rules = append(rules, redaction.Rule{Resource: schema.ParseGroupResource(rule.Resource), Path: rule.Path})
+	}
+	return rules
+}
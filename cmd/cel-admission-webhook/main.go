@@ -4,44 +4,142 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apiextensionsclientsetscheme "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/scheme"
 	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
-	"k8s.io/apimachinery/pkg/api/meta"
-	"k8s.io/apimachinery/pkg/util/wait"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/admission"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/tracing"
+	tracingapi "k8s.io/component-base/tracing/api/v1"
 	"k8s.io/klog/v2"
 	aggregatorclientsetscheme "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/scheme"
 
+	admissionregistrationx "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	configv1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/config/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	cellibrary "k8s.io/cel-admission-webhook/pkg/cel/library"
+	"k8s.io/cel-admission-webhook/pkg/config"
+	"k8s.io/cel-admission-webhook/pkg/controller"
 	"k8s.io/cel-admission-webhook/pkg/controller/admissionregistration.x-k8s.io/v1alpha1"
 	"k8s.io/cel-admission-webhook/pkg/controller/schemaresolver"
+	"k8s.io/cel-admission-webhook/pkg/conversion"
 	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned"
 	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/scheme"
 	"k8s.io/cel-admission-webhook/pkg/generated/informers/externalversions"
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+	"k8s.io/cel-admission-webhook/pkg/replicastatus"
+	"k8s.io/cel-admission-webhook/pkg/restmapping"
+	"k8s.io/cel-admission-webhook/pkg/validatingadmissionpolicy"
 	"k8s.io/cel-admission-webhook/pkg/validator"
 	"k8s.io/cel-admission-webhook/pkg/webhook"
 )
 
 func main() {
+	var configFile string
 	var certFile, keyFile string
 	var listenAddr string
+	var namespace, serviceAccount, exemptNamespaces string
+	var lookupResourceList, celLibraries string
+	var redactionRuleList string
+	var tracingEndpoint string
+	var celCompatibilityVersion int
+	var leaderElect, reportReplicaStatus bool
+	costBudgets := validatingadmissionpolicy.DefaultCostBudgets
+	flag.StringVar(&configFile, "config", "", fmt.Sprintf("Path to a %s file. When set, it supersedes the flags it covers, and its reloadable settings are applied as the file changes.", configv1alpha1.Kind))
 	flag.StringVar(&certFile, "cert", "server.pem", "Path to TLS certificate file.")
 	flag.StringVar(&keyFile, "key", "server-key.pem", "Path to TLS key file.")
 	flag.StringVar(&listenAddr, "addr", "0.0.0.0:8443", "Address to listen on.")
+	flag.StringVar(&namespace, "namespace", os.Getenv("POD_NAMESPACE"), "Namespace the webhook runs in, and of its service account.")
+	flag.StringVar(&serviceAccount, "service-account", os.Getenv("POD_SERVICE_ACCOUNT"), "Service account the webhook runs as. Requests made by this service account are never evaluated against policies.")
+	flag.StringVar(&exemptNamespaces, "exempt-namespaces", strings.Join(v1alpha1.DefaultExemptNamespaces, ","), "Comma separated list of namespaces whose requests are never evaluated against policies.")
+	celLibraryRegistry := webhookcel.NewLibraries()
+	cellibrary.Register(celLibraryRegistry)
+	flag.StringVar(&celLibraries, "cel-libraries", strings.Join(celLibraryRegistry.Registered(), ","), fmt.Sprintf("Comma separated list of additional CEL libraries available to policies, out of %s.", strings.Join(celLibraryRegistry.Registered(), ", ")))
+	flag.IntVar(&celCompatibilityVersion, "cel-compatibility-version", int(webhookcel.CurrentEnvironmentVersion), "Version of the CEL environment new expressions of policies must compile in. While upgrading, set it to the latest version supported by the previous release, so that all replicas can evaluate new policies. Stored policies are always compiled in the latest version.")
+	flag.Int64Var(&costBudgets.Policy, "cel-policy-cost-budget", costBudgets.Policy, fmt.Sprintf("Runtime CEL cost budget of the evaluation of a policy for one of its bindings. Policies may override it with the %s annotation.", admissionregistrationx.CostBudgetAnnotation))
+	flag.Int64Var(&costBudgets.Request, "cel-request-cost-budget", costBudgets.Request, "Runtime CEL cost budget of the evaluation of all policies for a request. Policies evaluated once it is exhausted fail according to their failure policy. Zero means unlimited.")
+	flag.StringVar(&lookupResourceList, "lookup-resources", "", "Comma separated list of resources, as group/version/resource or version/resource, which policies may read with the lookup and list CEL functions. Each of them is cached in memory.")
+	flag.StringVar(&redactionRuleList, "redaction-rules", "", "Semicolon separated list of rules selecting sensitive fields of objects, as resource.group:{jsonpath}, such as configmaps:{.data.password}. Their values are redacted from logs, messages, warnings and audit annotations, in addition to the data of Secrets.")
+	flag.BoolVar(&leaderElect, "leader-elect", false, "Elect a leader among the replicas, which alone writes the status of policies and bindings. Every replica serves requests. The Lease is created in the namespace of the webhook.")
+	flag.BoolVar(&reportReplicaStatus, "report-replica-status", false, "Publish the policies loaded by each replica in a Lease of its own, and report on how many replicas each policy is loaded in its Loaded condition. The Leases are created in the namespace of the webhook.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "", "Address of an OTLP gRPC collector, such as localhost:4317, to export OpenTelemetry spans of the evaluation of reviews to. They are sampled as the spans of the API server propagated with reviews. Disabled if empty.")
 	flag.Parse()
 
+	cfg, err := loadConfig(configFile, func() (*configv1alpha1.CELWebhookConfiguration, error) {
+		cfg := newConfig()
+		cfg.Serving.Address = listenAddr
+		cfg.Serving.CertFile = certFile
+		cfg.Serving.KeyFile = keyFile
+		cfg.Exemptions.Namespace = namespace
+		cfg.Exemptions.ServiceAccount = serviceAccount
+		// Empty lists are kept empty, rather than defaulted.
+		cfg.Exemptions.Namespaces = append([]string{}, splitList(exemptNamespaces)...)
+		cfg.CEL.Libraries = append([]string{}, splitList(celLibraries)...)
+		cfg.CEL.CompatibilityVersion = celCompatibilityVersion
+		cfg.CEL.PolicyCostBudget = costBudgets.Policy
+		cfg.CEL.RequestCostBudget = costBudgets.Request
+		cfg.CEL.LookupResources = splitList(lookupResourceList)
+		cfg.LeaderElection.Enabled = leaderElect
+		cfg.ReplicaStatus.Enabled = reportReplicaStatus
+		if len(tracingEndpoint) > 0 {
+			cfg.Tracing = &tracingapi.TracingConfiguration{Endpoint: &tracingEndpoint}
+		}
+		rules, err := redaction.ParseRules(redactionRuleList)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			cfg.Redaction.Rules = append(cfg.Redaction.Rules, configv1alpha1.RedactionRule{Resource: rule.Resource.String(), Path: rule.Path})
+		}
+		return cfg, nil
+	})
+	if err != nil {
+		klog.Errorf("Invalid configuration: %v", err)
+		return
+	}
+
+	libraries, err := celLibraryRegistry.Get(libraryNames(cfg, celLibraryRegistry)...)
+	if err != nil {
+		klog.Errorf("Invalid CEL libraries: %v", err)
+		return
+	}
+
+	redactor, err := redaction.NewRedactor(redactionRules(cfg)...)
+	if err != nil {
+		klog.Errorf("Invalid redaction rules: %v", err)
+		return
+	}
+
+	if err := webhookcel.ValidateEnvironmentVersion(compatibilityVersion(cfg)); err != nil {
+		klog.Errorf("Invalid CEL compatibility version: %v", err)
+		return
+	}
+
+	lookupGVRs, err := lookupResources(cfg)
+	if err != nil {
+		klog.Errorf("Invalid lookup resources: %v", err)
+		return
+	}
+
 	klog.EnableContextualLogging(true)
 
 	// Handle SIGINT and SIGTERM by cancelling the root context
@@ -88,29 +186,74 @@ func main() {
 		return
 	}
 
+	// Without leader election, every replica writes to the cluster.
+	var leadership controller.Leadership
+	var leaderElector *controller.LeaderElector
+	if cfg.LeaderElection.Enabled {
+		leaderElector, err = controller.NewLeaderElector(unwrappedKubeClient, controller.LeaderElectionOptions{
+			Namespace:     cfg.LeaderElection.LeaseNamespace,
+			Name:          cfg.LeaderElection.LeaseName,
+			LeaseDuration: cfg.LeaderElection.LeaseDuration.Duration,
+			RenewDeadline: cfg.LeaderElection.RenewDeadline.Duration,
+			RetryPeriod:   cfg.LeaderElection.RetryPeriod.Duration,
+		})
+		if err != nil {
+			klog.Errorf("Failed to set up leader election: %v", err)
+			return
+		}
+		leadership = leaderElector
+	}
+
+	var tracerProvider tracing.TracerProvider
+	if cfg.Tracing != nil {
+		tracerProvider, err = tracing.NewProvider(ctx, cfg.Tracing, nil, []resource.Option{
+			resource.WithAttributes(semconv.ServiceNameKey.String("cel-admission-webhook")),
+		})
+		if err != nil {
+			klog.Errorf("Failed to set up tracing: %v", err)
+			return
+		}
+		defer func() {
+			// Flush the spans of the last reviews.
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+				klog.Errorf("Failed to export the remaining spans: %v", err)
+			}
+		}()
+	}
+
+	// The broadcaster aggregates similar Events and rate limits them per
+	// object, so that a controller retrying a denied request does not flood
+	// the cluster with Events.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: unwrappedKubeClient.CoreV1().Events("")})
+	defer eventBroadcaster.Shutdown()
+	recorder := eventBroadcaster.NewRecorder(clientsetscheme.Scheme, corev1.EventSource{Component: "cel-admission-webhook"})
+
 	// used to keep process alive until all workers are finished
 	waitGroup := sync.WaitGroup{}
 	serverContext, serverCancel := context.WithCancel(ctx)
 
 	// Start any informers
-	// What is appropriate resync perriod?
-	factory := informers.NewSharedInformerFactory(kubeClient, 30*time.Second)
-	customFactory := externalversions.NewSharedInformerFactory(customClient, 30*time.Second)
-	apiextensionsFactory := apiextensionsinformers.NewSharedInformerFactory(apiextensionsClient, 30*time.Second)
+	resyncPeriod := cfg.Informers.ResyncPeriod.Duration
+	factory := informers.NewSharedInformerFactory(kubeClient, resyncPeriod)
+	customFactory := externalversions.NewSharedInformerFactory(customClient, resyncPeriod)
+	apiextensionsFactory := apiextensionsinformers.NewSharedInformerFactory(apiextensionsClient, resyncPeriod)
+	dynamicFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resyncPeriod)
+	leaseFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, resyncPeriod,
+		informers.WithNamespace(cfg.ReplicaStatus.LeaseNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = replicastatus.ReplicaLabel + "=true"
+		}))
 
-	restmapper := meta.NewLazyRESTMapperLoader(func() (meta.RESTMapper, error) {
-		groupResources, err := restmapper.GetAPIGroupResources(kubeClient.Discovery())
-		if err != nil {
-			return nil, err
-		}
-		return restmapper.NewDiscoveryRESTMapper(groupResources), nil
-	}).(meta.ResettableRESTMapper)
+	// Discovery runs again when resources are installed or removed, or when
+	// requests reference resources which are not known yet.
+	restmapper := restmapping.New(kubeClient.Discovery(), apiextensionsFactory.Apiextensions().V1().CustomResourceDefinitions(), dynamicFactory.ForResource(restmapping.APIServicesResource))
 
-	go wait.PollUntilContextCancel(ctx, 1*time.Minute, false, func(ctx context.Context) (done bool, err error) {
-		// Refresh restmapper every minute
-		restmapper.Reset()
-		return false, nil
-	})
+	// Objects of requests are converted to the versions matched by policies,
+	// including the other served versions of custom resources.
+	objectInterfaces := conversion.NewObjectInterfaces(clientsetscheme.Scheme, restmapper, apiextensionsFactory.Apiextensions().V1().CustomResourceDefinitions())
 
 	// structuralschemaController := structuralschema.NewController(
 	// 	apiextensionsFactory.Apiextensions().V1().CustomResourceDefinitions().Informer(),
@@ -120,25 +263,89 @@ func main() {
 		Run(context.Context) error
 	}
 
-	validators := []admission.ValidationInterface{
-		v1alpha1.NewPlugin(factory, kubeClient, restmapper, schemaresolver.New(apiextensionsFactory.Apiextensions().V1().CustomResourceDefinitions(), kubeClient.Discovery()), dynamicClient, nil),
+	if len(lookupGVRs) > 0 {
+		libraries = append(libraries, webhookcel.NewLookupLibrary(factory, dynamicFactory, lookupGVRs))
 	}
 
+	schemaResolver := schemaresolver.New(apiextensionsFactory.Apiextensions().V1().CustomResourceDefinitions(), kubeClient.Discovery())
+
+	plugin := v1alpha1.NewPlugin(factory, kubeClient, customFactory, customClient, restmapper, schemaResolver, dynamicClient, nil,
+		libraries, compatibilityVersion(cfg), leadership, recorder, pluginSettings(cfg))
+	// Policies apply their own failure policies, so the plugin only fails
+	// when it panics, times out or returns an internal error.
+	validatorOptions, validators := configuredValidators(cfg, map[string]admission.ValidationInterface{
+		configv1alpha1.ValidatingAdmissionPolicyValidator: plugin,
+	})
+
+	controllers := []runnable{
+		v1alpha1.NewBindingStatusController(customFactory, customClient, recorder, leadership),
+		schemaResolver,
+	}
+	healthChecks := []webhook.HealthCheck{restmapper}
+	if leaderElector != nil {
+		controllers = append(controllers, leaderElector)
+		healthChecks = append(healthChecks, leaderElector)
+	}
+	if cfg.ReplicaStatus.Enabled {
+		publisher, err := replicastatus.NewPublisher(unwrappedKubeClient, cfg.ReplicaStatus.LeaseNamespace, cfg.ReplicaStatus.LeaseDuration.Duration, func() replicastatus.Generations {
+			policies, bindings := plugin.LoadedGenerations()
+			return replicastatus.Generations{Policies: policies, Bindings: bindings}
+		})
+		if err != nil {
+			klog.Errorf("Failed to set up the replica status: %v", err)
+			return
+		}
+		controllers = append(controllers, publisher, v1alpha1.NewPolicyReplicasController(
+			unwrappedKubeClient, leaseFactory.Coordination().V1().Leases(), cfg.ReplicaStatus.LeaseNamespace, customFactory, customClient, leadership))
+	}
+	if len(configFile) > 0 {
+		reloader := config.NewReloader(configFile, cfg, func(cfg *configv1alpha1.CELWebhookConfiguration) error {
+			if err := redactor.SetRules(redactionRules(cfg)...); err != nil {
+				return err
+			}
+			plugin.Reconfigure(pluginSettings(cfg))
+			return nil
+		})
+		controllers = append(controllers, reloader)
+		healthChecks = append(healthChecks, reloader)
+	}
 	for _, v := range validators {
-		if r, ok := v.(runnable); ok {
-			waitGroup.Add(1)
-			go func() {
-				err := r.Run(serverContext)
-				if err != nil {
-					klog.Errorf("worker stopped due to error: %v", err)
-				}
-				serverCancel()
-				waitGroup.Done()
-			}()
+		if r, ok := v.ValidationInterface.(runnable); ok {
+			controllers = append(controllers, r)
 		}
 	}
 
-	webhook := webhook.New(listenAddr, certFile, keyFile, clientsetscheme.Scheme, validator.NewMulti(validators...))
+	for _, r := range controllers {
+		r := r
+		waitGroup.Add(1)
+		go func() {
+			err := r.Run(serverContext)
+			if err != nil {
+				klog.Errorf("worker stopped due to error: %v", err)
+			}
+			serverCancel()
+			waitGroup.Done()
+		}()
+	}
+
+	webhook := webhook.New(webhook.Options{
+		Addr:             cfg.Serving.Address,
+		CertFile:         cfg.Serving.CertFile,
+		KeyFile:          cfg.Serving.KeyFile,
+		CertPollInterval: cfg.Serving.CertPollInterval.Duration,
+		ShutdownTimeout:  cfg.Serving.ShutdownTimeout.Duration,
+
+		RequestTimeout:      cfg.Serving.RequestTimeout.Duration,
+		ReadHeaderTimeout:   cfg.Serving.ReadHeaderTimeout.Duration,
+		ReadTimeout:         cfg.Serving.ReadTimeout.Duration,
+		WriteTimeout:        cfg.Serving.WriteTimeout.Duration,
+		MaxRequestBodyBytes: cfg.Serving.MaxRequestBodyBytes,
+		MaxInFlight:         int(cfg.Serving.MaxInFlight),
+		MaxQueued:           int(*cfg.Serving.MaxQueued),
+		QueueTimeout:        cfg.Serving.QueueTimeout.Duration,
+		OverloadPolicy:      webhook.OverloadPolicy(cfg.Serving.OverloadPolicy),
+		TracerProvider:      tracerProvider,
+	}, objectInterfaces, validator.New(validatorOptions, validators...), redactor, recorder, healthChecks...)
 
 	// Start HTTP REST server for webhook
 	waitGroup.Add(1)
@@ -157,6 +364,8 @@ func main() {
 	factory.Start(serverContext.Done())
 	apiextensionsFactory.Start(serverContext.Done())
 	customFactory.Start(serverContext.Done())
+	dynamicFactory.Start(serverContext.Done())
+	leaseFactory.Start(serverContext.Done())
 
 	// Wait for controller and HTTP server to stop. They both signal to the other's
 	// context that it is time to wrap up
@@ -164,6 +373,17 @@ func main() {
 	klog.Infof("exiting")
 }
 
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func loadClientConfig() (*rest.Config, error) {
 	// Connect to k8s
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
@@ -0,0 +1,31 @@
+package v1alpha1
+
+const (
+	// ShadowOfAnnotation marks a ValidatingAdmissionPolicy as a shadow of the
+	// policy named by the annotation value. A shadow policy is never enforced.
+	// Instead it is evaluated alongside the named policy, using that policy's
+	// bindings, and every request on which the two policies reach a different
+	// decision is logged and counted. The shadow is only evaluated where the
+	// named policy is: not for requests the named policy does not match, nor
+	// for bindings excepted by a PolicyException, nor while an
+	// EnforcementOverride disables the named policy.
+	ShadowOfAnnotation = GroupName + "/shadow-of"
+
+	// CostBudgetAnnotation overrides the runtime CEL cost budget of the
+	// evaluation of a policy for one of its bindings. The value is a positive
+	// integer. Namespaced policies may only lower their budget.
+	CostBudgetAnnotation = GroupName + "/cost-budget"
+
+	// ExplainAnnotation enables the explain mode for the evaluations of a
+	// ValidatingAdmissionPolicyBinding when set to "true". When a validation
+	// fails, the values of its sub-expressions are logged and returned as
+	// response warnings.
+	ExplainAnnotation = GroupName + "/explain"
+
+	// ExplainLabel enables the explain mode for a single request when set to
+	// "true" on the object of the request. Since the requester may not be
+	// allowed to read params or the objects looked up by policies, the
+	// explanations are only logged. Explaining evaluates the failed
+	// validations again, which is charged to the cost budget of the request.
+	ExplainLabel = GroupName + "/explain"
+)
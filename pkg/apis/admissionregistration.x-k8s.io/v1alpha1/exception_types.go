@@ -0,0 +1,91 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyException exempts selected requests from a ValidatingAdmissionPolicy,
+// without changing the policy or its bindings. A request is exempt if it
+// matches every criteria of the exception. Exceptions only ever apply to
+// requests in their own namespace, so that permission to create them in a
+// namespace never extends to other namespaces.
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced
+type PolicyException struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata; More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata.
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Specification of the desired behavior of the PolicyException.
+	Spec PolicyExceptionSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolicyExceptionList is a list of PolicyException.
+type PolicyExceptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// List of PolicyException.
+	Items []PolicyException `json:"items"`
+}
+
+// PolicyKind is the kind of policy a PolicyException applies to.
+type PolicyKind string
+
+const (
+	// ValidatingAdmissionPolicyKind is a cluster scoped ValidatingAdmissionPolicy.
+	ValidatingAdmissionPolicyKind PolicyKind = "ValidatingAdmissionPolicy"
+	// NamespacedValidatingAdmissionPolicyKind is a
+	// NamespacedValidatingAdmissionPolicy in the namespace of the exception.
+	NamespacedValidatingAdmissionPolicyKind PolicyKind = "NamespacedValidatingAdmissionPolicy"
+)
+
+// PolicyExceptionSpec is the specification of a PolicyException.
+type PolicyExceptionSpec struct {
+	// policyName references the policy the exception applies to, of
+	// policyKind.
+	// Required.
+	// +kubebuilder:validation:Required
+	PolicyName string `json:"policyName"`
+
+	// policyKind is the kind of the policy the exception applies to: a
+	// ValidatingAdmissionPolicy, or a NamespacedValidatingAdmissionPolicy in
+	// the namespace of the exception. An exception never applies to policies
+	// of the other kind of the same name.
+	//
+	// Allowed values are ValidatingAdmissionPolicy or
+	// NamespacedValidatingAdmissionPolicy. Defaults to
+	// ValidatingAdmissionPolicy.
+	// +optional
+	// +kubebuilder:default=ValidatingAdmissionPolicy
+	// +kubebuilder:validation:Enum=ValidatingAdmissionPolicy;NamespacedValidatingAdmissionPolicy
+	PolicyKind PolicyKind `json:"policyKind,omitempty"`
+
+	// bindingName references the binding of the policy the exception applies
+	// to. If empty, the exception applies to all bindings of the policy.
+	// +optional
+	BindingName string `json:"bindingName,omitempty"`
+
+	// resourceRules describe the operations on resources the exception
+	// applies to. If empty, the exception applies to all resources.
+	// +optional
+	// +listType=atomic
+	ResourceRules []NamedRuleWithOperations `json:"resourceRules,omitempty"`
+
+	// users are the names of the users whose requests the exception applies
+	// to. If empty, the exception applies to requests of all users.
+	// +optional
+	// +listType=set
+	Users []string `json:"users,omitempty"`
+
+	// expiresAt is the time after which the exception no longer applies. If
+	// unset, the exception never expires.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
@@ -0,0 +1,45 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// String returns a human readable form of the ParamKind, used in error
+// messages about the param of a policy.
+func (p *ParamKind) String() string {
+	if p == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%s, Kind=%s", p.APIVersion, p.Kind)
+}
+
+// EffectiveValidationActions returns the validationActions of the binding
+// which are in effect at the given time, along with the time at which the
+// next entry of the schedule takes effect. The returned time is nil if no
+// further entries are scheduled.
+func (s *ValidatingAdmissionPolicyBindingSpec) EffectiveValidationActions(now time.Time) ([]ValidationAction, *metav1.Time) {
+	actions := s.ValidationActions
+	var effectiveSince, next *metav1.Time
+	for i := range s.ValidationActionsSchedule {
+		entry := &s.ValidationActionsSchedule[i]
+		if entry.After.Time.After(now) {
+			if next == nil || entry.After.Before(next) {
+				next = &entry.After
+			}
+			continue
+		}
+		// The schedule is not required to be sorted, the latest entry which
+		// has already passed wins.
+		if effectiveSince == nil || !entry.After.Before(effectiveSince) {
+			effectiveSince = &entry.After
+			actions = entry.ValidationActions
+		}
+	}
+	if next != nil {
+		next = next.DeepCopy()
+	}
+	return actions, next
+}
@@ -0,0 +1,75 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEffectiveValidationActions(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	past := metav1.NewTime(now.Add(-time.Hour))
+	longAgo := metav1.NewTime(now.Add(-48 * time.Hour))
+	future := metav1.NewTime(now.Add(time.Hour))
+	farFuture := metav1.NewTime(now.Add(48 * time.Hour))
+
+	for _, testCase := range []struct {
+		name     string
+		spec     ValidatingAdmissionPolicyBindingSpec
+		expected []ValidationAction
+		next     *metav1.Time
+	}{
+		{
+			name:     "no-schedule",
+			spec:     ValidatingAdmissionPolicyBindingSpec{ValidationActions: []ValidationAction{Deny}},
+			expected: []ValidationAction{Deny},
+		},
+		{
+			name: "before-schedule",
+			spec: ValidatingAdmissionPolicyBindingSpec{
+				ValidationActions: []ValidationAction{Warn},
+				ValidationActionsSchedule: []ScheduledValidationActions{
+					{After: farFuture, ValidationActions: []ValidationAction{Deny}},
+					{After: future, ValidationActions: []ValidationAction{Warn, Audit}},
+				},
+			},
+			expected: []ValidationAction{Warn},
+			next:     &future,
+		},
+		{
+			name: "latest-passed-entry-wins",
+			spec: ValidatingAdmissionPolicyBindingSpec{
+				ValidationActions: []ValidationAction{Warn},
+				ValidationActionsSchedule: []ScheduledValidationActions{
+					{After: past, ValidationActions: []ValidationAction{Deny}},
+					{After: longAgo, ValidationActions: []ValidationAction{Audit}},
+					{After: future, ValidationActions: []ValidationAction{Warn}},
+				},
+			},
+			expected: []ValidationAction{Deny},
+			next:     &future,
+		},
+		{
+			name: "entry-takes-effect-at-its-time",
+			spec: ValidatingAdmissionPolicyBindingSpec{
+				ValidationActions: []ValidationAction{Warn},
+				ValidationActionsSchedule: []ScheduledValidationActions{
+					{After: metav1.NewTime(now), ValidationActions: []ValidationAction{Deny}},
+				},
+			},
+			expected: []ValidationAction{Deny},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			actions, next := testCase.spec.EffectiveValidationActions(now)
+			if !reflect.DeepEqual(actions, testCase.expected) {
+				t.Errorf("expected actions %v, got %v", testCase.expected, actions)
+			}
+			if !next.Equal(testCase.next) {
+				t.Errorf("expected next transition %v, got %v", testCase.next, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnforcementOverride is a break-glass switch which relaxes the enforcement
+// of all or selected ValidatingAdmissionPolicies without changing the
+// policies or their bindings. Overrides take effect as soon as the shim
+// observes them, and every decision which is affected by an override is
+// recorded in the audit annotations of the request.
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+type EnforcementOverride struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata; More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata.
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Specification of the desired behavior of the EnforcementOverride.
+	Spec EnforcementOverrideSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EnforcementOverrideList is a list of EnforcementOverride.
+type EnforcementOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// List of EnforcementOverride.
+	Items []EnforcementOverride `json:"items"`
+}
+
+// EnforcementOverrideSpec is the specification of an EnforcementOverride.
+type EnforcementOverrideSpec struct {
+	// policyNames are the names of the ValidatingAdmissionPolicies which are
+	// overridden. NamespacedValidatingAdmissionPolicies are named as
+	// namespace/name. If empty, all policies are overridden.
+	// +optional
+	// +listType=set
+	PolicyNames []string `json:"policyNames,omitempty"`
+
+	// validationActions replace the validationActions of every binding of the
+	// overridden policies. Only Warn and Audit may be used.
+	//
+	// If empty, the overridden policies are not evaluated at all.
+	//
+	// When several overrides apply to a policy, evaluation is disabled if any
+	// of them disables it, otherwise the union of their validationActions is
+	// used.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:XValidation:rule="self.all(action, action == 'Warn' || action == 'Audit')",message="only Warn and Audit may be used to override validationActions"
+	ValidationActions []ValidationAction `json:"validationActions,omitempty"`
+}
@@ -101,6 +101,54 @@ type ValidatingAdmissionPolicyStatus struct {
 	// +listType=map
 	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty" protobuf:"bytes,3,rep,name=conditions"`
+	// The versions of the CEL environment the expressions of the policy
+	// compile in.
+	// +optional
+	CELEnvironment *CELEnvironment `json:"celEnvironment,omitempty" protobuf:"bytes,4,opt,name=celEnvironment"`
+	// The expressions of the policy with the highest average runtime cost,
+	// as observed by the shim since the policy last changed. Updated
+	// periodically while the policy is evaluated.
+	// +optional
+	// +listType=atomic
+	ExpressionProfiles []ExpressionProfile `json:"expressionProfiles,omitempty" protobuf:"bytes,5,rep,name=expressionProfiles"`
+}
+
+const (
+	// PolicyConditionLoaded reports on how many replicas of the shim the
+	// current generation of a policy is loaded, with the message
+	// "Ready on N/M replicas". It is True once every replica loaded it.
+	PolicyConditionLoaded = "Loaded"
+)
+
+// ExpressionProfile summarizes the evaluations of an expression of a policy.
+type ExpressionProfile struct {
+	// The path to the expression in the policy, such as
+	// "spec.validations[0].expression".
+	FieldRef string `json:"fieldRef" protobuf:"bytes,1,opt,name=fieldRef"`
+	// The number of evaluations of the expression.
+	Evaluations int64 `json:"evaluations" protobuf:"varint,2,opt,name=evaluations"`
+	// The average runtime CEL cost of an evaluation.
+	AverageCost int64 `json:"averageCost" protobuf:"varint,3,opt,name=averageCost"`
+	// The highest runtime CEL cost of an evaluation.
+	MaxCost int64 `json:"maxCost" protobuf:"varint,4,opt,name=maxCost"`
+	// The average evaluation time.
+	AverageLatency metav1.Duration `json:"averageLatency" protobuf:"bytes,5,opt,name=averageLatency"`
+	// The longest evaluation time.
+	MaxLatency metav1.Duration `json:"maxLatency" protobuf:"bytes,6,opt,name=maxLatency"`
+}
+
+// CELEnvironment describes the versions of the CEL environment the
+// expressions of a policy compile in.
+type CELEnvironment struct {
+	// The version of the CEL environment the expressions were compiled in.
+	// Stored policies are always compiled in the latest version the shim
+	// supports.
+	Version int32 `json:"version" protobuf:"varint,1,opt,name=version"`
+	// The oldest version of the CEL environment all expressions compile in.
+	// Replicas of the shim which only support older versions fail to compile
+	// the policy. Unset if some expressions compile in no version.
+	// +optional
+	MinimumVersion int32 `json:"minimumVersion,omitempty" protobuf:"varint,2,opt,name=minimumVersion"`
 }
 
 // TypeChecking contains results of type checking the expressions in the
@@ -346,6 +394,7 @@ type AuditAnnotation struct {
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +k8s:prerelease-lifecycle-gen:introduced=1.26
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
 type ValidatingAdmissionPolicyBinding struct {
 	metav1.TypeMeta `json:",inline"`
@@ -354,6 +403,27 @@ type ValidatingAdmissionPolicyBinding struct {
 	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
 	// Specification of the desired behavior of the ValidatingAdmissionPolicyBinding.
 	Spec ValidatingAdmissionPolicyBindingSpec `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
+	// The status of the ValidatingAdmissionPolicyBinding.
+	// Populated by the system.
+	// Read-only.
+	// +optional
+	Status ValidatingAdmissionPolicyBindingStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+// ValidatingAdmissionPolicyBindingStatus represents the status of a ValidatingAdmissionPolicyBinding.
+type ValidatingAdmissionPolicyBindingStatus struct {
+	// The generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,1,opt,name=observedGeneration"`
+	// The validationActions currently enforced for the binding, taking
+	// validationActionsSchedule into account.
+	// +optional
+	// +listType=set
+	EffectiveValidationActions []ValidationAction `json:"effectiveValidationActions,omitempty" protobuf:"bytes,2,rep,name=effectiveValidationActions"`
+	// The time at which the next entry of validationActionsSchedule takes effect.
+	// Unset if no further entries are scheduled.
+	// +optional
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty" protobuf:"bytes,3,opt,name=nextTransitionTime"`
 }
 
 // ValidatingAdmissionPolicyBindingList is a list of ValidatingAdmissionPolicyBinding.
@@ -433,6 +503,34 @@ type ValidatingAdmissionPolicyBindingSpec struct {
 	// +kubebuilder:validation:Required
 	// +listType=set
 	ValidationActions []ValidationAction `json:"validationActions,omitempty" protobuf:"bytes,4,rep,name=validationActions"`
+
+	// validationActionsSchedule replaces validationActions from the given
+	// points in time, for example to warn about violations for a grace period
+	// before they are denied.
+	//
+	// The entry with the latest `after` time which has already passed is in
+	// effect. Before the first entry takes effect validationActions are used.
+	// The actions in effect are reported in status.effectiveValidationActions.
+	// +optional
+	// +listType=atomic
+	ValidationActionsSchedule []ScheduledValidationActions `json:"validationActionsSchedule,omitempty" protobuf:"bytes,5,rep,name=validationActionsSchedule"`
+}
+
+// ScheduledValidationActions declares the validationActions of a binding from
+// a point in time onwards.
+type ScheduledValidationActions struct {
+	// After is the time from which the validationActions are in effect.
+	// Required.
+	// +kubebuilder:validation:Required
+	After metav1.Time `json:"after" protobuf:"bytes,1,opt,name=after"`
+
+	// ValidationActions in effect from After until the next entry of the
+	// schedule takes effect. Supports the same values as the validationActions
+	// of the binding.
+	// Required.
+	// +kubebuilder:validation:Required
+	// +listType=set
+	ValidationActions []ValidationAction `json:"validationActions" protobuf:"bytes,2,rep,name=validationActions"`
 }
 
 // ParamRef references a parameter resource
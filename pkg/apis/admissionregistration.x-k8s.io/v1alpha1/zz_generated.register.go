@@ -58,6 +58,14 @@ func init() {
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&EnforcementOverride{},
+		&EnforcementOverrideList{},
+		&NamespacedValidatingAdmissionPolicy{},
+		&NamespacedValidatingAdmissionPolicyBinding{},
+		&NamespacedValidatingAdmissionPolicyBindingList{},
+		&NamespacedValidatingAdmissionPolicyList{},
+		&PolicyException{},
+		&PolicyExceptionList{},
 		&ValidatingAdmissionPolicy{},
 		&ValidatingAdmissionPolicyBinding{},
 		&ValidatingAdmissionPolicyBindingList{},
@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+)
+
+// SetDefaults sets the defaults of the unset fields of the configuration.
+// Defaults which depend on the build of the webhook, such as its CEL
+// libraries, are left to the webhook.
+func SetDefaults(c *CELWebhookConfiguration) {
+	if c.Exemptions.Objects == nil {
+		c.Exemptions.Objects = []ExemptObject{
+			{Resource: "deployments.apps", Name: "cel-shim-webhook"},
+			{Resource: "services", Name: "cel-shim-webhook"},
+			{Resource: "secrets", Name: "cel-shim-webhook"},
+			{Resource: "secrets", Name: "cel-shim-webhook.tls.example.com"},
+		}
+	}
+	if len(c.Serving.Address) == 0 {
+		c.Serving.Address = "0.0.0.0:8443"
+	}
+	if len(c.Serving.CertFile) == 0 {
+		c.Serving.CertFile = "server.pem"
+	}
+	if len(c.Serving.KeyFile) == 0 {
+		c.Serving.KeyFile = "server-key.pem"
+	}
+	setDefaultDuration(&c.Serving.CertPollInterval, 2*time.Second)
+	setDefaultDuration(&c.Serving.ShutdownTimeout, 5*time.Second)
+	setDefaultDuration(&c.Serving.RequestTimeout, 10*time.Second)
+	setDefaultDuration(&c.Serving.ReadHeaderTimeout, 10*time.Second)
+	setDefaultDuration(&c.Serving.ReadTimeout, 30*time.Second)
+	setDefaultDuration(&c.Serving.WriteTimeout, 30*time.Second)
+	if c.Serving.MaxRequestBodyBytes == 0 {
+		c.Serving.MaxRequestBodyBytes = 16 << 20
+	}
+	if c.Serving.MaxInFlight == 0 {
+		c.Serving.MaxInFlight = 100
+	}
+	if c.Serving.MaxQueued == nil {
+		maxQueued := int32(100)
+		c.Serving.MaxQueued = &maxQueued
+	}
+	setDefaultDuration(&c.Serving.QueueTimeout, time.Second)
+	if len(c.Serving.OverloadPolicy) == 0 {
+		c.Serving.OverloadPolicy = "Reject"
+	}
+	setDefaultDuration(&c.Informers.ResyncPeriod, 30*time.Second)
+	setDefaultDuration(&c.Admission.SyncTimeout, time.Second)
+	if c.Admission.Parallel == nil {
+		parallel := true
+		c.Admission.Parallel = &parallel
+	}
+	for i := range c.Admission.Validators {
+		if len(c.Admission.Validators[i].FailurePolicy) == 0 {
+			c.Admission.Validators[i].FailurePolicy = "Fail"
+		}
+	}
+	if c.CEL.PolicyCostBudget == 0 {
+		c.CEL.PolicyCostBudget = celconfig.RuntimeCELCostBudget
+	}
+	if len(c.LeaderElection.LeaseName) == 0 {
+		c.LeaderElection.LeaseName = "cel-admission-webhook"
+	}
+	if len(c.LeaderElection.LeaseNamespace) == 0 {
+		c.LeaderElection.LeaseNamespace = c.Exemptions.Namespace
+	}
+	setDefaultDuration(&c.LeaderElection.LeaseDuration, 15*time.Second)
+	setDefaultDuration(&c.LeaderElection.RenewDeadline, 10*time.Second)
+	setDefaultDuration(&c.LeaderElection.RetryPeriod, 2*time.Second)
+	if len(c.ReplicaStatus.LeaseNamespace) == 0 {
+		c.ReplicaStatus.LeaseNamespace = c.Exemptions.Namespace
+	}
+	setDefaultDuration(&c.ReplicaStatus.LeaseDuration, 30*time.Second)
+}
+
+func setDefaultDuration(d *metav1.Duration, value time.Duration) {
+	if d.Duration == 0 {
+		d.Duration = value
+	}
+}
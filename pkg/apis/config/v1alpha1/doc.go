@@ -0,0 +1,5 @@
+// Package v1alpha1 is the configuration API of the webhook, read from the file
+// given with the -config flag.
+//
+// +groupName=config.cel-admission-webhook.x-k8s.io
+package v1alpha1
@@ -0,0 +1,338 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	tracingapi "k8s.io/component-base/tracing/api/v1"
+)
+
+const (
+	// GroupName is the group of the configuration API.
+	GroupName = "config.cel-admission-webhook.x-k8s.io"
+	// Version is the version of the configuration API.
+	Version = "v1alpha1"
+	// Kind is the kind of the configuration file.
+	Kind = "CELWebhookConfiguration"
+)
+
+// CELWebhookConfiguration configures the webhook.
+//
+// Fields documented as reloadable take effect for the requests handled after
+// the file changes. A change to any other field only takes effect once the
+// webhook restarts, which it reports in its logs, on its health endpoint and
+// with the cel_admission_webhook_config_restart_required metric.
+type CELWebhookConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Serving configures the HTTPS server of the webhook.
+	// +optional
+	Serving ServingConfiguration `json:"serving,omitempty"`
+
+	// Informers configures the caches of the webhook.
+	// +optional
+	Informers InformersConfiguration `json:"informers,omitempty"`
+
+	// Admission configures the evaluation of requests. Reloadable.
+	// +optional
+	Admission AdmissionConfiguration `json:"admission,omitempty"`
+
+	// Exemptions configures the requests which are admitted without
+	// evaluating any policy. Reloadable.
+	// +optional
+	Exemptions ExemptionsConfiguration `json:"exemptions,omitempty"`
+
+	// CEL configures the CEL environment of policies.
+	// +optional
+	CEL CELConfiguration `json:"cel,omitempty"`
+
+	// Redaction configures the fields of objects whose values are redacted
+	// from logs, messages, warnings and audit annotations. Reloadable.
+	// +optional
+	Redaction RedactionConfiguration `json:"redaction,omitempty"`
+
+	// LeaderElection configures the election of the replica which writes the
+	// status of policies and bindings. Every replica serves requests.
+	// +optional
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+
+	// ReplicaStatus configures the report of the replicas which loaded the
+	// current generation of each policy.
+	// +optional
+	ReplicaStatus ReplicaStatusConfiguration `json:"replicaStatus,omitempty"`
+
+	// Tracing exports OpenTelemetry spans of the evaluation of reviews to an
+	// OTLP collector. The spans are children of those of the API server,
+	// whose sampling decision they follow unless samplingRatePerMillion is
+	// set. Disabled if unset.
+	// +optional
+	Tracing *tracingapi.TracingConfiguration `json:"tracing,omitempty"`
+}
+
+// ServingConfiguration configures the HTTPS server of the webhook.
+type ServingConfiguration struct {
+	// Address to listen on. Defaults to 0.0.0.0:8443.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// CertFile is the path to the TLS certificate. Defaults to server.pem.
+	// +optional
+	CertFile string `json:"certFile,omitempty"`
+
+	// KeyFile is the path to the TLS key. Defaults to server-key.pem.
+	// +optional
+	KeyFile string `json:"keyFile,omitempty"`
+
+	// CertPollInterval is the interval at which the certificate and key are
+	// checked for changes, upon which the server restarts. Defaults to 2s.
+	// +optional
+	CertPollInterval metav1.Duration `json:"certPollInterval,omitempty"`
+
+	// ShutdownTimeout bounds the graceful shutdown of the server when the
+	// certificate changes. Defaults to 5s.
+	// +optional
+	ShutdownTimeout metav1.Duration `json:"shutdownTimeout,omitempty"`
+
+	// RequestTimeout bounds the evaluation of a review. The API server sends
+	// the timeoutSeconds of the webhook with each review, which bounds it as
+	// well. A tenth of the timeout is reserved for the response to reach the
+	// API server in time. Defaults to 10s.
+	// +optional
+	RequestTimeout metav1.Duration `json:"requestTimeout,omitempty"`
+
+	// ReadHeaderTimeout bounds the reading of the headers of a request.
+	// Defaults to 10s.
+	// +optional
+	ReadHeaderTimeout metav1.Duration `json:"readHeaderTimeout,omitempty"`
+
+	// ReadTimeout bounds the reading of a request. Defaults to 30s.
+	// +optional
+	ReadTimeout metav1.Duration `json:"readTimeout,omitempty"`
+
+	// WriteTimeout bounds the handling of a request and the writing of its
+	// response. Defaults to 30s, the longest timeout of a webhook.
+	// +optional
+	WriteTimeout metav1.Duration `json:"writeTimeout,omitempty"`
+
+	// MaxRequestBodyBytes is the maximum size of an AdmissionReview. Larger
+	// ones are rejected with 413 Request Entity Too Large. Defaults to 16Mi.
+	// +optional
+	MaxRequestBodyBytes int64 `json:"maxRequestBodyBytes,omitempty"`
+
+	// MaxInFlight is the maximum number of reviews evaluated concurrently.
+	// Defaults to 100.
+	// +optional
+	MaxInFlight int32 `json:"maxInFlight,omitempty"`
+
+	// MaxQueued is the maximum number of reviews waiting for the evaluation
+	// of others to complete. Defaults to 100.
+	// +optional
+	MaxQueued *int32 `json:"maxQueued,omitempty"`
+
+	// QueueTimeout is how long a review waits in the queue. Defaults to 1s.
+	// +optional
+	QueueTimeout metav1.Duration `json:"queueTimeout,omitempty"`
+
+	// OverloadPolicy is how the reviews which do not fit in the queue, or
+	// wait in it for longer than queueTimeout, are answered: Reject answers
+	// with 429 Too Many Requests, upon which the API server applies the
+	// failurePolicy of the webhook, while Allow admits the request with a
+	// warning. Defaults to Reject.
+	// +optional
+	OverloadPolicy string `json:"overloadPolicy,omitempty"`
+}
+
+// InformersConfiguration configures the caches of the webhook.
+type InformersConfiguration struct {
+	// ResyncPeriod of the informers. Defaults to 30s.
+	// +optional
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+}
+
+// AdmissionConfiguration configures the evaluation of requests.
+type AdmissionConfiguration struct {
+	// SyncTimeout is how long a request waits for the policies to be loaded
+	// after the webhook starts, before it is denied. Defaults to 1s.
+	// +optional
+	SyncTimeout metav1.Duration `json:"syncTimeout,omitempty"`
+
+	// Parallel evaluates the validators of a request concurrently rather
+	// than in order. Defaults to true.
+	// +optional
+	Parallel *bool `json:"parallel,omitempty"`
+
+	// Validators configure how the failures of the validators evaluating
+	// requests are handled, by the name of the validator. Validators which
+	// are not listed fail requests when they fail, and are only bounded by
+	// the deadline of the request.
+	// +optional
+	Validators []ValidatorConfiguration `json:"validators,omitempty"`
+}
+
+// ValidatorNames are the names of the validators evaluating requests.
+var ValidatorNames = []string{ValidatingAdmissionPolicyValidator}
+
+// ValidatingAdmissionPolicyValidator is the name of the validator which
+// evaluates ValidatingAdmissionPolicies and their bindings.
+const ValidatingAdmissionPolicyValidator = "validating-admission-policy"
+
+// ValidatorConfiguration configures a validator evaluating requests.
+type ValidatorConfiguration struct {
+	// Name of the validator, one of ValidatorNames.
+	Name string `json:"name"`
+
+	// FailurePolicy is how the failures of the validator, as opposed to its
+	// denials, are handled: Fail denies the request, Ignore admits it as if
+	// the validator had not been evaluated. Failures are internal errors,
+	// panics and timeouts. Defaults to Fail.
+	// +optional
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+
+	// Timeout bounds the evaluation of the validator. Zero means the
+	// deadline of the request only.
+	// +optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// ExemptionsConfiguration configures the requests which are admitted without
+// evaluating any policy.
+type ExemptionsConfiguration struct {
+	// Namespace the webhook runs in, and of its ServiceAccount. Requests in
+	// this namespace are only exempt if it is listed in Namespaces. Defaults
+	// to the POD_NAMESPACE environment variable.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// ServiceAccount the webhook runs as, in its namespace. Requests made
+	// by this service account are exempt. Defaults to the
+	// POD_SERVICE_ACCOUNT environment variable.
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+
+	// Namespaces whose requests are exempt. Defaults to the namespaces of the
+	// control plane.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Objects of the webhook in Namespace, such as its Deployment and the
+	// Secret of its certificate, whose requests are exempt so that policies
+	// never prevent updating the webhook. Defaults to the objects of the
+	// manifests of the webhook. The Lease of the leader election is always
+	// exempt.
+	// +optional
+	Objects []ExemptObject `json:"objects,omitempty"`
+}
+
+// ExemptObject is an object of the webhook whose requests are exempt.
+type ExemptObject struct {
+	// Resource of the object, as resource.group, such as secrets or
+	// deployments.apps.
+	Resource string `json:"resource"`
+
+	// Name of the object.
+	Name string `json:"name"`
+}
+
+// CELConfiguration configures the CEL environment of policies.
+type CELConfiguration struct {
+	// Libraries are the additional CEL libraries available to policies.
+	// Defaults to all of them.
+	// +optional
+	Libraries []string `json:"libraries,omitempty"`
+
+	// CompatibilityVersion is the version of the CEL environment new
+	// expressions of policies must compile in. Defaults to the latest one.
+	// +optional
+	CompatibilityVersion int `json:"compatibilityVersion,omitempty"`
+
+	// PolicyCostBudget is the runtime cost budget of the evaluation of a
+	// policy for one of its bindings. Defaults to the budget of the
+	// Kubernetes API server. Reloadable.
+	// +optional
+	PolicyCostBudget int64 `json:"policyCostBudget,omitempty"`
+
+	// RequestCostBudget is the runtime cost budget of the evaluation of all
+	// policies for a request. Zero means unlimited. Reloadable.
+	// +optional
+	RequestCostBudget int64 `json:"requestCostBudget,omitempty"`
+
+	// LookupResources are the resources, as group/version/resource or
+	// version/resource, which policies may read with the lookup and list
+	// CEL functions. Secrets may not be read, their data would escape
+	// redaction.
+	// +optional
+	LookupResources []string `json:"lookupResources,omitempty"`
+}
+
+// RedactionConfiguration configures the sensitive fields of objects. The
+// data of Secrets is always redacted.
+type RedactionConfiguration struct {
+	// Rules select the sensitive fields of objects.
+	// +optional
+	Rules []RedactionRule `json:"rules,omitempty"`
+}
+
+// RedactionRule selects the sensitive fields of the objects of a resource.
+type RedactionRule struct {
+	// Resource of the objects, as resource.group, such as configmaps or
+	// widgets.example.com. Empty selects every resource.
+	// +optional
+	Resource string `json:"resource,omitempty"`
+
+	// Path is a JSONPath template selecting the sensitive fields, such as
+	// {.data.password}.
+	Path string `json:"path"`
+}
+
+// LeaderElectionConfiguration configures the election of the leader among the
+// replicas of the webhook, with a coordination.k8s.io Lease.
+type LeaderElectionConfiguration struct {
+	// Enabled elects a leader among the replicas. Otherwise every replica
+	// writes the status of policies and bindings.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// LeaseName is the name of the Lease. Defaults to cel-admission-webhook.
+	// +optional
+	LeaseName string `json:"leaseName,omitempty"`
+
+	// LeaseNamespace is the namespace of the Lease. Defaults to the namespace
+	// of the exemptions, which is the namespace the webhook runs in.
+	// +optional
+	LeaseNamespace string `json:"leaseNamespace,omitempty"`
+
+	// LeaseDuration is how long the other replicas wait before they take over
+	// the lease of a leader which stopped renewing it. Defaults to 15s.
+	// +optional
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// RenewDeadline is how long the leader tries to renew its lease before it
+	// stops leading. Defaults to 10s.
+	// +optional
+	RenewDeadline metav1.Duration `json:"renewDeadline,omitempty"`
+
+	// RetryPeriod is the interval between attempts to acquire or renew the
+	// lease. Defaults to 2s.
+	// +optional
+	RetryPeriod metav1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// ReplicaStatusConfiguration configures the report of the replicas which
+// loaded the current generation of each policy.
+type ReplicaStatusConfiguration struct {
+	// Enabled publishes the generations of the policies and bindings loaded
+	// by each replica in a Lease of its own, and reports on how many replicas
+	// the current generation of each policy is loaded in its Loaded
+	// condition.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// LeaseNamespace is the namespace of the Leases. Defaults to the
+	// namespace of the exemptions, which is the namespace the webhook runs
+	// in.
+	// +optional
+	LeaseNamespace string `json:"leaseNamespace,omitempty"`
+
+	// LeaseDuration is how long a replica which stopped renewing its Lease
+	// is still counted. Defaults to 30s.
+	// +optional
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+}
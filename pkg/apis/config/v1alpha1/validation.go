@@ -0,0 +1,133 @@
+package v1alpha1
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/jsonpath"
+	tracingapi "k8s.io/component-base/tracing/api/v1"
+)
+
+// Validate validates a defaulted configuration.
+func Validate(c *CELWebhookConfiguration) field.ErrorList {
+	var errs field.ErrorList
+	if c.APIVersion != GroupName+"/"+Version {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{GroupName + "/" + Version}))
+	}
+	if c.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+
+	serving := field.NewPath("serving")
+	errs = append(errs, validatePositiveDuration(serving.Child("certPollInterval"), c.Serving.CertPollInterval.Duration)...)
+	errs = append(errs, validatePositiveDuration(serving.Child("shutdownTimeout"), c.Serving.ShutdownTimeout.Duration)...)
+	errs = append(errs, validatePositiveDuration(serving.Child("requestTimeout"), c.Serving.RequestTimeout.Duration)...)
+	errs = append(errs, validatePositiveDuration(serving.Child("readHeaderTimeout"), c.Serving.ReadHeaderTimeout.Duration)...)
+	errs = append(errs, validatePositiveDuration(serving.Child("readTimeout"), c.Serving.ReadTimeout.Duration)...)
+	errs = append(errs, validatePositiveDuration(serving.Child("writeTimeout"), c.Serving.WriteTimeout.Duration)...)
+	if c.Serving.MaxRequestBodyBytes <= 0 {
+		errs = append(errs, field.Invalid(serving.Child("maxRequestBodyBytes"), c.Serving.MaxRequestBodyBytes, "must be positive"))
+	}
+	if c.Serving.MaxInFlight <= 0 {
+		errs = append(errs, field.Invalid(serving.Child("maxInFlight"), c.Serving.MaxInFlight, "must be positive"))
+	}
+	if c.Serving.MaxQueued != nil && *c.Serving.MaxQueued < 0 {
+		errs = append(errs, field.Invalid(serving.Child("maxQueued"), *c.Serving.MaxQueued, "must not be negative"))
+	}
+	errs = append(errs, validatePositiveDuration(serving.Child("queueTimeout"), c.Serving.QueueTimeout.Duration)...)
+	if c.Serving.OverloadPolicy != "Reject" && c.Serving.OverloadPolicy != "Allow" {
+		errs = append(errs, field.NotSupported(serving.Child("overloadPolicy"), c.Serving.OverloadPolicy, []string{"Reject", "Allow"}))
+	}
+	errs = append(errs, validatePositiveDuration(field.NewPath("informers", "resyncPeriod"), c.Informers.ResyncPeriod.Duration)...)
+	errs = append(errs, validatePositiveDuration(field.NewPath("admission", "syncTimeout"), c.Admission.SyncTimeout.Duration)...)
+	validators := field.NewPath("admission", "validators")
+	names := sets.New[string]()
+	for i, v := range c.Admission.Validators {
+		path := validators.Index(i)
+		if !sets.New(ValidatorNames...).Has(v.Name) {
+			errs = append(errs, field.NotSupported(path.Child("name"), v.Name, ValidatorNames))
+		} else if names.Has(v.Name) {
+			errs = append(errs, field.Duplicate(path.Child("name"), v.Name))
+		}
+		names.Insert(v.Name)
+		if v.FailurePolicy != "Fail" && v.FailurePolicy != "Ignore" {
+			errs = append(errs, field.NotSupported(path.Child("failurePolicy"), v.FailurePolicy, []string{"Fail", "Ignore"}))
+		}
+		if v.Timeout.Duration < 0 {
+			errs = append(errs, field.Invalid(path.Child("timeout"), v.Timeout.Duration.String(), "must not be negative"))
+		}
+	}
+
+	exemptions := field.NewPath("exemptions")
+	if len(c.Exemptions.ServiceAccount) > 0 && len(c.Exemptions.Namespace) == 0 {
+		errs = append(errs, field.Required(exemptions.Child("namespace"), "the namespace of the service account is required"))
+	}
+	for i, namespace := range c.Exemptions.Namespaces {
+		if len(namespace) == 0 {
+			errs = append(errs, field.Required(exemptions.Child("namespaces").Index(i), "an empty namespace would exempt every cluster scoped request"))
+		}
+	}
+	for i, object := range c.Exemptions.Objects {
+		if len(object.Resource) == 0 {
+			errs = append(errs, field.Required(exemptions.Child("objects").Index(i).Child("resource"), ""))
+		}
+		if len(object.Name) == 0 {
+			errs = append(errs, field.Required(exemptions.Child("objects").Index(i).Child("name"), ""))
+		}
+	}
+
+	cel := field.NewPath("cel")
+	if c.CEL.CompatibilityVersion < 0 {
+		errs = append(errs, field.Invalid(cel.Child("compatibilityVersion"), c.CEL.CompatibilityVersion, "must not be negative"))
+	}
+	if c.CEL.PolicyCostBudget <= 0 {
+		errs = append(errs, field.Invalid(cel.Child("policyCostBudget"), c.CEL.PolicyCostBudget, "must be positive"))
+	}
+	if c.CEL.RequestCostBudget < 0 {
+		errs = append(errs, field.Invalid(cel.Child("requestCostBudget"), c.CEL.RequestCostBudget, "must not be negative"))
+	}
+
+	rules := field.NewPath("redaction", "rules")
+	for i, rule := range c.Redaction.Rules {
+		if len(rule.Path) == 0 {
+			errs = append(errs, field.Required(rules.Index(i).Child("path"), ""))
+		} else if err := jsonpath.New("").Parse(rule.Path); err != nil {
+			errs = append(errs, field.Invalid(rules.Index(i).Child("path"), rule.Path, err.Error()))
+		}
+	}
+
+	leaderElection := field.NewPath("leaderElection")
+	if c.LeaderElection.Enabled {
+		if len(c.LeaderElection.LeaseNamespace) == 0 {
+			errs = append(errs, field.Required(leaderElection.Child("leaseNamespace"), "required unless exemptions.namespace is set"))
+		}
+		errs = append(errs, validatePositiveDuration(leaderElection.Child("retryPeriod"), c.LeaderElection.RetryPeriod.Duration)...)
+		if c.LeaderElection.RenewDeadline.Duration <= c.LeaderElection.RetryPeriod.Duration {
+			errs = append(errs, field.Invalid(leaderElection.Child("renewDeadline"), c.LeaderElection.RenewDeadline.Duration.String(), "must be greater than retryPeriod"))
+		}
+		if c.LeaderElection.LeaseDuration.Duration <= c.LeaderElection.RenewDeadline.Duration {
+			errs = append(errs, field.Invalid(leaderElection.Child("leaseDuration"), c.LeaderElection.LeaseDuration.Duration.String(), "must be greater than renewDeadline"))
+		}
+	}
+
+	replicaStatus := field.NewPath("replicaStatus")
+	if c.ReplicaStatus.Enabled {
+		if len(c.ReplicaStatus.LeaseNamespace) == 0 {
+			errs = append(errs, field.Required(replicaStatus.Child("leaseNamespace"), "required unless exemptions.namespace is set"))
+		}
+		if c.ReplicaStatus.LeaseDuration.Duration < 3*time.Second {
+			errs = append(errs, field.Invalid(replicaStatus.Child("leaseDuration"), c.ReplicaStatus.LeaseDuration.Duration.String(), "must be at least 3s"))
+		}
+	}
+
+	errs = append(errs, tracingapi.ValidateTracingConfiguration(c.Tracing, nil, field.NewPath("tracing"))...)
+	return errs
+}
+
+func validatePositiveDuration(path *field.Path, d time.Duration) field.ErrorList {
+	if d <= 0 {
+		return field.ErrorList{field.Invalid(path, d.String(), "must be positive")}
+	}
+	return nil
+}
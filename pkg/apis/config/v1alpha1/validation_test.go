@@ -0,0 +1,138 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	tracingapi "k8s.io/component-base/tracing/api/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *CELWebhookConfiguration {
+		c := &CELWebhookConfiguration{TypeMeta: metav1.TypeMeta{APIVersion: GroupName + "/" + Version, Kind: Kind}}
+		SetDefaults(c)
+		return c
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		mutate   func(*CELWebhookConfiguration)
+		expected []string
+	}{
+		{
+			name:   "defaults",
+			mutate: func(c *CELWebhookConfiguration) {},
+		},
+		{
+			name: "wrong-kind",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.APIVersion = "v1"
+				c.Kind = "ConfigMap"
+			},
+			expected: []string{"apiVersion", "kind"},
+		},
+		{
+			name: "negative-durations",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.Serving.CertPollInterval.Duration = -1
+				c.Serving.RequestTimeout.Duration = -1
+				c.Admission.SyncTimeout.Duration = -1
+			},
+			expected: []string{"serving.certPollInterval", "serving.requestTimeout", "admission.syncTimeout"},
+		},
+		{
+			name: "overload",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.Serving.MaxInFlight = -1
+				c.Serving.OverloadPolicy = "Drop"
+			},
+			expected: []string{"serving.maxInFlight", "serving.overloadPolicy"},
+		},
+		{
+			name: "validators",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.Admission.Validators = []ValidatorConfiguration{
+					{Name: ValidatingAdmissionPolicyValidator, FailurePolicy: "Ignore", Timeout: metav1.Duration{Duration: time.Second}},
+					{Name: ValidatingAdmissionPolicyValidator, FailurePolicy: "Fail", Timeout: metav1.Duration{Duration: -1}},
+					{Name: "unknown", FailurePolicy: "Retry"},
+				}
+			},
+			expected: []string{"admission.validators[1].name", "admission.validators[1].timeout", "admission.validators[2].name", "admission.validators[2].failurePolicy"},
+		},
+		{
+			name: "service-account-without-namespace",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.Exemptions.ServiceAccount = "cel-webhook"
+			},
+			expected: []string{"exemptions.namespace"},
+		},
+		{
+			name: "empty-exemptions",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.Exemptions.Namespaces = []string{"kube-system", ""}
+				c.Exemptions.Objects = []ExemptObject{{Resource: "secrets"}, {Name: "example"}}
+			},
+			expected: []string{"exemptions.namespaces[1]", "exemptions.objects[0].name", "exemptions.objects[1].resource"},
+		},
+		{
+			name: "cost-budgets",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.CEL.PolicyCostBudget = -1
+				c.CEL.RequestCostBudget = -1
+			},
+			expected: []string{"cel.policyCostBudget", "cel.requestCostBudget"},
+		},
+		{
+			name: "redaction-rules",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.Redaction.Rules = []RedactionRule{
+					{Resource: "configmaps", Path: "{.data.password}"},
+					{Resource: "configmaps"},
+					{Path: "{.data["},
+				}
+			},
+			expected: []string{"redaction.rules[1].path", "redaction.rules[2].path"},
+		},
+		{
+			name: "leader-election",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.LeaderElection.Enabled = true
+				c.LeaderElection.LeaseNamespace = ""
+				c.LeaderElection.RenewDeadline = c.LeaderElection.LeaseDuration
+			},
+			expected: []string{"leaderElection.leaseNamespace", "leaderElection.leaseDuration"},
+		},
+		{
+			name: "replica-status",
+			mutate: func(c *CELWebhookConfiguration) {
+				c.ReplicaStatus.Enabled = true
+				c.ReplicaStatus.LeaseNamespace = ""
+				c.ReplicaStatus.LeaseDuration.Duration = time.Second
+			},
+			expected: []string{"replicaStatus.leaseNamespace", "replicaStatus.leaseDuration"},
+		},
+		{
+			name: "tracing",
+			mutate: func(c *CELWebhookConfiguration) {
+				endpoint, rate := "https://collector:4317", int32(2000000)
+				c.Tracing = &tracingapi.TracingConfiguration{Endpoint: &endpoint, SamplingRatePerMillion: &rate}
+			},
+			expected: []string{"tracing.samplingRatePerMillion", "tracing.endpoint"},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := valid()
+			testCase.mutate(c)
+			errs := Validate(c)
+			if len(errs) != len(testCase.expected) {
+				t.Fatalf("expected %d errors, got %v", len(testCase.expected), errs)
+			}
+			for i, err := range errs {
+				if err.Field != testCase.expected[i] {
+					t.Errorf("expected an error for %s, got %v", testCase.expected[i], err)
+				}
+			}
+		})
+	}
+}
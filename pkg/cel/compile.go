@@ -0,0 +1,231 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+	"k8s.io/apiserver/pkg/cel/library"
+)
+
+// COPIED FROM K8S SOURCE: k8s.io/apiserver/pkg/admission/plugin/cel/compile.go
+// Modified so that the environments are built for a version of the environment
+// and include the libraries of the compiler, and the programs account for the
+// cost of calls to their functions and explain their evaluations on request.
+
+type envs map[plugincel.OptionalVariableDeclarations]*cel.Env
+
+type compiler struct {
+	version   EnvironmentVersion
+	libraries []Library
+
+	initEnvsOnce sync.Once
+	initEnvs     envs
+	initEnvsErr  error
+}
+
+func (c *compiler) getEnvs() (envs, error) {
+	c.initEnvsOnce.Do(func() {
+		requiredVarsEnv, err := c.buildRequiredVarsEnv()
+		if err != nil {
+			c.initEnvsErr = err
+			return
+		}
+
+		c.initEnvs, err = buildWithOptionalVarsEnvs(requiredVarsEnv)
+		if err != nil {
+			c.initEnvsErr = err
+			return
+		}
+	})
+	return c.initEnvs, c.initEnvsErr
+}
+
+func (c *compiler) buildBaseEnv() (*cel.Env, error) {
+	var opts []cel.EnvOption
+	opts = append(opts, cel.HomogeneousAggregateLiterals())
+	// Validate function declarations once during base env initialization,
+	// so they don't need to be evaluated each time a CEL rule is compiled.
+	// This is a relatively expensive operation.
+	opts = append(opts, cel.EagerlyValidateDeclarations(true), cel.DefaultUTCTimeZone(true))
+	opts = append(opts, BaseEnvOptions(c.version)...)
+	opts = append(opts, EnvOptions(c.libraries)...)
+
+	return cel.NewEnv(opts...)
+}
+
+func (c *compiler) buildRequiredVarsEnv() (*cel.Env, error) {
+	baseEnv, err := c.buildBaseEnv()
+	if err != nil {
+		return nil, err
+	}
+	var propDecls []cel.EnvOption
+	reg := apiservercel.NewRegistry(baseEnv)
+
+	requestType := plugincel.BuildRequestType()
+	rt, err := apiservercel.NewRuleTypes(requestType.TypeName(), requestType, reg)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, nil
+	}
+	opts, err := rt.EnvOptions(baseEnv.TypeProvider())
+	if err != nil {
+		return nil, err
+	}
+	propDecls = append(propDecls, cel.Variable(plugincel.ObjectVarName, cel.DynType))
+	propDecls = append(propDecls, cel.Variable(plugincel.OldObjectVarName, cel.DynType))
+	propDecls = append(propDecls, cel.Variable(plugincel.RequestVarName, requestType.CelType()))
+
+	opts = append(opts, propDecls...)
+	env, err := baseEnv.Extend(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func buildEnvWithVars(baseVarsEnv *cel.Env, options plugincel.OptionalVariableDeclarations) (*cel.Env, error) {
+	var opts []cel.EnvOption
+	if options.HasParams {
+		opts = append(opts, cel.Variable(plugincel.ParamsVarName, cel.DynType))
+	}
+	if options.HasAuthorizer {
+		opts = append(opts, cel.Variable(plugincel.AuthorizerVarName, library.AuthorizerType))
+		opts = append(opts, cel.Variable(plugincel.RequestResourceAuthorizerVarName, library.ResourceCheckType))
+	}
+	return baseVarsEnv.Extend(opts...)
+}
+
+func buildWithOptionalVarsEnvs(requiredVarsEnv *cel.Env) (envs, error) {
+	envs := make(envs, 4) // since the number of variable combinations is small, pre-build a environment for each
+	for _, hasParams := range []bool{false, true} {
+		for _, hasAuthorizer := range []bool{false, true} {
+			opts := plugincel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: hasAuthorizer}
+			env, err := buildEnvWithVars(requiredVarsEnv, opts)
+			if err != nil {
+				return nil, err
+			}
+			envs[opts] = env
+		}
+	}
+	return envs, nil
+}
+
+// compileCELExpression returns a compiled CEL expression.
+func (c *compiler) compileCELExpression(expressionAccessor plugincel.ExpressionAccessor, optionalVars plugincel.OptionalVariableDeclarations, perCallLimit uint64) plugincel.CompilationResult {
+	var env *cel.Env
+	envs, err := c.getEnvs()
+	if err != nil {
+		return plugincel.CompilationResult{
+			Error: &apiservercel.Error{
+				Type:   apiservercel.ErrorTypeInternal,
+				Detail: "compiler initialization failed: " + err.Error(),
+			},
+			ExpressionAccessor: expressionAccessor,
+		}
+	}
+	env, ok := envs[optionalVars]
+	if !ok {
+		return plugincel.CompilationResult{
+			Error: &apiservercel.Error{
+				Type:   apiservercel.ErrorTypeInvalid,
+				Detail: fmt.Sprintf("compiler initialization failed: failed to load environment for %v", optionalVars),
+			},
+			ExpressionAccessor: expressionAccessor,
+		}
+	}
+
+	ast, issues := env.Compile(expressionAccessor.GetExpression())
+	if issues != nil {
+		return plugincel.CompilationResult{
+			Error: &apiservercel.Error{
+				Type:   apiservercel.ErrorTypeInvalid,
+				Detail: "compilation failed: " + issues.String(),
+			},
+			ExpressionAccessor: expressionAccessor,
+		}
+	}
+	found := false
+	returnTypes := expressionAccessor.ReturnTypes()
+	for _, returnType := range returnTypes {
+		if ast.OutputType() == returnType {
+			found = true
+			break
+		}
+	}
+	if !found {
+		var reason string
+		if len(returnTypes) == 1 {
+			reason = fmt.Sprintf("must evaluate to %v", returnTypes[0].String())
+		} else {
+			reason = fmt.Sprintf("must evaluate to one of %v", returnTypes)
+		}
+
+		return plugincel.CompilationResult{
+			Error: &apiservercel.Error{
+				Type:   apiservercel.ErrorTypeInvalid,
+				Detail: reason,
+			},
+			ExpressionAccessor: expressionAccessor,
+		}
+	}
+
+	_, err = cel.AstToCheckedExpr(ast)
+	if err != nil {
+		// should be impossible since env.Compile returned no issues
+		return plugincel.CompilationResult{
+			Error: &apiservercel.Error{
+				Type:   apiservercel.ErrorTypeInternal,
+				Detail: "unexpected compilation error: " + err.Error(),
+			},
+			ExpressionAccessor: expressionAccessor,
+		}
+	}
+	programOptions := []cel.ProgramOption{
+		cel.OptimizeRegex(library.ExtensionLibRegexOptimizations...),
+		cel.InterruptCheckFrequency(celconfig.CheckFrequency),
+		cel.CostLimit(perCallLimit),
+		cel.CostTracking(costEstimator(c.libraries)),
+	}
+	prog, err := env.Program(ast, append([]cel.ProgramOption{cel.EvalOptions(cel.OptOptimize, cel.OptTrackCost)}, programOptions...)...)
+	if err != nil {
+		return plugincel.CompilationResult{
+			Error: &apiservercel.Error{
+				Type:   apiservercel.ErrorTypeInvalid,
+				Detail: "program instantiation failed: " + err.Error(),
+			},
+			ExpressionAccessor: expressionAccessor,
+		}
+	}
+	return plugincel.CompilationResult{
+		Program: &explainableProgram{
+			Program:    prog,
+			expression: expressionAccessor.GetExpression(),
+			env:        env,
+			options:    programOptions,
+		},
+		ExpressionAccessor: expressionAccessor,
+	}
+}
@@ -0,0 +1,4 @@
+// Package cel compiles the CEL expressions of policies in an environment
+// which extends the one of k8s.io/apiserver with additional libraries, such
+// as lookups of the cluster state observed by informers.
+package cel
@@ -0,0 +1,238 @@
+package cel
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+	"github.com/google/cel-go/parser"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+)
+
+const (
+	// maxOperandLength is the length past which the values of operands are
+	// truncated.
+	maxOperandLength = 256
+)
+
+// Operand is the value a sub-expression of an expression evaluated to.
+type Operand struct {
+	// Expression is the source of the sub-expression.
+	Expression string
+	// Value is the value of the sub-expression as JSON, or the error it
+	// evaluated to.
+	Value string
+}
+
+// Explanation collects the values of the sub-expressions of the expressions
+// which evaluate to false while it is attached to the context of their
+// evaluation, so that authors can see why a validation failed.
+type Explanation struct {
+	mutex    sync.Mutex
+	operands map[string][]Operand
+	cost     int64
+}
+
+type explanationKey struct{}
+
+// WithExplanation returns a context which explains the expressions evaluated
+// with it into explanation.
+//
+// Explaining an expression evaluates it again, with all of its sub-expressions
+// and without short-circuits. The cost of this evaluation is limited like the
+// one of the expression, but is not part of the cost of the evaluation of the
+// expression: callers charge it to their budgets with Explanation.Cost.
+func WithExplanation(ctx context.Context, explanation *Explanation) context.Context {
+	return context.WithValue(ctx, explanationKey{}, explanation)
+}
+
+func explanationFrom(ctx context.Context) *Explanation {
+	explanation, _ := ctx.Value(explanationKey{}).(*Explanation)
+	return explanation
+}
+
+// Operands returns the operands of the expression, if it evaluated to false.
+func (e *Explanation) Operands(expression string) []Operand {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.operands[expression]
+}
+
+// Cost returns the runtime cost of the evaluations of the explanations.
+func (e *Explanation) Cost() int64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.cost
+}
+
+func (e *Explanation) add(expression string, operands []Operand, cost uint64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.operands == nil {
+		e.operands = map[string][]Operand{}
+	}
+	e.operands[expression] = operands
+	e.cost += int64(cost)
+}
+
+// explainableProgram is a program which explains its evaluations to false
+// when the context requests it.
+type explainableProgram struct {
+	cel.Program
+	expression string
+	env        *cel.Env
+	options    []cel.ProgramOption
+
+	initExplainOnce sync.Once
+	explainAST      *cel.Ast
+	explainProgram  cel.Program
+	explainErr      error
+}
+
+func (p *explainableProgram) ContextEval(ctx context.Context, input interface{}) (ref.Val, *cel.EvalDetails, error) {
+	val, details, err := p.Program.ContextEval(ctx, input)
+	if explanation := explanationFrom(ctx); explanation != nil && err == nil && val == types.False {
+		operands, cost := p.explain(ctx, input)
+		explanation.add(p.expression, operands, cost)
+	}
+	return val, details, err
+}
+
+// explain returns the operands of the expression and the cost of their
+// evaluation.
+func (p *explainableProgram) explain(ctx context.Context, input interface{}) ([]Operand, uint64) {
+	p.initExplainOnce.Do(func() {
+		// The expression is compiled again so that the macros it calls can be
+		// printed.
+		env, err := p.env.Extend(cel.EnableMacroCallTracking())
+		if err != nil {
+			p.explainErr = err
+			return
+		}
+		ast, issues := env.Compile(p.expression)
+		if issues != nil && issues.Err() != nil {
+			p.explainErr = issues.Err()
+			return
+		}
+		options := append([]cel.ProgramOption{cel.EvalOptions(cel.OptExhaustiveEval, cel.OptTrackCost)}, p.options...)
+		p.explainAST = ast
+		p.explainProgram, p.explainErr = env.Program(ast, options...)
+	})
+	if p.explainErr != nil {
+		return []Operand{{Expression: p.expression, Value: p.explainErr.Error()}}, 0
+	}
+	_, details, err := p.explainProgram.ContextEval(ctx, input)
+	var cost uint64
+	if details != nil && details.ActualCost() != nil {
+		cost = *details.ActualCost()
+	}
+	if details == nil || details.State() == nil {
+		return []Operand{{Expression: p.expression, Value: fmt.Sprintf("%v", err)}}, cost
+	}
+
+	var operands []Operand
+	for _, e := range subExpressions(p.explainAST.Expr()) {
+		source, err := parser.Unparse(e, p.explainAST.SourceInfo())
+		if err != nil {
+			continue
+		}
+		operands = append(operands, Operand{Expression: source, Value: operandValue(details.State(), e.GetId())})
+	}
+	return operands, cost
+}
+
+// subExpressions returns the top-level sub-expressions of an expression: the
+// operands of its logical operators, and the arguments of these operands
+// when they are calls, such as comparisons. Literals are omitted.
+func subExpressions(root *exprpb.Expr) []*exprpb.Expr {
+	var result []*exprpb.Expr
+	add := func(e *exprpb.Expr) {
+		if e != nil && e.GetConstExpr() == nil {
+			result = append(result, e)
+		}
+	}
+	var visit func(e *exprpb.Expr)
+	visit = func(e *exprpb.Expr) {
+		call := e.GetCallExpr()
+		if call == nil {
+			add(e)
+			return
+		}
+		switch call.GetFunction() {
+		case operators.LogicalAnd, operators.LogicalOr, operators.LogicalNot:
+			for _, arg := range call.GetArgs() {
+				visit(arg)
+			}
+			return
+		}
+		add(e)
+		add(call.GetTarget())
+		for _, arg := range call.GetArgs() {
+			add(arg)
+		}
+	}
+	visit(root)
+	return result
+}
+
+var jsonValueType = reflect.TypeOf(&structpb.Value{})
+
+func operandValue(state interpreter.EvalState, id int64) string {
+	val, found := state.Value(id)
+	if !found {
+		return "<not evaluated>"
+	}
+	if types.IsUnknownOrError(val) {
+		return fmt.Sprintf("%v", val)
+	}
+	var text string
+	if native, err := val.ConvertToNative(jsonValueType); err == nil {
+		value := native.(*structpb.Value)
+		redactSecrets(value)
+		bytes, err := protojson.Marshal(value)
+		if err != nil {
+			text = fmt.Sprintf("%v", val.Value())
+		} else {
+			text = string(bytes)
+		}
+	} else {
+		text = fmt.Sprintf("%v", val.Value())
+	}
+	if len(text) > maxOperandLength {
+		text = text[:maxOperandLength] + "..."
+	}
+	return text
+}
+
+// redactSecrets replaces the data of the Secrets in a value, such as the ones
+// returned by lookups.
+func redactSecrets(value *structpb.Value) {
+	switch v := value.GetKind().(type) {
+	case *structpb.Value_ListValue:
+		for _, item := range v.ListValue.GetValues() {
+			redactSecrets(item)
+		}
+	case *structpb.Value_StructValue:
+		fields := v.StructValue.GetFields()
+		if fields["kind"].GetStringValue() == "Secret" && fields["apiVersion"].GetStringValue() == "v1" {
+			for _, field := range []string{"data", "stringData"} {
+				if _, ok := fields[field]; ok {
+					fields[field] = structpb.NewStringValue(redaction.Redacted)
+				}
+			}
+		}
+		for _, field := range fields {
+			redactSecrets(field)
+		}
+	}
+}
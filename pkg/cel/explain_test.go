@@ -0,0 +1,129 @@
+package cel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestExplanationOperands(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("object", cel.DynType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "example"},
+		"spec":     map[string]interface{}{"replicas": 5},
+	}
+
+	for _, testCase := range []struct {
+		name       string
+		expression string
+		expected   []Operand
+	}{
+		{
+			name:       "true",
+			expression: `object.spec.replicas > 3`,
+		},
+		{
+			name:       "comparison",
+			expression: `object.spec.replicas < 3`,
+			expected: []Operand{
+				{Expression: `object.spec.replicas < 3`, Value: "false"},
+				{Expression: `object.spec.replicas`, Value: "5"},
+			},
+		},
+		{
+			// Both sides of logical operators are evaluated, with no
+			// short-circuit, and literals are omitted.
+			name:       "logical",
+			expression: `object.metadata.name == "other" && object.spec.replicas < 3`,
+			expected: []Operand{
+				{Expression: `object.metadata.name == "other"`, Value: "false"},
+				{Expression: `object.metadata.name`, Value: `"example"`},
+				{Expression: `object.spec.replicas < 3`, Value: "false"},
+				{Expression: `object.spec.replicas`, Value: "5"},
+			},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ast, issues := env.Compile(testCase.expression)
+			if issues != nil && issues.Err() != nil {
+				t.Fatal(issues.Err())
+			}
+			options := []cel.ProgramOption{cel.EvalOptions(cel.OptTrackCost)}
+			prg, err := env.Program(ast, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			explanation := &Explanation{}
+			program := &explainableProgram{Program: prg, expression: testCase.expression, env: env, options: options}
+			if _, _, err := program.ContextEval(WithExplanation(context.Background(), explanation), map[string]interface{}{"object": object}); err != nil {
+				t.Fatal(err)
+			}
+
+			if operands := explanation.Operands(testCase.expression); !reflect.DeepEqual(operands, testCase.expected) {
+				t.Errorf("expected operands %v, got %v", testCase.expected, operands)
+			}
+			// Only the explanations of failed expressions are charged.
+			if cost := explanation.Cost(); (cost > 0) != (len(testCase.expected) > 0) {
+				t.Errorf("unexpected cost of the explanation %d", cost)
+			}
+		})
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	value, err := structpb.NewValue([]interface{}{
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "credentials"},
+			"data":       map[string]interface{}{"password": "aHVudGVyMg=="},
+		},
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"data":       map[string]interface{}{"password": "hunter2"},
+			// Secrets nested in other values are redacted too.
+			"items": []interface{}{
+				map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "stringData": map[string]interface{}{"token": "s3cr3t"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactSecrets(value)
+
+	actual, err := protojson.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := structpb.NewValue([]interface{}{
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "credentials"},
+			"data":       "<redacted>",
+		},
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"data":       map[string]interface{}{"password": "hunter2"},
+			"items": []interface{}{
+				map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "stringData": "<redacted>"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(value.AsInterface(), expected.AsInterface()) {
+		t.Errorf("expected %v, got %s", expected.AsInterface(), actual)
+	}
+}
@@ -0,0 +1,143 @@
+package cel
+
+import (
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+)
+
+// Library is a set of CEL functions which is made available to the
+// expressions of policies in addition to the Kubernetes CEL libraries.
+type Library interface {
+	cel.Library
+
+	// CallCost returns the runtime cost of a call to a function of the
+	// library, or nil if the function does not belong to the library.
+	interpreter.ActualCostEstimator
+
+	// IntroducedVersion returns the version of the environment which
+	// introduced the library.
+	IntroducedVersion() EnvironmentVersion
+}
+
+// Compiler is a FilterCompiler which can also compile single expressions, to
+// report compilation errors without evaluating them.
+type Compiler interface {
+	plugincel.FilterCompiler
+
+	// CompileExpression compiles a single expression.
+	CompileExpression(expressionAccessor plugincel.ExpressionAccessor, options plugincel.OptionalVariableDeclarations, perCallLimit uint64) plugincel.CompilationResult
+
+	// Version returns the version of the environment of the compiler.
+	Version() EnvironmentVersion
+}
+
+// NamespacedLibrary is a Library which can be confined to the namespace of a
+// namespaced policy, so that the policy cannot observe other tenants.
+type NamespacedLibrary interface {
+	Library
+
+	// ForNamespace returns the library as seen by policies of the namespace.
+	ForNamespace(namespace string) Library
+}
+
+// NewFilterCompiler returns a Compiler which compiles expressions in the given
+// version of the environment, including the libraries introduced up to that
+// version.
+func NewFilterCompiler(version EnvironmentVersion, libraries ...Library) Compiler {
+	return &compiler{version: version, libraries: LibrariesOf(version, libraries)}
+}
+
+func (c *compiler) CompileExpression(expressionAccessor plugincel.ExpressionAccessor, options plugincel.OptionalVariableDeclarations, perCallLimit uint64) plugincel.CompilationResult {
+	return c.compileCELExpression(expressionAccessor, options, perCallLimit)
+}
+
+func (c *compiler) Version() EnvironmentVersion {
+	return c.version
+}
+
+// Compile compiles the cel expressions defined in the ExpressionAccessors into a Filter
+func (c *compiler) Compile(expressionAccessors []plugincel.ExpressionAccessor, options plugincel.OptionalVariableDeclarations, perCallLimit uint64) plugincel.Filter {
+	compilationResults := make([]plugincel.CompilationResult, len(expressionAccessors))
+	for i, expressionAccessor := range expressionAccessors {
+		if expressionAccessor == nil {
+			continue
+		}
+		compilationResults[i] = c.compileCELExpression(expressionAccessor, options, perCallLimit)
+	}
+	return plugincel.NewFilter(compilationResults)
+}
+
+// FilterCompilers holds the Compilers of cluster scoped policies and of the
+// namespaced policies of every namespace.
+type FilterCompilers struct {
+	version   EnvironmentVersion
+	libraries []Library
+	cluster   Compiler
+
+	mutex      sync.Mutex
+	namespaced map[string]Compiler
+}
+
+// NewFilterCompilers returns FilterCompilers which compile expressions in the
+// given version of the environment, including the given libraries.
+// NamespacedLibraries are confined to the namespace of namespaced policies.
+func NewFilterCompilers(version EnvironmentVersion, libraries ...Library) *FilterCompilers {
+	return &FilterCompilers{
+		version:    version,
+		libraries:  libraries,
+		cluster:    NewFilterCompiler(version, libraries...),
+		namespaced: map[string]Compiler{},
+	}
+}
+
+// ForNamespace returns the Compiler of the policies of the namespace, or of
+// cluster scoped policies if the namespace is empty.
+func (c *FilterCompilers) ForNamespace(namespace string) Compiler {
+	if len(namespace) == 0 {
+		return c.cluster
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if compiler, ok := c.namespaced[namespace]; ok {
+		return compiler
+	}
+	libraries := make([]Library, len(c.libraries))
+	for i, l := range c.libraries {
+		if namespaced, ok := l.(NamespacedLibrary); ok {
+			libraries[i] = namespaced.ForNamespace(namespace)
+		} else {
+			libraries[i] = l
+		}
+	}
+	compiler := NewFilterCompiler(c.version, libraries...)
+	c.namespaced[namespace] = compiler
+	return compiler
+}
+
+// EnvOptions returns the options which declare the given libraries in a CEL
+// environment.
+func EnvOptions(libraries []Library) []cel.EnvOption {
+	opts := make([]cel.EnvOption, 0, len(libraries))
+	for _, l := range libraries {
+		opts = append(opts, cel.Lib(l))
+	}
+	return opts
+}
+
+// costEstimator returns the cost of calls to the functions of the libraries.
+type costEstimator []Library
+
+func (e costEstimator) CallCost(function, overloadID string, args []ref.Val, result ref.Val) *uint64 {
+	for _, l := range e {
+		if cost := l.CallCost(function, overloadID, args, result); cost != nil {
+			return cost
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package library
+
+import (
+	"net/netip"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+// CIDR returns a library to check IP addresses and CIDR ranges:
+//
+//	isIP(string) -> bool
+//	isCIDR(string) -> bool
+//	cidrContains(string, string) -> bool
+//
+// cidrContains returns whether the CIDR range of its first argument contains
+// the IP address, or the whole CIDR range, of its second argument.
+func CIDR() webhookcel.Library {
+	l := newParsingLibrary(webhookcel.EnvironmentV2)
+	l.function("isIP", "is_ip_string", []*cel.Type{cel.StringType}, cel.BoolType,
+		cel.UnaryBinding(isIP))
+	l.function("isCIDR", "is_cidr_string", []*cel.Type{cel.StringType}, cel.BoolType,
+		cel.UnaryBinding(isCIDR))
+	l.function("cidrContains", "cidr_contains_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+		cel.BinaryBinding(cidrContains))
+	return l
+}
+
+func isIP(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	_, err := netip.ParseAddr(s)
+	return types.Bool(err == nil)
+}
+
+func isCIDR(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	_, err := netip.ParsePrefix(s)
+	return types.Bool(err == nil)
+}
+
+func cidrContains(lhs, rhs ref.Val) ref.Val {
+	cidr, ok := lhs.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(lhs)
+	}
+	other, ok := rhs.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(rhs)
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return types.NewErr("cidrContains: invalid CIDR %q: %v", cidr, err)
+	}
+	prefix = prefix.Masked()
+
+	if addr, err := netip.ParseAddr(other); err == nil {
+		return types.Bool(prefix.Contains(addr.Unmap()) || prefix.Contains(addr))
+	}
+	otherPrefix, err := netip.ParsePrefix(other)
+	if err != nil {
+		return types.NewErr("cidrContains: invalid IP address or CIDR %q", other)
+	}
+	return types.Bool(prefix.Bits() <= otherPrefix.Bits() && prefix.Contains(otherPrefix.Masked().Addr()))
+}
@@ -0,0 +1,3 @@
+// Package library contains the CEL libraries shipped with the shim, which may
+// be enabled in addition to the libraries of k8s.io/apiserver.
+package library
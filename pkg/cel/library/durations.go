@@ -0,0 +1,110 @@
+package library
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+// Durations returns a library to parse durations written with units:
+//
+//	isDuration(string) -> bool
+//	parseDuration(string) -> google.protobuf.Duration
+//
+// Durations are sequences of decimal numbers followed by a unit, such as
+// "1w2d" or "1.5h". Next to the units of the duration() function, "ns",
+// "us", "ms", "s", "m" and "h", the units "d" for 24 hours and "w" for 7 days
+// may be used.
+func Durations() webhookcel.Library {
+	l := newParsingLibrary(webhookcel.EnvironmentV2)
+	l.function("isDuration", "is_duration_string", []*cel.Type{cel.StringType}, cel.BoolType,
+		cel.UnaryBinding(isDuration))
+	l.function("parseDuration", "parse_duration_string", []*cel.Type{cel.StringType}, cel.DurationType,
+		cel.UnaryBinding(parseDuration))
+	return l
+}
+
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+func parseDurationWithUnits(s string) (time.Duration, error) {
+	rest := s
+	sign := 1.0
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = -1, rest[1:]
+	} else if strings.HasPrefix(rest, "+") {
+		rest = rest[1:]
+	}
+	if rest == "0" {
+		return 0, nil
+	}
+	if len(rest) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var total float64
+	for len(rest) > 0 {
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		value, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		rest = rest[i:]
+
+		j := strings.IndexAny(rest, "0123456789.")
+		if j < 0 {
+			j = len(rest)
+		}
+		unit, ok := durationUnits[rest[:j]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q in duration %q", rest[:j], s)
+		}
+		rest = rest[j:]
+		total += value * float64(unit)
+	}
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("duration %q is out of range", s)
+	}
+	return time.Duration(sign * total), nil
+}
+
+func isDuration(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	_, err := parseDurationWithUnits(s)
+	return types.Bool(err == nil)
+}
+
+func parseDuration(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	d, err := parseDurationWithUnits(s)
+	if err != nil {
+		return types.NewErr("parseDuration: %v", err)
+	}
+	return types.Duration{Duration: d}
+}
@@ -0,0 +1,121 @@
+package library
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+// Images returns a library to parse container image references:
+//
+//	isImageReference(string) -> bool
+//	parseImageReference(string) -> map(string, string)
+//
+// parseImageReference returns the registry, repository, tag and digest of the
+// reference, normalized the way container runtimes resolve them, e.g.
+// "nginx:1.25" has the registry "docker.io" and the repository
+// "library/nginx". The tag and digest are empty if the reference has none.
+func Images() webhookcel.Library {
+	l := newParsingLibrary(webhookcel.EnvironmentV2)
+	l.function("isImageReference", "is_image_reference_string", []*cel.Type{cel.StringType}, cel.BoolType,
+		cel.UnaryBinding(isImageReference))
+	l.function("parseImageReference", "parse_image_reference_string", []*cel.Type{cel.StringType}, cel.MapType(cel.StringType, cel.StringType),
+		cel.UnaryBinding(parseImageReference))
+	return l
+}
+
+const (
+	defaultRegistry     = "docker.io"
+	officialRepoPrefix  = "library/"
+	maxImageNameLength  = 255
+	legacyDockerHubHost = "index.docker.io"
+)
+
+var (
+	pathComponentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*$`)
+	registryRegexp      = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*|\[[0-9a-fA-F:.]+\])(?::[0-9]+)?$`)
+	tagRegexp           = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp        = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+type imageReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+func parseReference(s string) (*imageReference, error) {
+	reference := &imageReference{}
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference.digest = name[:i], name[i+1:]
+		if !digestRegexp.MatchString(reference.digest) {
+			return nil, fmt.Errorf("invalid digest %q", reference.digest)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		name, reference.tag = name[:i], name[i+1:]
+		if !tagRegexp.MatchString(reference.tag) {
+			return nil, fmt.Errorf("invalid tag %q", reference.tag)
+		}
+	}
+	if len(name) == 0 || len(name) > maxImageNameLength {
+		return nil, fmt.Errorf("invalid image name %q", name)
+	}
+
+	components := strings.Split(name, "/")
+	if len(components) > 1 && (strings.ContainsAny(components[0], ".:[") || components[0] == "localhost") {
+		reference.registry = components[0]
+		components = components[1:]
+		if !registryRegexp.MatchString(reference.registry) {
+			return nil, fmt.Errorf("invalid registry %q", reference.registry)
+		}
+	}
+	for _, component := range components {
+		if !pathComponentRegexp.MatchString(component) {
+			return nil, fmt.Errorf("invalid repository %q", strings.Join(components, "/"))
+		}
+	}
+	reference.repository = strings.Join(components, "/")
+
+	if len(reference.registry) == 0 || reference.registry == legacyDockerHubHost {
+		reference.registry = defaultRegistry
+	}
+	if reference.registry == defaultRegistry && len(components) == 1 {
+		reference.repository = officialRepoPrefix + reference.repository
+	}
+	return reference, nil
+}
+
+func isImageReference(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	_, err := parseReference(s)
+	return types.Bool(err == nil)
+}
+
+func parseImageReference(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	reference, err := parseReference(s)
+	if err != nil {
+		return types.NewErr("parseImageReference: invalid image reference %q: %v", s, err)
+	}
+	return types.DefaultTypeAdapter.NativeToValue(map[string]string{
+		"registry":   reference.registry,
+		"repository": reference.repository,
+		"tag":        reference.tag,
+		"digest":     reference.digest,
+	})
+}
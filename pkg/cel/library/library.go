@@ -0,0 +1,68 @@
+package library
+
+import (
+	"math"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+// Register adds the built-in libraries to the registry.
+func Register(libraries *webhookcel.Libraries) {
+	libraries.Register("images", Images())
+	libraries.Register("semver", Semver())
+	libraries.Register("cidr", CIDR())
+	libraries.Register("durations", Durations())
+}
+
+// parsingLibrary is a library of functions which parse their string
+// arguments, so that the cost of a call is a traversal of the arguments.
+type parsingLibrary struct {
+	introducedVersion webhookcel.EnvironmentVersion
+	functions         []cel.EnvOption
+	overloads         sets.Set[string]
+}
+
+var _ webhookcel.Library = &parsingLibrary{}
+
+func (l *parsingLibrary) CompileOptions() []cel.EnvOption {
+	return l.functions
+}
+
+func (l *parsingLibrary) IntroducedVersion() webhookcel.EnvironmentVersion {
+	return l.introducedVersion
+}
+
+func (l *parsingLibrary) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+func (l *parsingLibrary) CallCost(function, overloadID string, args []ref.Val, result ref.Val) *uint64 {
+	if !l.overloads.Has(overloadID) {
+		return nil
+	}
+	var size int
+	for _, arg := range args {
+		if s, ok := arg.(types.String); ok {
+			size += len(s)
+		}
+	}
+	cost := 1 + uint64(math.Ceil(float64(size)*common.StringTraversalCostFactor))
+	return &cost
+}
+
+// function declares a function of a parsingLibrary with a single overload.
+func (l *parsingLibrary) function(name, overloadID string, args []*cel.Type, result *cel.Type, binding cel.OverloadOpt) {
+	l.functions = append(l.functions, cel.Function(name, cel.Overload(overloadID, args, result, binding)))
+	l.overloads.Insert(overloadID)
+}
+
+func newParsingLibrary(introducedVersion webhookcel.EnvironmentVersion) *parsingLibrary {
+	return &parsingLibrary{introducedVersion: introducedVersion, overloads: sets.New[string]()}
+}
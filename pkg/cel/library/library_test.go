@@ -0,0 +1,74 @@
+package library
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+func TestLibraries(t *testing.T) {
+	env, err := cel.NewEnv(webhookcel.EnvOptions([]webhookcel.Library{Images(), Semver(), CIDR(), Durations()})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expression string
+		expected   interface{}
+		expectErr  bool
+	}{
+		{expression: `parseImageReference("nginx") == {"registry": "docker.io", "repository": "library/nginx", "tag": "", "digest": ""}`, expected: true},
+		{expression: `parseImageReference("registry.k8s.io/pause:3.9").repository`, expected: "pause"},
+		{expression: `parseImageReference("localhost:5000/team/app:v1").registry`, expected: "localhost:5000"},
+		{expression: `parseImageReference("quay.io/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef").digest`, expected: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		{expression: `isImageReference("Nginx:latest")`, expected: false},
+		{expression: `parseImageReference("nginx:")`, expectErr: true},
+		{expression: `semverCompare("v1.10.0", "1.9.3")`, expected: int64(1)},
+		{expression: `semverCompare("1.0.0-rc.1", "1.0.0")`, expected: int64(-1)},
+		{expression: `isSemver("1.2")`, expected: false},
+		{expression: `semverCompare("1.2", "1.2.0")`, expectErr: true},
+		{expression: `cidrContains("10.0.0.0/8", "10.1.2.3")`, expected: true},
+		{expression: `cidrContains("10.0.0.0/8", "10.1.0.0/16")`, expected: true},
+		{expression: `cidrContains("10.1.0.0/16", "10.0.0.0/8")`, expected: false},
+		{expression: `cidrContains("fd00::/8", "192.168.0.1")`, expected: false},
+		{expression: `isIP("::1") && isCIDR("::1/128") && !isCIDR("::1")`, expected: true},
+		{expression: `parseDuration("1w2d") == duration("216h")`, expected: true},
+		{expression: `parseDuration("-1.5h")`, expected: -90 * time.Minute},
+		{expression: `isDuration("3y")`, expected: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			ast, issues := env.Compile(tc.expression)
+			if issues != nil {
+				t.Fatalf("unexpected compilation issues: %v", issues)
+			}
+			prg, err := env.Program(ast)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, _, err := prg.Eval(cel.NoVars())
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d, ok := out.(types.Duration); ok {
+				if d.Duration != tc.expected {
+					t.Errorf("expected %v, got %v", tc.expected, d.Duration)
+				}
+				return
+			}
+			if out.Value() != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, out.Value())
+			}
+		})
+	}
+}
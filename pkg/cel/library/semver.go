@@ -0,0 +1,62 @@
+package library
+
+import (
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+// Semver returns a library to compare semantic versions:
+//
+//	isSemver(string) -> bool
+//	semverCompare(string, string) -> int
+//
+// Versions follow https://semver.org, optionally prefixed with "v" as is
+// common for image tags. semverCompare returns -1, 0 or 1 when the first
+// version is lower than, equal to or greater than the second one.
+func Semver() webhookcel.Library {
+	l := newParsingLibrary(webhookcel.EnvironmentV2)
+	l.function("isSemver", "is_semver_string", []*cel.Type{cel.StringType}, cel.BoolType,
+		cel.UnaryBinding(isSemver))
+	l.function("semverCompare", "semver_compare_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.IntType,
+		cel.BinaryBinding(semverCompare))
+	return l
+}
+
+func parseSemver(s string) (semver.Version, error) {
+	return semver.Parse(strings.TrimPrefix(s, "v"))
+}
+
+func isSemver(arg ref.Val) ref.Val {
+	s, ok := arg.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(arg)
+	}
+	_, err := parseSemver(s)
+	return types.Bool(err == nil)
+}
+
+func semverCompare(lhs, rhs ref.Val) ref.Val {
+	a, ok := lhs.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(lhs)
+	}
+	b, ok := rhs.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(rhs)
+	}
+	va, err := parseSemver(a)
+	if err != nil {
+		return types.NewErr("semverCompare: invalid version %q: %v", a, err)
+	}
+	vb, err := parseSemver(b)
+	if err != nil {
+		return types.NewErr("semverCompare: invalid version %q: %v", b, err)
+	}
+	return types.Int(va.Compare(vb))
+}
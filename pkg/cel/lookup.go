@@ -0,0 +1,401 @@
+package cel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	lookupOverload = "lookup_string_string_string"
+	listOverload   = "list_string_string_string"
+
+	// lookupCost is the runtime cost of a call to lookup, and of every
+	// object returned by a call to list. Reading objects from the informer
+	// cache and converting them for CEL is far more expensive than the
+	// builtin operations of CEL which cost 1.
+	lookupCost = 100
+)
+
+// LookupLibrary gives expressions read access to the cluster state observed by
+// shared informers:
+//
+//	lookup(resource string, namespace string, name string) -> dyn
+//	list(resource string, namespace string, labelSelector string) -> list(dyn)
+//
+// Resources are written as group/version/resource, or version/resource for
+// the core group, e.g. "networking.k8s.io/v1/ingresses" or "v1/configmaps".
+// lookup returns null if the object does not exist. An empty namespace
+// lists objects across all namespaces, and reads cluster scoped objects.
+//
+// Only the resources the library was created for can be read, since every
+// one of them requires an informer holding all of its objects in memory.
+// Secrets can never be read, see ValidateLookupResource.
+// Namespaced policies may only read objects of their own namespace.
+//
+// Both functions return dyn when evaluated. When policies are type checked,
+// calls for a constant resource return objects of the schema of the resource
+// instead, see TypedCompileOptions.
+type LookupLibrary struct {
+	informers map[schema.GroupVersionResource]cache.SharedIndexInformer
+	listers   map[schema.GroupVersionResource]cache.GenericLister
+
+	// namespace the library is confined to, if any.
+	namespace string
+}
+
+var _ NamespacedLibrary = &LookupLibrary{}
+
+// NewLookupLibrary returns a LookupLibrary for the given resources. Informers
+// are requested from factory when it supports the resource, and from
+// dynamicFactory otherwise, so both factories must be started afterwards.
+func NewLookupLibrary(
+	factory informers.SharedInformerFactory,
+	dynamicFactory dynamicinformer.DynamicSharedInformerFactory,
+	resources []schema.GroupVersionResource,
+) *LookupLibrary {
+	l := &LookupLibrary{
+		informers: map[schema.GroupVersionResource]cache.SharedIndexInformer{},
+		listers:   map[schema.GroupVersionResource]cache.GenericLister{},
+	}
+	for _, gvr := range resources {
+		if ValidateLookupResource(gvr) != nil {
+			continue
+		}
+		informer, err := factory.ForResource(gvr)
+		if err != nil {
+			informer = dynamicFactory.ForResource(gvr)
+		}
+		l.informers[gvr] = informer.Informer()
+		l.listers[gvr] = informer.Lister()
+	}
+	return l
+}
+
+// ParseResource parses a resource written as group/version/resource, or
+// version/resource for the core group.
+func ParseResource(resource string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(resource, "/")
+	switch {
+	case len(parts) == 2 && len(parts[0]) > 0 && len(parts[1]) > 0:
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}, nil
+	case len(parts) == 3 && len(parts[0]) > 0 && len(parts[1]) > 0 && len(parts[2]) > 0:
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+	default:
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q, expected group/version/resource or version/resource", resource)
+	}
+}
+
+// ValidateLookupResource returns an error if the resource may not be read by
+// lookups. Secrets may not: their data would reach the messages and audit
+// annotations of policies without being redacted.
+func ValidateLookupResource(gvr schema.GroupVersionResource) error {
+	if gvr.GroupResource() == (schema.GroupResource{Resource: "secrets"}) {
+		return fmt.Errorf("resource %q may not be read by lookups", FormatResource(gvr))
+	}
+	return nil
+}
+
+// FormatResource formats a resource as ParseResource parses it.
+func FormatResource(gvr schema.GroupVersionResource) string {
+	if len(gvr.Group) == 0 {
+		return gvr.Version + "/" + gvr.Resource
+	}
+	return gvr.Group + "/" + gvr.Version + "/" + gvr.Resource
+}
+
+// HasSynced returns true once the informers of all resources have synced.
+func (l *LookupLibrary) HasSynced() bool {
+	for _, informer := range l.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *LookupLibrary) ForNamespace(namespace string) Library {
+	confined := *l
+	confined.namespace = namespace
+	return &confined
+}
+
+func (l *LookupLibrary) IntroducedVersion() EnvironmentVersion {
+	return EnvironmentV2
+}
+
+func (l *LookupLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("lookup",
+			cel.Overload(lookupOverload, []*cel.Type{cel.StringType, cel.StringType, cel.StringType}, cel.DynType,
+				cel.FunctionBinding(l.lookup))),
+		cel.Function("list",
+			cel.Overload(listOverload, []*cel.Type{cel.StringType, cel.StringType, cel.StringType}, cel.ListType(cel.DynType),
+				cel.FunctionBinding(l.list))),
+	}
+}
+
+func (l *LookupLibrary) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+func (l *LookupLibrary) CallCost(function, overloadID string, args []ref.Val, result ref.Val) *uint64 {
+	switch overloadID {
+	case lookupOverload:
+		cost := uint64(lookupCost)
+		return &cost
+	case listOverload:
+		cost := uint64(lookupCost)
+		if sizer, ok := result.(traits.Sizer); ok {
+			if size, ok := sizer.Size().(types.Int); ok {
+				cost += uint64(size) * lookupCost
+			}
+		}
+		return &cost
+	}
+	return nil
+}
+
+// CheckExpression returns warnings for the calls of the expression which
+// read resources that are not available to the library.
+func (l *LookupLibrary) CheckExpression(ast *cel.Ast) []string {
+	var warnings []string
+	walkCalls(ast.Expr(), func(call *exprpb.Expr_Call) {
+		if (call.Function != "lookup" && call.Function != "list") || len(call.Args) != 3 {
+			return
+		}
+		constant := call.Args[0].GetConstExpr()
+		if constant == nil {
+			return
+		}
+		if _, err := l.lister(types.String(constant.GetStringValue()), l.namespace); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", call.Function, err))
+		}
+	})
+	return warnings
+}
+
+// Resources returns the resources the library can read, sorted.
+func (l *LookupLibrary) Resources() []schema.GroupVersionResource {
+	resources := make([]schema.GroupVersionResource, 0, len(l.listers))
+	for gvr := range l.listers {
+		resources = append(resources, gvr)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return FormatResource(resources[i]) < FormatResource(resources[j])
+	})
+	return resources
+}
+
+// TypedCompileOptions declares variants of lookup and list which return
+// objects of the given types rather than dyn, for type checking. TypeCalls
+// rewrites the calls of expressions to them.
+func (l *LookupLibrary) TypedCompileOptions(types map[schema.GroupVersionResource]*cel.Type) []cel.EnvOption {
+	var opts []cel.EnvOption
+	for gvr, t := range types {
+		lookup, list := typedFunction("lookup", gvr), typedFunction("list", gvr)
+		opts = append(opts,
+			cel.Function(lookup,
+				cel.Overload(lookup, []*cel.Type{cel.StringType, cel.StringType, cel.StringType}, t)),
+			cel.Function(list,
+				cel.Overload(list, []*cel.Type{cel.StringType, cel.StringType, cel.StringType}, cel.ListType(t))),
+		)
+	}
+	return opts
+}
+
+// TypeCalls rewrites the calls of lookup and list which read a constant
+// resource of types to their variants declared by TypedCompileOptions, so
+// that checking the expression knows the types of the objects they return.
+func (l *LookupLibrary) TypeCalls(ast *cel.Ast, types map[schema.GroupVersionResource]*cel.Type) (*cel.Ast, error) {
+	parsed, err := cel.AstToParsedExpr(ast)
+	if err != nil {
+		return nil, err
+	}
+	walkCalls(parsed.GetExpr(), func(call *exprpb.Expr_Call) {
+		if (call.Function != "lookup" && call.Function != "list") || call.Target != nil || len(call.Args) != 3 {
+			return
+		}
+		constant := call.Args[0].GetConstExpr()
+		if constant == nil {
+			return
+		}
+		gvr, err := ParseResource(constant.GetStringValue())
+		if err != nil {
+			return
+		}
+		if _, ok := types[gvr]; ok {
+			call.Function = typedFunction(call.Function, gvr)
+		}
+	})
+	return cel.ParsedExprToAstWithSource(parsed, ast.Source()), nil
+}
+
+// typedFunction returns the name of the variant of function which returns
+// objects of the resource.
+func typedFunction(function string, gvr schema.GroupVersionResource) string {
+	return function + "@" + FormatResource(gvr)
+}
+
+func (l *LookupLibrary) lister(resource ref.Val, namespace string) (cache.GenericLister, error) {
+	if len(l.namespace) > 0 && namespace != l.namespace {
+		return nil, fmt.Errorf("policies of namespace %q may only read objects of their own namespace", l.namespace)
+	}
+	str, ok := resource.(types.String)
+	if !ok {
+		return nil, fmt.Errorf("expected resource to be a string but got %s", resource.Type().TypeName())
+	}
+	gvr, err := ParseResource(string(str))
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateLookupResource(gvr); err != nil {
+		return nil, err
+	}
+	lister, ok := l.listers[gvr]
+	if !ok {
+		return nil, fmt.Errorf("resource %q is not available for lookups", str)
+	}
+	return lister, nil
+}
+
+func (l *LookupLibrary) lookup(args ...ref.Val) ref.Val {
+	namespace, name := string(args[1].(types.String)), string(args[2].(types.String))
+	lister, err := l.lister(args[0], namespace)
+	if err != nil {
+		return types.NewErr("lookup: %v", err)
+	}
+
+	var obj runtime.Object
+	if len(namespace) == 0 {
+		obj, err = lister.Get(name)
+	} else {
+		obj, err = lister.ByNamespace(namespace).Get(name)
+	}
+	if k8serrors.IsNotFound(err) {
+		return types.NullValue
+	} else if err != nil {
+		return types.NewErr("lookup: %v", err)
+	}
+
+	object, err := toUnstructured(obj)
+	if err != nil {
+		return types.NewErr("lookup: %v", err)
+	}
+	return types.DefaultTypeAdapter.NativeToValue(object)
+}
+
+func (l *LookupLibrary) list(args ...ref.Val) ref.Val {
+	namespace := string(args[1].(types.String))
+	lister, err := l.lister(args[0], namespace)
+	if err != nil {
+		return types.NewErr("list: %v", err)
+	}
+	selector, err := labels.Parse(string(args[2].(types.String)))
+	if err != nil {
+		return types.NewErr("list: invalid label selector: %v", err)
+	}
+
+	var objs []runtime.Object
+	if len(namespace) == 0 {
+		objs, err = lister.List(selector)
+	} else {
+		objs, err = lister.ByNamespace(namespace).List(selector)
+	}
+	if err != nil {
+		return types.NewErr("list: %v", err)
+	}
+
+	items := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		object, err := toUnstructured(obj)
+		if err != nil {
+			return types.NewErr("list: %v", err)
+		}
+		items = append(items, object)
+	}
+	// Informers do not guarantee any order, sort to keep evaluation stable.
+	sort.Slice(items, func(i, j int) bool {
+		return objectKey(items[i].(map[string]interface{})) < objectKey(items[j].(map[string]interface{}))
+	})
+	return types.DefaultTypeAdapter.NativeToValue(items)
+}
+
+// toUnstructured converts an object of an informer to the representation of
+// objects in CEL, including its apiVersion and kind. Objects of the informer
+// cache are shared, so the result is always a copy.
+func toUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.Object), nil
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	// Typed objects of informers lack their TypeMeta.
+	gvks, _, err := clientsetscheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: object}
+	u.SetGroupVersionKind(gvks[0])
+	return u.Object, nil
+}
+
+func objectKey(object map[string]interface{}) string {
+	accessor, err := meta.Accessor(&unstructured.Unstructured{Object: object})
+	if err != nil {
+		return ""
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName()
+}
+
+// walkCalls calls fn for every call in the expression.
+func walkCalls(e *exprpb.Expr, fn func(*exprpb.Expr_Call)) {
+	if e == nil {
+		return
+	}
+	switch k := e.ExprKind.(type) {
+	case *exprpb.Expr_CallExpr:
+		fn(k.CallExpr)
+		walkCalls(k.CallExpr.Target, fn)
+		for _, arg := range k.CallExpr.Args {
+			walkCalls(arg, fn)
+		}
+	case *exprpb.Expr_SelectExpr:
+		walkCalls(k.SelectExpr.Operand, fn)
+	case *exprpb.Expr_ListExpr:
+		for _, element := range k.ListExpr.Elements {
+			walkCalls(element, fn)
+		}
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range k.StructExpr.Entries {
+			walkCalls(entry.GetMapKey(), fn)
+			walkCalls(entry.Value, fn)
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		c := k.ComprehensionExpr
+		walkCalls(c.IterRange, fn)
+		walkCalls(c.AccuInit, fn)
+		walkCalls(c.LoopCondition, fn)
+		walkCalls(c.LoopStep, fn)
+		walkCalls(c.Result, fn)
+	}
+}
@@ -0,0 +1,228 @@
+package cel
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newTestLookupLibrary returns a LookupLibrary for configmaps, reading from
+// an indexer in place of an informer.
+func newTestLookupLibrary(t *testing.T) *LookupLibrary {
+	configMap := func(namespace, name string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+			Data:       map[string]string{"key": name},
+		}
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range []*corev1.ConfigMap{
+		configMap("team-a", "b", map[string]string{"app": "example"}),
+		configMap("team-a", "a", map[string]string{"app": "example"}),
+		configMap("team-a", "c", nil),
+		configMap("team-b", "a", map[string]string{"app": "example"}),
+	} {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "a"},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	}
+	secretIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if err := secretIndexer.Add(secret); err != nil {
+		t.Fatal(err)
+	}
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	// Secrets are never read, even when a lister is available.
+	return &LookupLibrary{
+		informers: map[schema.GroupVersionResource]cache.SharedIndexInformer{},
+		listers: map[schema.GroupVersionResource]cache.GenericLister{
+			configMaps: cache.NewGenericLister(indexer, configMaps.GroupResource()),
+			secrets:    cache.NewGenericLister(secretIndexer, secrets.GroupResource()),
+		},
+	}
+}
+
+func TestLookupLibrary(t *testing.T) {
+	l := newTestLookupLibrary(t)
+
+	for _, testCase := range []struct {
+		name       string
+		library    Library
+		expression string
+		expected   interface{}
+		cost       uint64
+		err        string
+	}{
+		{
+			name:       "lookup",
+			library:    l,
+			expression: `lookup("v1/configmaps", "team-a", "a").data.key`,
+			expected:   "a",
+			cost:       lookupCost,
+		},
+		{
+			name:       "lookup-kind",
+			library:    l,
+			expression: `lookup("v1/configmaps", "team-a", "a").kind`,
+			expected:   "ConfigMap",
+		},
+		{
+			name:       "lookup-missing",
+			library:    l,
+			expression: `lookup("v1/configmaps", "team-a", "missing") == null`,
+			expected:   true,
+		},
+		{
+			name:       "list-sorted",
+			library:    l,
+			expression: `list("v1/configmaps", "", "app=example").map(c, c.metadata.namespace + "/" + c.metadata.name) == ["team-a/a", "team-a/b", "team-b/a"]`,
+			expected:   true,
+			cost:       4 * lookupCost,
+		},
+		{
+			name:       "list-namespace",
+			library:    l,
+			expression: `list("v1/configmaps", "team-a", "").size()`,
+			expected:   int64(3),
+		},
+		{
+			name:       "list-invalid-selector",
+			library:    l,
+			expression: `list("v1/configmaps", "team-a", "app in")`,
+			err:        "invalid label selector",
+		},
+		{
+			name:       "unavailable-resource",
+			library:    l,
+			expression: `lookup("apps/v1/deployments", "team-a", "a")`,
+			err:        `resource "apps/v1/deployments" is not available for lookups`,
+		},
+		{
+			name:       "lookup-secret",
+			library:    l,
+			expression: `lookup("v1/secrets", "team-a", "a")`,
+			err:        `resource "v1/secrets" may not be read by lookups`,
+		},
+		{
+			name:       "list-secrets",
+			library:    l,
+			expression: `list("v1/secrets", "team-a", "")`,
+			err:        `resource "v1/secrets" may not be read by lookups`,
+		},
+		{
+			name:       "namespaced",
+			library:    l.ForNamespace("team-a"),
+			expression: `lookup("v1/configmaps", "team-a", "a").data.key`,
+			expected:   "a",
+		},
+		{
+			name:       "namespaced-other-namespace",
+			library:    l.ForNamespace("team-a"),
+			expression: `lookup("v1/configmaps", "team-b", "a")`,
+			err:        "may only read objects of their own namespace",
+		},
+		{
+			name:       "namespaced-all-namespaces",
+			library:    l.ForNamespace("team-a"),
+			expression: `list("v1/configmaps", "", "")`,
+			err:        "may only read objects of their own namespace",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			env, err := cel.NewEnv(EnvOptions([]Library{testCase.library})...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast, issues := env.Compile(testCase.expression)
+			if issues != nil {
+				t.Fatalf("unexpected compilation issues: %v", issues)
+			}
+			prg, err := env.Program(ast, cel.CostTracking(costEstimator{testCase.library}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, details, err := prg.Eval(cel.NoVars())
+			if len(testCase.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.Value() != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, out.Value())
+			}
+			if testCase.cost > 0 && *details.ActualCost() < testCase.cost {
+				t.Errorf("expected a cost of at least %d, got %d", testCase.cost, *details.ActualCost())
+			}
+		})
+	}
+}
+
+func TestLookupCheckExpression(t *testing.T) {
+	l := newTestLookupLibrary(t)
+	env, err := cel.NewEnv(EnvOptions([]Library{l})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testCase := range []struct {
+		expression string
+		warnings   int
+	}{
+		{expression: `lookup("v1/configmaps", "team-a", "a") != null`},
+		{expression: `lookup("v1/secrets", "team-a", "a") != null`, warnings: 1},
+		{expression: `list("v1/secrets", "", "").size() + list("apps/v1/deployments", "", "").size() > 0`, warnings: 2},
+		{expression: `lookup("configmaps", "team-a", "a") != null`, warnings: 1},
+	} {
+		t.Run(testCase.expression, func(t *testing.T) {
+			ast, issues := env.Parse(testCase.expression)
+			if issues != nil {
+				t.Fatalf("unexpected issues: %v", issues)
+			}
+			if warnings := l.CheckExpression(ast); len(warnings) != testCase.warnings {
+				t.Errorf("expected %d warnings, got %v", testCase.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestToUnstructuredCopies(t *testing.T) {
+	for _, obj := range []runtime.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "a"}, Data: map[string]string{"key": "a"}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"namespace": "team-a", "name": "a"},
+			"data":       map[string]interface{}{"key": "a"},
+		}},
+	} {
+		t.Run(fmt.Sprintf("%T", obj), func(t *testing.T) {
+			original := obj.DeepCopyObject()
+			object, err := toUnstructured(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			object["data"].(map[string]interface{})["key"] = "b"
+			if !apiequality.Semantic.DeepEqual(obj, original) {
+				t.Errorf("expected the object of the informer to be unchanged, got %v", obj)
+			}
+		})
+	}
+}
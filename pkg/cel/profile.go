@@ -0,0 +1,232 @@
+package cel
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	expressionCost = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "expression_cost",
+			Help:           "Runtime CEL cost of the evaluations of expressions, labeled by policy and expression.",
+			Buckets:        metrics.ExponentialBuckets(1, 4, 11),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"policy", "expression"},
+	)
+	expressionDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "expression_duration_seconds",
+			Help:           "Evaluation time of expressions in seconds, labeled by policy and expression.",
+			Buckets:        metrics.ExponentialBuckets(0.00001, 4, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"policy", "expression"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(expressionCost)
+	legacyregistry.MustRegister(expressionDuration)
+}
+
+// ExpressionProfile summarizes the evaluations of an expression of a policy.
+type ExpressionProfile struct {
+	// FieldRef is the path to the expression in the policy, such as
+	// spec.validations[0].expression.
+	FieldRef     string
+	Evaluations  int64
+	TotalCost    uint64
+	MaxCost      uint64
+	TotalElapsed time.Duration
+	MaxElapsed   time.Duration
+}
+
+// AverageCost returns the average runtime cost of an evaluation.
+func (p *ExpressionProfile) AverageCost() uint64 {
+	if p.Evaluations == 0 {
+		return 0
+	}
+	return p.TotalCost / uint64(p.Evaluations)
+}
+
+// AverageElapsed returns the average evaluation time.
+func (p *ExpressionProfile) AverageElapsed() time.Duration {
+	if p.Evaluations == 0 {
+		return 0
+	}
+	return p.TotalElapsed / time.Duration(p.Evaluations)
+}
+
+type policyProfile struct {
+	generation  int64
+	expressions map[string]*ExpressionProfile
+}
+
+// Profiles records the runtime cost and evaluation time of the expressions
+// of policies, into metrics and into profiles which summarize the
+// evaluations since a policy last changed.
+type Profiles struct {
+	mutex    sync.Mutex
+	policies map[string]*policyProfile
+}
+
+// NewProfiles returns empty Profiles.
+func NewProfiles() *Profiles {
+	return &Profiles{policies: map[string]*policyProfile{}}
+}
+
+// Compiler returns a FilterCompiler which compiles expressions with compiler,
+// and records the evaluations of the i-th expression as the one at
+// fieldRef(i) of the given generation of the policy.
+func (p *Profiles) Compiler(compiler Compiler, policy string, generation int64, fieldRef func(i int) string) plugincel.FilterCompiler {
+	return &profilingCompiler{
+		Compiler:   compiler,
+		profiles:   p,
+		policy:     policy,
+		generation: generation,
+		fieldRef:   fieldRef,
+	}
+}
+
+func (p *Profiles) observe(policy string, generation int64, fieldRef string, cost uint64, elapsed time.Duration) {
+	expressionCost.WithLabelValues(policy, fieldRef).Observe(float64(cost))
+	expressionDuration.WithLabelValues(policy, fieldRef).Observe(elapsed.Seconds())
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	profile, ok := p.policies[policy]
+	if !ok || profile.generation < generation {
+		// The policy changed, its previous evaluations no longer matter.
+		profile = &policyProfile{generation: generation, expressions: map[string]*ExpressionProfile{}}
+		p.policies[policy] = profile
+	} else if profile.generation > generation {
+		return
+	}
+	e, ok := profile.expressions[fieldRef]
+	if !ok {
+		e = &ExpressionProfile{FieldRef: fieldRef}
+		profile.expressions[fieldRef] = e
+	}
+	e.Evaluations++
+	e.TotalCost += cost
+	e.TotalElapsed += elapsed
+	if cost > e.MaxCost {
+		e.MaxCost = cost
+	}
+	if elapsed > e.MaxElapsed {
+		e.MaxElapsed = elapsed
+	}
+}
+
+// Top returns up to n profiles of the expressions of the given generation of
+// the policy with the highest average cost.
+func (p *Profiles) Top(policy string, generation int64, n int) []ExpressionProfile {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	profile, ok := p.policies[policy]
+	if !ok || profile.generation != generation {
+		return nil
+	}
+	result := make([]ExpressionProfile, 0, len(profile.expressions))
+	for _, e := range profile.expressions {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].AverageCost() != result[j].AverageCost() {
+			return result[i].AverageCost() > result[j].AverageCost()
+		}
+		if result[i].AverageElapsed() != result[j].AverageElapsed() {
+			return result[i].AverageElapsed() > result[j].AverageElapsed()
+		}
+		return result[i].FieldRef < result[j].FieldRef
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// Retain forgets the profiles and metrics of all policies but the given ones.
+func (p *Profiles) Retain(policies map[string]bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for policy := range p.policies {
+		if !policies[policy] {
+			expressionCost.DeletePartialMatch(map[string]string{"policy": policy})
+			expressionDuration.DeletePartialMatch(map[string]string{"policy": policy})
+			delete(p.policies, policy)
+		}
+	}
+}
+
+type profilingCompiler struct {
+	Compiler
+	profiles   *Profiles
+	policy     string
+	generation int64
+	fieldRef   func(i int) string
+}
+
+func (c *profilingCompiler) Compile(expressionAccessors []plugincel.ExpressionAccessor, options plugincel.OptionalVariableDeclarations, perCallLimit uint64) plugincel.Filter {
+	compilationResults := make([]plugincel.CompilationResult, len(expressionAccessors))
+	for i, expressionAccessor := range expressionAccessors {
+		if expressionAccessor == nil {
+			continue
+		}
+		compilationResults[i] = c.CompileExpression(expressionAccessor, options, perCallLimit)
+		if compilationResults[i].Program != nil {
+			compilationResults[i].Program = &profiledProgram{
+				Program:  compilationResults[i].Program,
+				observe:  c.profiles.observe,
+				policy:   c.policy,
+				gen:      c.generation,
+				fieldRef: c.fieldRef(i),
+			}
+		}
+	}
+	return plugincel.NewFilter(compilationResults)
+}
+
+// profiledProgram records the runtime cost and evaluation time of a program.
+type profiledProgram struct {
+	cel.Program
+	observe  func(policy string, generation int64, fieldRef string, cost uint64, elapsed time.Duration)
+	policy   string
+	gen      int64
+	fieldRef string
+}
+
+func (p *profiledProgram) Eval(input interface{}) (ref.Val, *cel.EvalDetails, error) {
+	start := time.Now()
+	val, details, err := p.Program.Eval(input)
+	p.record(details, time.Since(start))
+	return val, details, err
+}
+
+func (p *profiledProgram) ContextEval(ctx context.Context, input interface{}) (ref.Val, *cel.EvalDetails, error) {
+	start := time.Now()
+	val, details, err := p.Program.ContextEval(ctx, input)
+	p.record(details, time.Since(start))
+	return val, details, err
+}
+
+func (p *profiledProgram) record(details *cel.EvalDetails, elapsed time.Duration) {
+	var cost uint64
+	if details != nil && details.ActualCost() != nil {
+		cost = *details.ActualCost()
+	}
+	p.observe(p.policy, p.gen, p.fieldRef, cost, elapsed)
+}
@@ -0,0 +1,72 @@
+package cel
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fieldRefs(profiles []ExpressionProfile) []string {
+	var refs []string
+	for _, p := range profiles {
+		refs = append(refs, p.FieldRef)
+	}
+	return refs
+}
+
+func TestProfilesTop(t *testing.T) {
+	p := NewProfiles()
+	p.observe("policy", 1, "spec.validations[0].expression", 10, time.Millisecond)
+	p.observe("policy", 1, "spec.validations[0].expression", 30, 3*time.Millisecond)
+	p.observe("policy", 1, "spec.validations[1].expression", 20, time.Millisecond)
+	p.observe("policy", 1, "spec.validations[2].expression", 20, 3*time.Millisecond)
+	p.observe("policy", 1, "spec.validations[3].expression", 5, time.Millisecond)
+
+	// By average cost, then by average evaluation time.
+	top := p.Top("policy", 1, 3)
+	expected := []string{"spec.validations[2].expression", "spec.validations[0].expression", "spec.validations[1].expression"}
+	if !reflect.DeepEqual(fieldRefs(top), expected) {
+		t.Errorf("expected %v, got %v", expected, fieldRefs(top))
+	}
+	if second := top[1]; second.Evaluations != 2 || second.AverageCost() != 20 || second.MaxCost != 30 || second.AverageElapsed() != 2*time.Millisecond || second.MaxElapsed != 3*time.Millisecond {
+		t.Errorf("unexpected profile %+v", second)
+	}
+	if top := p.Top("other", 1, 3); top != nil {
+		t.Errorf("expected no profiles of an unknown policy, got %v", top)
+	}
+}
+
+func TestProfilesGenerations(t *testing.T) {
+	p := NewProfiles()
+	p.observe("policy", 1, "spec.validations[0].expression", 10, time.Millisecond)
+
+	// A new generation resets the profiles of the policy.
+	p.observe("policy", 2, "spec.validations[1].expression", 10, time.Millisecond)
+	if top := p.Top("policy", 1, 5); top != nil {
+		t.Errorf("expected no profiles of the previous generation, got %v", top)
+	}
+	expected := []string{"spec.validations[1].expression"}
+	if top := p.Top("policy", 2, 5); !reflect.DeepEqual(fieldRefs(top), expected) {
+		t.Errorf("expected %v, got %v", expected, fieldRefs(top))
+	}
+
+	// Evaluations of the previous generation still in flight are ignored.
+	p.observe("policy", 1, "spec.validations[0].expression", 10, time.Millisecond)
+	if top := p.Top("policy", 2, 5); !reflect.DeepEqual(fieldRefs(top), expected) {
+		t.Errorf("expected %v, got %v", expected, fieldRefs(top))
+	}
+}
+
+func TestProfilesRetain(t *testing.T) {
+	p := NewProfiles()
+	p.observe("deleted", 1, "spec.validations[0].expression", 10, time.Millisecond)
+	p.observe("team-a/kept", 1, "spec.validations[0].expression", 10, time.Millisecond)
+
+	p.Retain(map[string]bool{"team-a/kept": true})
+	if top := p.Top("deleted", 1, 5); top != nil {
+		t.Errorf("expected the profiles of the deleted policy to be dropped, got %v", top)
+	}
+	if top := p.Top("team-a/kept", 1, 5); len(top) != 1 {
+		t.Errorf("expected the profiles of the retained policy to be kept, got %v", top)
+	}
+}
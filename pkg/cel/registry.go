@@ -0,0 +1,59 @@
+package cel
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Libraries is a registry of the Libraries which may be enabled by name when
+// the shim starts.
+type Libraries struct {
+	mutex    sync.Mutex
+	registry map[string]Library
+}
+
+// NewLibraries returns an empty registry of Libraries.
+func NewLibraries() *Libraries {
+	return &Libraries{registry: map[string]Library{}}
+}
+
+// Register adds a library to the registry. Registering two libraries with
+// the same name is a programming error.
+func (l *Libraries) Register(name string, library Library) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, found := l.registry[name]; found {
+		klog.Fatalf("CEL library %q was registered twice", name)
+	}
+	l.registry[name] = library
+}
+
+// Registered returns the sorted names of the registered libraries.
+func (l *Libraries) Registered() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	names := make([]string, 0, len(l.registry))
+	for name := range l.registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the libraries with the given names.
+func (l *Libraries) Get(names ...string) ([]Library, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	libraries := make([]Library, 0, len(names))
+	for _, name := range names {
+		library, found := l.registry[name]
+		if !found {
+			return nil, fmt.Errorf("unknown CEL library %q", name)
+		}
+		libraries = append(libraries, library)
+	}
+	return libraries, nil
+}
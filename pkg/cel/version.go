@@ -0,0 +1,90 @@
+package cel
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+
+	"k8s.io/apiserver/pkg/cel/library"
+)
+
+// EnvironmentVersion identifies the functions and behavior of the CEL
+// environment expressions are compiled in. An expression which compiles in
+// one version of the environment compiles in all later versions.
+//
+// Bumping the vendored k8s.io/apiserver or cel-go, or adding a Library, must
+// not change the environment of existing versions. New functions are
+// introduced with a new version instead, so that replicas which run
+// different releases of the shim agree on which expressions are valid.
+type EnvironmentVersion int32
+
+const (
+	// EnvironmentV1 is the environment of k8s.io/apiserver v0.27: the CEL
+	// standard library, the Kubernetes URL, regex, list and authorizer
+	// libraries, and the strings extension.
+	EnvironmentV1 EnvironmentVersion = 1
+	// EnvironmentV2 adds the libraries of the shim, such as those of
+	// k8s.io/cel-admission-webhook/pkg/cel/library and the LookupLibrary.
+	EnvironmentV2 EnvironmentVersion = 2
+
+	// CurrentEnvironmentVersion is the latest version of the environment.
+	// Stored policies are always compiled in this version, so that they keep
+	// working whatever version they were written against.
+	CurrentEnvironmentVersion = EnvironmentV2
+)
+
+// versionedOptions are options of the environment, and the version of the
+// environment they were introduced in.
+type versionedOptions struct {
+	introducedVersion EnvironmentVersion
+	options           []cel.EnvOption
+}
+
+// baseOptions are the options of the environment besides the ones of the
+// libraries of the shim. Never change existing entries, append new ones.
+var baseOptions = []versionedOptions{
+	{
+		introducedVersion: EnvironmentV1,
+		options: []cel.EnvOption{
+			library.URLs(),
+			library.Regex(),
+			library.Lists(),
+			library.Authz(),
+			ext.Strings(),
+		},
+	},
+}
+
+// BaseEnvOptions returns the options of the given version of the environment,
+// besides the ones of the libraries of the shim.
+func BaseEnvOptions(version EnvironmentVersion) []cel.EnvOption {
+	var opts []cel.EnvOption
+	for _, o := range baseOptions {
+		if o.introducedVersion <= version {
+			opts = append(opts, o.options...)
+		}
+	}
+	return opts
+}
+
+// LibrariesOf returns the libraries which are part of the given version of
+// the environment.
+func LibrariesOf(version EnvironmentVersion, libraries []Library) []Library {
+	var result []Library
+	for _, l := range libraries {
+		if l.IntroducedVersion() <= version {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// ValidateEnvironmentVersion returns an error if the version is not a known
+// version of the environment.
+func ValidateEnvironmentVersion(version EnvironmentVersion) error {
+	if version < EnvironmentV1 || version > CurrentEnvironmentVersion {
+		return fmt.Errorf("unknown CEL environment version %d, expected a version from %d to %d", version, EnvironmentV1, CurrentEnvironmentVersion)
+	}
+	return nil
+}
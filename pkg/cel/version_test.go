@@ -0,0 +1,31 @@
+package cel
+
+import (
+	"testing"
+)
+
+func TestLibrariesOf(t *testing.T) {
+	l := newTestLookupLibrary(t)
+	if libraries := LibrariesOf(EnvironmentV1, []Library{l}); len(libraries) != 0 {
+		t.Errorf("expected no libraries in version 1, got %v", libraries)
+	}
+	if libraries := LibrariesOf(EnvironmentV2, []Library{l}); len(libraries) != 1 {
+		t.Errorf("expected the lookup library in version 2, got %v", libraries)
+	}
+}
+
+func TestValidateEnvironmentVersion(t *testing.T) {
+	for _, testCase := range []struct {
+		version   EnvironmentVersion
+		expectErr bool
+	}{
+		{version: 0, expectErr: true},
+		{version: EnvironmentV1},
+		{version: EnvironmentV2},
+		{version: CurrentEnvironmentVersion + 1, expectErr: true},
+	} {
+		if err := ValidateEnvironmentVersion(testCase.version); (err != nil) != testCase.expectErr {
+			t.Errorf("version %d: expected error %v, got %v", testCase.version, testCase.expectErr, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/config/v1alpha1"
+)
+
+// Load reads, defaults and validates the configuration file at path. The
+// namespace and service account of the exemptions default to the
+// POD_NAMESPACE and POD_SERVICE_ACCOUNT environment variables, like the flags
+// the file supersedes, and before the fields defaulted from them.
+func Load(path string) (*v1alpha1.CELWebhookConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &v1alpha1.CELWebhookConfiguration{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if len(config.Exemptions.Namespace) == 0 {
+		config.Exemptions.Namespace = os.Getenv("POD_NAMESPACE")
+	}
+	if len(config.Exemptions.ServiceAccount) == 0 {
+		config.Exemptions.ServiceAccount = os.Getenv("POD_SERVICE_ACCOUNT")
+	}
+	v1alpha1.SetDefaults(config)
+	if errs := v1alpha1.Validate(config); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, errs.ToAggregate())
+	}
+	return config, nil
+}
+
+// RestartRequired returns the paths of the fields which changed between the
+// configurations and only take effect once the webhook restarts.
+func RestartRequired(old, new *v1alpha1.CELWebhookConfiguration) []string {
+	var paths []string
+	changed := func(path *field.Path, oldValue, newValue interface{}) {
+		if !reflect.DeepEqual(oldValue, newValue) {
+			paths = append(paths, path.String())
+		}
+	}
+	changed(field.NewPath("serving"), old.Serving, new.Serving)
+	changed(field.NewPath("informers"), old.Informers, new.Informers)
+	admission := field.NewPath("admission")
+	changed(admission.Child("parallel"), old.Admission.Parallel, new.Admission.Parallel)
+	changed(admission.Child("validators"), old.Admission.Validators, new.Admission.Validators)
+	cel := field.NewPath("cel")
+	changed(cel.Child("libraries"), old.CEL.Libraries, new.CEL.Libraries)
+	changed(cel.Child("compatibilityVersion"), old.CEL.CompatibilityVersion, new.CEL.CompatibilityVersion)
+	changed(cel.Child("lookupResources"), old.CEL.LookupResources, new.CEL.LookupResources)
+	changed(field.NewPath("leaderElection"), old.LeaderElection, new.LeaderElection)
+	changed(field.NewPath("replicaStatus"), old.ReplicaStatus, new.ReplicaStatus)
+	changed(field.NewPath("tracing"), old.Tracing, new.Tracing)
+	return paths
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDefaultsFromEnvironment(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "cel-shim")
+	t.Setenv("POD_SERVICE_ACCOUNT", "cel-webhook")
+
+	for _, testCase := range []struct {
+		name                   string
+		config                 string
+		expectedNamespace      string
+		expectedServiceAccount string
+	}{
+		{
+			name:                   "empty",
+			config:                 "apiVersion: config.cel-admission-webhook.x-k8s.io/v1alpha1\nkind: CELWebhookConfiguration\n",
+			expectedNamespace:      "cel-shim",
+			expectedServiceAccount: "cel-webhook",
+		},
+		{
+			name:                   "configured",
+			config:                 "apiVersion: config.cel-admission-webhook.x-k8s.io/v1alpha1\nkind: CELWebhookConfiguration\nexemptions:\n  namespace: other\n  serviceAccount: other-webhook\n",
+			expectedNamespace:      "other",
+			expectedServiceAccount: "other-webhook",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(testCase.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Exemptions.Namespace != testCase.expectedNamespace {
+				t.Errorf("expected namespace %q, got %q", testCase.expectedNamespace, config.Exemptions.Namespace)
+			}
+			if config.Exemptions.ServiceAccount != testCase.expectedServiceAccount {
+				t.Errorf("expected service account %q, got %q", testCase.expectedServiceAccount, config.Exemptions.ServiceAccount)
+			}
+			// Leases are created in the namespace of the webhook.
+			if config.LeaderElection.LeaseNamespace != testCase.expectedNamespace {
+				t.Errorf("expected lease namespace %q, got %q", testCase.expectedNamespace, config.LeaderElection.LeaseNamespace)
+			}
+			if config.ReplicaStatus.LeaseNamespace != testCase.expectedNamespace {
+				t.Errorf("expected replica status lease namespace %q, got %q", testCase.expectedNamespace, config.ReplicaStatus.LeaseNamespace)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Package config loads the configuration file of the webhook, and applies its
+// changes while the webhook runs.
+package config
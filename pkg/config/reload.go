@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/config/v1alpha1"
+)
+
+// pollInterval is the interval at which the configuration file is checked for
+// changes.
+const pollInterval = 2 * time.Second
+
+var (
+	reloads = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "config_reloads_total",
+			Help:           "Reloads of the configuration file, labeled by whether it was applied or rejected as invalid.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	restartRequired = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "config_restart_required",
+			Help:           "1 when the configuration file changed settings which only take effect once the webhook restarts.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(reloads, restartRequired)
+}
+
+// ApplyFunc applies the reloadable settings of a configuration to the running
+// webhook. The configuration is rejected if it returns an error.
+type ApplyFunc func(*v1alpha1.CELWebhookConfiguration) error
+
+// Reloader watches the configuration file and applies its changes.
+type Reloader struct {
+	path  string
+	apply ApplyFunc
+
+	lock sync.Mutex
+	// running is the configuration the webhook started with.
+	running *v1alpha1.CELWebhookConfiguration
+	modTime time.Time
+	// restartRequired are the paths of the fields changed since the webhook
+	// started which require a restart.
+	restartRequired []string
+	lastError       error
+}
+
+// NewReloader returns a Reloader of the configuration file at path, which the
+// running configuration was loaded from.
+func NewReloader(path string, running *v1alpha1.CELWebhookConfiguration, apply ApplyFunc) *Reloader {
+	r := &Reloader{path: path, running: running, apply: apply}
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return r
+}
+
+// Run checks the configuration file for changes until the context is done.
+func (r *Reloader) Run(ctx context.Context) error {
+	wait.UntilWithContext(ctx, r.reloadIfChanged, pollInterval)
+	return nil
+}
+
+func (r *Reloader) reloadIfChanged(ctx context.Context) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		klog.ErrorS(err, "Failed to check the configuration file for changes", "path", r.path)
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if info.ModTime().Equal(r.modTime) {
+		return
+	}
+	r.modTime = info.ModTime()
+
+	config, err := Load(r.path)
+	if err == nil {
+		err = r.apply(config)
+	}
+	if err != nil {
+		reloads.WithLabelValues("rejected").Inc()
+		r.lastError = err
+		klog.ErrorS(err, "Rejected the changes to the configuration file, the previous configuration remains in effect", "path", r.path)
+		return
+	}
+	reloads.WithLabelValues("applied").Inc()
+	r.lastError = nil
+
+	r.restartRequired = RestartRequired(r.running, config)
+	if len(r.restartRequired) > 0 {
+		restartRequired.Set(1)
+		klog.InfoS("Applied the reloadable changes to the configuration file, the others take effect after a restart", "path", r.path, "restartRequired", r.restartRequired)
+	} else {
+		restartRequired.Set(0)
+		klog.InfoS("Applied the changes to the configuration file", "path", r.path)
+	}
+}
+
+// Name implements the health check of the webhook.
+func (r *Reloader) Name() string {
+	return "config"
+}
+
+// Check reports the fields which require a restart, and the last rejected
+// change to the configuration file. Neither makes the webhook unhealthy, since
+// it keeps running with its previous configuration.
+func (r *Reloader) Check() (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var state []string
+	if len(r.restartRequired) > 0 {
+		state = append(state, fmt.Sprintf("restart required for %s", strings.Join(r.restartRequired, ", ")))
+	}
+	if r.lastError != nil {
+		state = append(state, fmt.Sprintf("last change rejected: %v", r.lastError))
+	}
+	if len(state) == 0 {
+		return "up to date", nil
+	}
+	return strings.Join(state, "; "), nil
+}
@@ -0,0 +1,152 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/controller"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned"
+	"k8s.io/cel-admission-webhook/pkg/generated/informers/externalversions"
+)
+
+const (
+	// ReasonEnforcementStarted is the reason of the event emitted when Deny
+	// becomes one of the effective validationActions of a binding.
+	ReasonEnforcementStarted = "EnforcementStarted"
+)
+
+type bindingStatusController struct {
+	ctx          context.Context
+	policyClient versioned.Interface
+	recorder     record.EventRecorder
+	controllers  []controller.Interface
+}
+
+// NewBindingStatusController returns a controller which keeps the status of
+// ValidatingAdmissionPolicyBindings and NamespacedValidatingAdmissionPolicyBindings
+// up to date with the validationActions in effect according to their
+// validationActionsSchedule, and records an event whenever a binding starts
+// to deny requests. Only the leader writes the status, unless leadership is
+// nil.
+func NewBindingStatusController(
+	policyFactory externalversions.SharedInformerFactory,
+	policyClient versioned.Interface,
+	recorder record.EventRecorder,
+	leadership controller.Leadership,
+) controller.Interface {
+	c := &bindingStatusController{
+		policyClient: policyClient,
+		recorder:     recorder,
+	}
+	c.controllers = []controller.Interface{
+		controller.New[*v1alpha1.ValidatingAdmissionPolicyBinding](
+			controller.NewInformer[*v1alpha1.ValidatingAdmissionPolicyBinding](
+				policyFactory.Admissionregistration().V1alpha1().ValidatingAdmissionPolicyBindings().Informer()),
+			c.reconcile,
+			controller.ControllerOptions{Name: "binding-status-controller", Leadership: leadership},
+		),
+		controller.New[*v1alpha1.NamespacedValidatingAdmissionPolicyBinding](
+			controller.NewInformer[*v1alpha1.NamespacedValidatingAdmissionPolicyBinding](
+				policyFactory.Admissionregistration().V1alpha1().NamespacedValidatingAdmissionPolicyBindings().Informer()),
+			c.reconcileNamespaced,
+			controller.ControllerOptions{Name: "namespaced-binding-status-controller", Leadership: leadership},
+		),
+	}
+	return c
+}
+
+func (c *bindingStatusController) Run(ctx context.Context) error {
+	c.ctx = ctx
+
+	errs := make([]error, len(c.controllers))
+	wg := sync.WaitGroup{}
+	for i, ctrl := range c.controllers {
+		i, ctrl := i, ctrl
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ctrl.Run(ctx)
+		}()
+	}
+	wg.Wait()
+	return errs[0]
+}
+
+func (c *bindingStatusController) reconcile(namespace, name string, binding *v1alpha1.ValidatingAdmissionPolicyBinding) error {
+	if binding == nil {
+		// Deleted, nothing to report.
+		return nil
+	}
+	return c.reconcileStatus(binding, binding.Generation, &binding.Spec, binding.Status, func(status v1alpha1.ValidatingAdmissionPolicyBindingStatus) error {
+		updated := binding.DeepCopy()
+		updated.Status = status
+		_, err := c.policyClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicyBindings().UpdateStatus(c.ctx, updated, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *bindingStatusController) reconcileNamespaced(namespace, name string, binding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding) error {
+	if binding == nil {
+		// Deleted, nothing to report.
+		return nil
+	}
+	return c.reconcileStatus(binding, binding.Generation, &binding.Spec, binding.Status, func(status v1alpha1.ValidatingAdmissionPolicyBindingStatus) error {
+		updated := binding.DeepCopy()
+		updated.Status = status
+		_, err := c.policyClient.AdmissionregistrationV1alpha1().NamespacedValidatingAdmissionPolicyBindings(namespace).UpdateStatus(c.ctx, updated, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *bindingStatusController) reconcileStatus(
+	binding runtime.Object,
+	generation int64,
+	spec *v1alpha1.ValidatingAdmissionPolicyBindingSpec,
+	oldStatus v1alpha1.ValidatingAdmissionPolicyBindingStatus,
+	updateStatus func(v1alpha1.ValidatingAdmissionPolicyBindingStatus) error,
+) error {
+	now := time.Now()
+	actions, next := spec.EffectiveValidationActions(now)
+	status := v1alpha1.ValidatingAdmissionPolicyBindingStatus{
+		ObservedGeneration:         generation,
+		EffectiveValidationActions: actions,
+		NextTransitionTime:         next,
+	}
+
+	if !apiequality.Semantic.DeepEqual(oldStatus, status) {
+		if err := updateStatus(status); err != nil {
+			return fmt.Errorf("failed to update status of binding: %w", err)
+		}
+
+		// Only report actual transitions: a binding whose status was never
+		// written before did not start denying, it was created denying.
+		if hasAction(actions, v1alpha1.Deny) && oldStatus.ObservedGeneration != 0 && !hasAction(oldStatus.EffectiveValidationActions, v1alpha1.Deny) {
+			c.recorder.Eventf(binding, corev1.EventTypeNormal, ReasonEnforcementStarted,
+				"Binding now denies requests violating policy '%s', effective validationActions: %v", spec.PolicyName, actions)
+		}
+	}
+
+	if next != nil {
+		// Reconcile again once the next entry of the schedule takes effect.
+		return controller.RequeueAfter(next.Sub(now))
+	}
+	return nil
+}
+
+func hasAction(actions []v1alpha1.ValidationAction, action v1alpha1.ValidationAction) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/fake"
+)
+
+func TestReconcileBindingStatus(t *testing.T) {
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	for _, testCase := range []struct {
+		name     string
+		spec     v1alpha1.ValidatingAdmissionPolicyBindingSpec
+		status   v1alpha1.ValidatingAdmissionPolicyBindingStatus
+		expected []v1alpha1.ValidationAction
+		event    bool
+	}{
+		{
+			name:     "created-denying",
+			spec:     v1alpha1.ValidatingAdmissionPolicyBindingSpec{ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Deny}},
+			expected: []v1alpha1.ValidationAction{v1alpha1.Deny},
+		},
+		{
+			name: "created-with-passed-schedule",
+			spec: v1alpha1.ValidatingAdmissionPolicyBindingSpec{
+				ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Warn},
+				ValidationActionsSchedule: []v1alpha1.ScheduledValidationActions{
+					{After: past, ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Deny}},
+				},
+			},
+			expected: []v1alpha1.ValidationAction{v1alpha1.Deny},
+		},
+		{
+			name: "schedule-starts-denying",
+			spec: v1alpha1.ValidatingAdmissionPolicyBindingSpec{
+				ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Warn},
+				ValidationActionsSchedule: []v1alpha1.ScheduledValidationActions{
+					{After: past, ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Deny}},
+				},
+			},
+			status: v1alpha1.ValidatingAdmissionPolicyBindingStatus{
+				ObservedGeneration:         1,
+				EffectiveValidationActions: []v1alpha1.ValidationAction{v1alpha1.Warn},
+			},
+			expected: []v1alpha1.ValidationAction{v1alpha1.Deny},
+			event:    true,
+		},
+		{
+			name: "updated-to-deny",
+			spec: v1alpha1.ValidatingAdmissionPolicyBindingSpec{ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Deny}},
+			status: v1alpha1.ValidatingAdmissionPolicyBindingStatus{
+				ObservedGeneration:         1,
+				EffectiveValidationActions: []v1alpha1.ValidationAction{v1alpha1.Audit},
+			},
+			expected: []v1alpha1.ValidationAction{v1alpha1.Deny},
+			event:    true,
+		},
+		{
+			name: "already-denying",
+			spec: v1alpha1.ValidatingAdmissionPolicyBindingSpec{ValidationActions: []v1alpha1.ValidationAction{v1alpha1.Deny, v1alpha1.Audit}},
+			status: v1alpha1.ValidatingAdmissionPolicyBindingStatus{
+				ObservedGeneration:         1,
+				EffectiveValidationActions: []v1alpha1.ValidationAction{v1alpha1.Deny},
+			},
+			expected: []v1alpha1.ValidationAction{v1alpha1.Deny, v1alpha1.Audit},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			binding := &v1alpha1.ValidatingAdmissionPolicyBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "binding", Generation: 2},
+				Spec:       testCase.spec,
+				Status:     testCase.status,
+			}
+			client := fake.NewSimpleClientset(binding)
+			recorder := record.NewFakeRecorder(10)
+			c := &bindingStatusController{ctx: context.Background(), policyClient: client, recorder: recorder}
+
+			if err := c.reconcile("", binding.Name, binding); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			updated, err := client.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicyBindings().Get(context.Background(), binding.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.Status.ObservedGeneration != binding.Generation {
+				t.Errorf("expected observedGeneration %d, got %d", binding.Generation, updated.Status.ObservedGeneration)
+			}
+			if !reflect.DeepEqual(updated.Status.EffectiveValidationActions, testCase.expected) {
+				t.Errorf("expected effective actions %v, got %v", testCase.expected, updated.Status.EffectiveValidationActions)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if !testCase.event {
+					t.Errorf("unexpected event: %s", event)
+				}
+			default:
+				if testCase.event {
+					t.Errorf("expected an %s event", ReasonEnforcementStarted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+
+	admissionregistrationx "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/scheme"
+	"k8s.io/cel-admission-webhook/pkg/validatingadmissionpolicy"
+)
+
+// validatePolicyExpressions rejects writes of policies whose new expressions
+// do not compile in the compatibility version of the CEL environment, so that
+// every replica of the shim, including those of the previous release during
+// an upgrade, can evaluate them.
+func (c *celAdmissionPlugin) validatePolicyExpressions(a admission.Attributes) error {
+	if (a.GetOperation() != admission.Create && a.GetOperation() != admission.Update) || len(a.GetSubresource()) > 0 {
+		return nil
+	}
+
+	spec, err := policySpec(a.GetObject())
+	if err != nil {
+		return k8serrors.NewBadRequest(err.Error())
+	}
+	if spec == nil {
+		return nil
+	}
+	var oldSpec *admissionregistrationx.ValidatingAdmissionPolicySpec
+	if a.GetOperation() == admission.Update {
+		if oldSpec, err = policySpec(a.GetOldObject()); err != nil {
+			return k8serrors.NewBadRequest(err.Error())
+		}
+	}
+
+	compiler := c.newExpressionCompilers.ForNamespace(a.GetNamespace())
+	if errs := validatingadmissionpolicy.ValidateNewExpressions(compiler, spec, oldSpec); len(errs) > 0 {
+		return k8serrors.NewInvalid(a.GetKind().GroupKind(), a.GetName(), errs)
+	}
+	return nil
+}
+
+// policySpec returns the spec of a policy, or nil if the object is not a
+// policy.
+func policySpec(obj runtime.Object) (*admissionregistrationx.ValidatingAdmissionPolicySpec, error) {
+	// The objects of requests are unstructured until their type is needed.
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		if !scheme.Scheme.Recognizes(u.GroupVersionKind()) {
+			return nil, nil
+		}
+		typed, err := scheme.Scheme.New(u.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, fmt.Errorf("failed to decode %v: %w", u.GroupVersionKind(), err)
+		}
+		obj = typed
+	}
+	switch policy := obj.(type) {
+	case *admissionregistrationx.ValidatingAdmissionPolicy:
+		return &policy.Spec, nil
+	case *admissionregistrationx.NamespacedValidatingAdmissionPolicy:
+		return &policy.Spec, nil
+	default:
+		return nil, nil
+	}
+}
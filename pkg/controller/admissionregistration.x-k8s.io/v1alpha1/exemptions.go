@@ -0,0 +1,129 @@
+package v1alpha1
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	admissionregistrationx "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// DefaultExemptNamespaces are the namespaces of the control plane which
+// policies may never lock out.
+var DefaultExemptNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Exemptions describe requests which are admitted without evaluating any
+// policy, so that a policy can never lock the shim or the control plane out of
+// the cluster.
+//
+// Regardless of the configuration, requests for the CRDs of the shim, for the
+// EnforcementOverrides and PolicyExceptions which relax policies and for
+// webhook configurations are always exempt.
+type Exemptions struct {
+	// Namespaces whose objects are exempt, including the Namespace objects
+	// themselves.
+	Namespaces []string
+
+	// Usernames of users whose requests are exempt, such as the service
+	// account of the shim.
+	Usernames []string
+
+	// Objects whose requests are exempt, such as the Deployment of the shim
+	// and the Secret of its certificate.
+	Objects []ExemptObject
+}
+
+// ExemptObject identifies an object whose requests are exempt.
+type ExemptObject struct {
+	Resource  schema.GroupResource
+	Namespace string
+	Name      string
+}
+
+// NewExemptions returns the exemptions for a shim running as the given
+// service account of the given namespace, in addition to the given exempt
+// namespaces. Empty values are ignored.
+//
+// The namespace of the shim itself is not exempt, since other workloads may
+// run in it: the objects of the shim are listed in Objects instead.
+func NewExemptions(namespace, serviceAccount string, namespaces ...string) Exemptions {
+	exemptions := Exemptions{}
+	for _, ns := range namespaces {
+		if len(ns) > 0 {
+			exemptions.Namespaces = append(exemptions.Namespaces, ns)
+		}
+	}
+	if len(namespace) > 0 && len(serviceAccount) > 0 {
+		exemptions.Usernames = append(exemptions.Usernames, serviceaccount.MakeUsername(namespace, serviceAccount))
+	}
+	return exemptions
+}
+
+const (
+	exemptionReasonNamespace     = "namespace"
+	exemptionReasonUser          = "user"
+	exemptionReasonObject        = "object"
+	exemptionReasonCRD           = "crd"
+	exemptionReasonBreakGlass    = "break_glass"
+	exemptionReasonWebhookConfig = "webhook_configuration"
+)
+
+var exemptedRequests = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Namespace:      "cel_admission_webhook",
+		Name:           "exempted_requests_total",
+		Help:           "Admission requests which were admitted without evaluating policies because they are exempt, labeled by the reason of the exemption.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"reason"},
+)
+
+func init() {
+	legacyregistry.MustRegister(exemptedRequests)
+}
+
+type exemptionMatcher struct {
+	namespaces sets.Set[string]
+	usernames  sets.Set[string]
+	objects    sets.Set[ExemptObject]
+}
+
+func newExemptionMatcher(exemptions Exemptions) exemptionMatcher {
+	return exemptionMatcher{
+		namespaces: sets.New(exemptions.Namespaces...),
+		usernames:  sets.New(exemptions.Usernames...),
+		objects:    sets.New(exemptions.Objects...),
+	}
+}
+
+// exempt returns the reason for which the request is exempt, or the empty
+// string if it is not.
+func (m exemptionMatcher) exempt(attr admission.Attributes) string {
+	resource := attr.GetResource()
+	switch {
+	case resource.Group == "apiextensions.k8s.io" && resource.Resource == "customresourcedefinitions" &&
+		strings.HasSuffix(attr.GetName(), "."+admissionregistrationx.GroupName):
+		return exemptionReasonCRD
+	case resource.Group == admissionregistrationx.GroupName &&
+		(resource.Resource == "enforcementoverrides" || resource.Resource == "policyexceptions"):
+		// Policies may never prevent relaxing themselves.
+		return exemptionReasonBreakGlass
+	case resource.Group == "admissionregistration.k8s.io" &&
+		(resource.Resource == "validatingwebhookconfigurations" || resource.Resource == "mutatingwebhookconfigurations"):
+		return exemptionReasonWebhookConfig
+	case m.namespaces.Has(attr.GetNamespace()):
+		return exemptionReasonNamespace
+	case resource.Group == "" && resource.Resource == "namespaces" && m.namespaces.Has(attr.GetName()):
+		return exemptionReasonNamespace
+	case attr.GetUserInfo() != nil && m.usernames.Has(attr.GetUserInfo().GetName()):
+		return exemptionReasonUser
+	case len(attr.GetName()) > 0 && m.objects.Has(ExemptObject{Resource: resource.GroupResource(), Namespace: attr.GetNamespace(), Name: attr.GetName()}):
+		return exemptionReasonObject
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestExempt(t *testing.T) {
+	exemptions := NewExemptions("cel-shim", "cel-webhook", append([]string{""}, DefaultExemptNamespaces...)...)
+	exemptions.Objects = []ExemptObject{
+		{Resource: schema.GroupResource{Group: "apps", Resource: "deployments"}, Namespace: "cel-shim", Name: "cel-shim-webhook"},
+		{Resource: schema.GroupResource{Resource: "secrets"}, Namespace: "cel-shim", Name: "cel-shim-webhook.tls"},
+	}
+	matcher := newExemptionMatcher(exemptions)
+
+	attributes := func(resource schema.GroupVersionResource, namespace, name, username string) admission.Attributes {
+		return admission.NewAttributesRecord(nil, nil, schema.GroupVersionKind{}, namespace, name, resource, "", admission.Create, nil, false, &user.DefaultInfo{Name: username})
+	}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	for _, testCase := range []struct {
+		name       string
+		attributes admission.Attributes
+		expected   string
+	}{
+		{
+			name:       "workload",
+			attributes: attributes(pods, "default", "example", "alice"),
+		},
+		{
+			name:       "namespace-of-the-shim",
+			attributes: attributes(pods, "cel-shim", "example", "alice"),
+		},
+		{
+			name:       "control-plane-namespace",
+			attributes: attributes(pods, "kube-system", "example", "alice"),
+			expected:   exemptionReasonNamespace,
+		},
+		{
+			name:       "control-plane-namespace-object",
+			attributes: attributes(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, "", "kube-system", "alice"),
+			expected:   exemptionReasonNamespace,
+		},
+		{
+			name:       "service-account-of-the-shim",
+			attributes: attributes(schema.GroupVersionResource{Group: "coordination.k8s.io", Version: "v1", Resource: "leases"}, "cel-shim", "example", "system:serviceaccount:cel-shim:cel-webhook"),
+			expected:   exemptionReasonUser,
+		},
+		{
+			name:       "deployment-of-the-shim",
+			attributes: attributes(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "cel-shim", "cel-shim-webhook", "alice"),
+			expected:   exemptionReasonObject,
+		},
+		{
+			name:       "secret-of-the-shim",
+			attributes: attributes(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, "cel-shim", "cel-shim-webhook.tls", "alice"),
+			expected:   exemptionReasonObject,
+		},
+		{
+			name:       "other-deployment",
+			attributes: attributes(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "cel-shim", "example", "alice"),
+		},
+		{
+			name:       "same-name-in-other-namespace",
+			attributes: attributes(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "default", "cel-shim-webhook", "alice"),
+		},
+		{
+			name:       "same-name-of-other-resource",
+			attributes: attributes(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "cel-shim", "cel-shim-webhook", "alice"),
+		},
+		{
+			// An empty exempt namespace is ignored, rather than exempting
+			// cluster scoped requests.
+			name:       "cluster-scoped",
+			attributes: attributes(schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, "", "admin", "alice"),
+		},
+		{
+			name:       "other-service-account",
+			attributes: attributes(pods, "cel-shim", "example", "system:serviceaccount:cel-shim:default"),
+		},
+		{
+			name:       "crd-of-the-shim",
+			attributes: attributes(schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}, "", "enforcementoverrides.admissionregistration.x-k8s.io", "alice"),
+			expected:   exemptionReasonCRD,
+		},
+		{
+			name:       "other-crd",
+			attributes: attributes(schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}, "", "widgets.example.com", "alice"),
+		},
+		{
+			name:       "enforcement-override",
+			attributes: attributes(schema.GroupVersionResource{Group: "admissionregistration.x-k8s.io", Version: "v1alpha1", Resource: "enforcementoverrides"}, "", "break-glass", "alice"),
+			expected:   exemptionReasonBreakGlass,
+		},
+		{
+			name:       "policy-exception",
+			attributes: attributes(schema.GroupVersionResource{Group: "admissionregistration.x-k8s.io", Version: "v1alpha1", Resource: "policyexceptions"}, "default", "example", "alice"),
+			expected:   exemptionReasonBreakGlass,
+		},
+		{
+			name:       "webhook-configuration",
+			attributes: attributes(schema.GroupVersionResource{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "validatingwebhookconfigurations"}, "", "cel-shim.example.com", "alice"),
+			expected:   exemptionReasonWebhookConfig,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if reason := matcher.exempt(testCase.attributes); reason != testCase.expected {
+				t.Errorf("expected exemption reason %q, got %q", testCase.expected, reason)
+			}
+		})
+	}
+}
@@ -3,56 +3,125 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/admission"
-	"k8s.io/apiserver/pkg/admission/plugin/validatingadmissionpolicy"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	"k8s.io/apiserver/pkg/cel/openapi/resolver"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/tracing"
+
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/controller"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned"
+	"k8s.io/cel-admission-webhook/pkg/generated/informers/externalversions"
+	"k8s.io/cel-admission-webhook/pkg/validatingadmissionpolicy"
 )
 
 type ValidationInterface interface {
 	admission.ValidationInterface
 	Run(context.Context) error
 	HasSynced() bool
+	// Reconfigure replaces the settings of the plugin, for the requests
+	// handled from then on.
+	Reconfigure(Settings)
+	// LoadedGenerations returns the generations of the policies and
+	// bindings which requests are evaluated against.
+	LoadedGenerations() (policies, bindings map[string]int64)
+}
+
+// Settings are the settings of the plugin which may change while it runs.
+type Settings struct {
+	Exemptions  Exemptions
+	CostBudgets validatingadmissionpolicy.CostBudgets
+	// SyncTimeout is how long requests wait for the policies to be loaded
+	// before they are denied.
+	SyncTimeout time.Duration
+}
+
+// DefaultSyncTimeout is the default Settings.SyncTimeout.
+const DefaultSyncTimeout = time.Second
+
+// pluginSettings are the Settings of the plugin, prepared for requests.
+type pluginSettings struct {
+	exemptions  exemptionMatcher
+	syncTimeout time.Duration
 }
 
 type celAdmissionPlugin struct {
 	factory        informers.SharedInformerFactory
 	client         kubernetes.Interface
+	policyFactory  externalversions.SharedInformerFactory
+	policyClient   versioned.Interface
 	restMapper     meta.RESTMapper
 	schemaResolver resolver.SchemaResolver
 	dynamicClient  dynamic.Interface
 	authorizer     authorizer.Authorizer
 	evaluator      validatingadmissionpolicy.CELPolicyEvaluator
+
+	// settings are the pluginSettings currently in effect.
+	settings atomic.Value
+
+	// Compilers of new expressions of policies, in the compatibility version
+	// of the CEL environment.
+	newExpressionCompilers *webhookcel.FilterCompilers
 }
 
 func NewPlugin(
 	factory informers.SharedInformerFactory,
 	client kubernetes.Interface,
+	policyFactory externalversions.SharedInformerFactory,
+	policyClient versioned.Interface,
 	restMapper meta.RESTMapper,
 	schemaResolver resolver.SchemaResolver,
 	dynamicClient dynamic.Interface,
 	authorizer authorizer.Authorizer,
+	libraries []webhookcel.Library,
+	compatibilityVersion webhookcel.EnvironmentVersion,
+	leadership controller.Leadership,
+	recorder record.EventRecorder,
+	settings Settings,
 ) ValidationInterface {
-	return &celAdmissionPlugin{
+	c := &celAdmissionPlugin{
 		factory:        factory,
 		client:         client,
+		policyFactory:  policyFactory,
+		policyClient:   policyClient,
 		restMapper:     restMapper,
 		schemaResolver: schemaResolver,
 		dynamicClient:  dynamicClient,
 		authorizer:     authorizer,
 		evaluator: validatingadmissionpolicy.NewAdmissionController(
-			factory, client, restMapper, schemaResolver, dynamicClient, authorizer,
+			factory, client, policyFactory, policyClient, restMapper, schemaResolver, dynamicClient, authorizer, libraries, settings.CostBudgets, leadership, recorder,
 		),
+		newExpressionCompilers: webhookcel.NewFilterCompilers(compatibilityVersion, libraries...),
+	}
+	c.settings.Store(newPluginSettings(settings))
+	return c
+}
+
+func newPluginSettings(settings Settings) *pluginSettings {
+	return &pluginSettings{
+		exemptions:  newExemptionMatcher(settings.Exemptions),
+		syncTimeout: settings.SyncTimeout,
 	}
 }
 
+func (c *celAdmissionPlugin) Reconfigure(settings Settings) {
+	c.settings.Store(newPluginSettings(settings))
+	c.evaluator.SetCostBudgets(settings.CostBudgets)
+}
+
+func (c *celAdmissionPlugin) LoadedGenerations() (policies, bindings map[string]int64) {
+	return c.evaluator.LoadedGenerations()
+}
+
 func (c *celAdmissionPlugin) HasSynced() bool {
 	// l, e := c.client.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().List(context.TODO(), v1.ListOptions{})
 	// l2, e2 := c.client.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicyBindings().List(context.TODO(), v1.ListOptions{})
@@ -81,12 +150,23 @@ func (c *celAdmissionPlugin) Validate(
 	// isPolicyResource determines if an admission.Attributes object is describing
 	// the admission of a ValidatingAdmissionPolicy or a ValidatingAdmissionPolicyBinding
 	if isPolicyResource(a) {
+		return c.validatePolicyExpressions(a)
+	}
+
+	settings := c.settings.Load().(*pluginSettings)
+
+	// Never let a policy lock the shim or the control plane out
+	if reason := settings.exemptions.exempt(a); len(reason) > 0 {
+		exemptedRequests.WithContext(ctx).WithLabelValues(reason).Inc()
 		return
 	}
 
-	if err := wait.PollImmediateWithContext(ctx, 100*time.Millisecond, 1*time.Second, func(ctx context.Context) (done bool, err error) {
+	_, span := tracing.Start(ctx, "Wait for informers to sync")
+	err = wait.PollImmediateWithContext(ctx, 100*time.Millisecond, settings.syncTimeout, func(ctx context.Context) (done bool, err error) {
 		return c.HasSynced(), nil
-	}); err != nil {
+	})
+	span.End(500 * time.Millisecond)
+	if err != nil {
 		return admission.NewForbidden(a, fmt.Errorf("not yet ready to handle request"))
 	}
 
@@ -100,5 +180,10 @@ func isPolicyResource(attr admission.Attributes) bool {
 			return true
 		}
 	}
+	if gvk.Group == "admissionregistration.x-k8s.io" {
+		if gvk.Resource == "namespacedvalidatingadmissionpolicies" || gvk.Resource == "namespacedvalidatingadmissionpolicybindings" {
+			return true
+		}
+	}
 	return false
 }
@@ -0,0 +1,173 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationinformers "k8s.io/client-go/informers/coordination/v1"
+	"k8s.io/client-go/kubernetes"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/controller"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned"
+	"k8s.io/cel-admission-webhook/pkg/generated/informers/externalversions"
+	listers "k8s.io/cel-admission-webhook/pkg/generated/listers/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/replicastatus"
+)
+
+// policyReplicasPeriod is the interval at which the Loaded condition of
+// policies is updated.
+const policyReplicasPeriod = 5 * time.Second
+
+type policyReplicasController struct {
+	client                 kubernetes.Interface
+	policyClient           versioned.Interface
+	leaseLister            coordinationlisters.LeaseNamespaceLister
+	policyLister           listers.ValidatingAdmissionPolicyLister
+	namespacedPolicyLister listers.NamespacedValidatingAdmissionPolicyLister
+	synced                 []cache.InformerSynced
+	leadership             controller.Leadership
+}
+
+// NewPolicyReplicasController returns a controller which sets the Loaded
+// condition of ValidatingAdmissionPolicies and
+// NamespacedValidatingAdmissionPolicies from the generations published by
+// each replica in the Leases of the namespace, and deletes the Leases of the
+// replicas which stopped renewing them. Only the leader writes, unless
+// leadership is nil.
+func NewPolicyReplicasController(
+	client kubernetes.Interface,
+	leaseInformer coordinationinformers.LeaseInformer,
+	namespace string,
+	policyFactory externalversions.SharedInformerFactory,
+	policyClient versioned.Interface,
+	leadership controller.Leadership,
+) controller.Interface {
+	if leadership == nil {
+		leadership = controller.AlwaysLeader
+	}
+	policies := policyFactory.Admissionregistration().V1alpha1().ValidatingAdmissionPolicies()
+	namespacedPolicies := policyFactory.Admissionregistration().V1alpha1().NamespacedValidatingAdmissionPolicies()
+	return &policyReplicasController{
+		client:                 client,
+		policyClient:           policyClient,
+		leaseLister:            leaseInformer.Lister().Leases(namespace),
+		policyLister:           policies.Lister(),
+		namespacedPolicyLister: namespacedPolicies.Lister(),
+		synced: []cache.InformerSynced{
+			leaseInformer.Informer().HasSynced,
+			policies.Informer().HasSynced,
+			namespacedPolicies.Informer().HasSynced,
+		},
+		leadership: leadership,
+	}
+}
+
+func (c *policyReplicasController) Run(ctx context.Context) error {
+	if !cache.WaitForNamedCacheSync("policy-replicas-controller", ctx.Done(), c.synced...) {
+		return ctx.Err()
+	}
+	wait.UntilWithContext(ctx, c.sync, policyReplicasPeriod)
+	return ctx.Err()
+}
+
+func (c *policyReplicasController) sync(ctx context.Context) {
+	if !c.leadership.IsLeader() {
+		return
+	}
+
+	leases, err := c.leaseLister.List(labels.SelectorFromSet(labels.Set{replicastatus.ReplicaLabel: "true"}))
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	now := time.Now()
+	for _, lease := range leases {
+		if !replicastatus.Expired(lease, now) {
+			continue
+		}
+		err := c.client.CoordinationV1().Leases(lease.Namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+		})
+		if err != nil && !kerrors.IsNotFound(err) && !kerrors.IsConflict(err) {
+			utilruntime.HandleError(fmt.Errorf("failed to delete the expired Lease of a replica: %w", err))
+		}
+	}
+	replicas := replicastatus.LiveReplicas(leases, now)
+	if len(replicas) == 0 {
+		// Not even the Lease of this replica is published yet.
+		return
+	}
+
+	policies, err := c.policyLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, policy := range policies {
+		policy := policy
+		c.updateCondition(policy.Name, policy.Generation, policy.Status, replicas, func(status v1alpha1.ValidatingAdmissionPolicyStatus) error {
+			updated := policy.DeepCopy()
+			updated.Status = status
+			_, err := c.policyClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+			return err
+		})
+	}
+
+	namespacedPolicies, err := c.namespacedPolicyLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, policy := range namespacedPolicies {
+		policy := policy
+		c.updateCondition(policy.Namespace+"/"+policy.Name, policy.Generation, policy.Status, replicas, func(status v1alpha1.ValidatingAdmissionPolicyStatus) error {
+			updated := policy.DeepCopy()
+			updated.Status = status
+			_, err := c.policyClient.AdmissionregistrationV1alpha1().NamespacedValidatingAdmissionPolicies(policy.Namespace).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+			return err
+		})
+	}
+}
+
+func (c *policyReplicasController) updateCondition(
+	key string,
+	generation int64,
+	oldStatus v1alpha1.ValidatingAdmissionPolicyStatus,
+	replicas []replicastatus.Generations,
+	updateStatus func(v1alpha1.ValidatingAdmissionPolicyStatus) error,
+) {
+	ready := replicastatus.Ready(replicas, key, generation)
+	condition := metav1.Condition{
+		Type:               v1alpha1.PolicyConditionLoaded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Loaded",
+		Message:            fmt.Sprintf("Ready on %d/%d replicas", ready, len(replicas)),
+	}
+	if ready < len(replicas) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Loading"
+	}
+
+	status := oldStatus.DeepCopy()
+	meta.SetStatusCondition(&status.Conditions, condition)
+	if apiequality.Semantic.DeepEqual(oldStatus.Conditions, status.Conditions) {
+		return
+	}
+	if err := updateStatus(*status); err != nil {
+		// Conflicts are retried in the next period, with the latest version
+		// of the policy.
+		utilruntime.HandleError(fmt.Errorf("failed to update the Loaded condition of policy %s: %w", key, err))
+	}
+}
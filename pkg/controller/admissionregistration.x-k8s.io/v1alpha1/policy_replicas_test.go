@@ -0,0 +1,105 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/replicastatus"
+)
+
+func TestUpdateLoadedCondition(t *testing.T) {
+	replicas := []replicastatus.Generations{
+		{Policies: map[string]int64{"policy": 2}},
+		{Policies: map[string]int64{"policy": 1}},
+		{Policies: map[string]int64{"policy": 2}},
+	}
+	loading := v1alpha1.ValidatingAdmissionPolicyStatus{Conditions: []metav1.Condition{{
+		Type:               v1alpha1.PolicyConditionLoaded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: 2,
+		Reason:             "Loading",
+		Message:            "Ready on 2/3 replicas",
+	}}}
+
+	for _, testCase := range []struct {
+		name            string
+		generation      int64
+		status          v1alpha1.ValidatingAdmissionPolicyStatus
+		replicas        []replicastatus.Generations
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+		expectedUpdate  bool
+	}{
+		{
+			name:            "loading",
+			generation:      2,
+			replicas:        replicas,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "Loading",
+			expectedMessage: "Ready on 2/3 replicas",
+			expectedUpdate:  true,
+		},
+		{
+			name:            "loaded",
+			generation:      1,
+			replicas:        replicas,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Loaded",
+			expectedMessage: "Ready on 3/3 replicas",
+			expectedUpdate:  true,
+		},
+		{
+			name:            "unchanged",
+			generation:      2,
+			status:          loading,
+			replicas:        replicas,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "Loading",
+			expectedMessage: "Ready on 2/3 replicas",
+		},
+		{
+			name:            "replica-gone",
+			generation:      2,
+			status:          loading,
+			replicas:        []replicastatus.Generations{replicas[0], replicas[2]},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Loaded",
+			expectedMessage: "Ready on 2/2 replicas",
+			expectedUpdate:  true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			var updated *v1alpha1.ValidatingAdmissionPolicyStatus
+			c := &policyReplicasController{}
+			c.updateCondition("policy", testCase.generation, testCase.status, testCase.replicas, func(status v1alpha1.ValidatingAdmissionPolicyStatus) error {
+				updated = &status
+				return nil
+			})
+
+			if !testCase.expectedUpdate {
+				if updated != nil {
+					t.Errorf("expected the status not to be updated, got %v", updated)
+				}
+				return
+			}
+			if updated == nil {
+				t.Fatal("expected the status to be updated")
+			}
+			condition := meta.FindStatusCondition(updated.Conditions, v1alpha1.PolicyConditionLoaded)
+			if condition == nil {
+				t.Fatalf("expected a %s condition, got %v", v1alpha1.PolicyConditionLoaded, updated.Conditions)
+			}
+			if condition.Status != testCase.expectedStatus || condition.Reason != testCase.expectedReason || condition.Message != testCase.expectedMessage {
+				t.Errorf("expected %s %s %q, got %s %s %q", testCase.expectedStatus, testCase.expectedReason, testCase.expectedMessage, condition.Status, condition.Reason, condition.Message)
+			}
+			if condition.ObservedGeneration != testCase.generation {
+				t.Errorf("expected observedGeneration %d, got %d", testCase.generation, condition.ObservedGeneration)
+			}
+		})
+	}
+
+}
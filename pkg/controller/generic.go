@@ -32,9 +32,22 @@ type controller[T runtime.Object] struct {
 	options ControllerOptions
 }
 
+// RequeueAfter may be returned by a reconciler to have the object reconciled
+// again after the given duration, for example because the desired state
+// depends on time. It is not treated as a failure.
+type RequeueAfter time.Duration
+
+func (r RequeueAfter) Error() string {
+	return fmt.Sprintf("requeue after %v", time.Duration(r))
+}
+
 type ControllerOptions struct {
 	Name    string
 	Workers uint
+
+	// Leadership, if set, restricts reconciliation to the leader replica.
+	// Every object is reconciled again when the replica starts leading.
+	Leadership Leadership
 }
 
 func New[T runtime.Object](
@@ -105,6 +118,15 @@ func (c *controller[T]) Run(ctx context.Context) error {
 		},
 	})
 
+	if c.options.Leadership != nil {
+		// Objects changed while another replica was leading were dropped.
+		c.options.Leadership.OnStartedLeading(func() {
+			for _, key := range c.informer.GetStore().ListKeys() {
+				c.queue.Add(key)
+			}
+		})
+	}
+
 	//!TODO: check if informer is even valid?
 	// e.g. if crd isnt even installed yet this just waits forever here
 	if !cache.WaitForNamedCacheSync(c.options.Name, ctx.Done(), c.informer.HasSynced) {
@@ -175,7 +197,16 @@ func (c *controller[T]) runWorker() {
 				return fmt.Errorf("expected string in workqueue but got %#v", obj)
 			}
 
-			if err := c.reconcile(key); err != nil {
+			err := c.reconcile(key)
+			var requeueAfter RequeueAfter
+			if errors.As(err, &requeueAfter) {
+				// Forget the failures of the item and reconcile it again
+				// once it asked for.
+				c.queue.Forget(obj)
+				c.queue.AddAfter(key, time.Duration(requeueAfter))
+				klog.Infof("Successfully synced '%s', requeuing after %v", key, time.Duration(requeueAfter))
+				return nil
+			} else if err != nil {
 				// Put the item back on the workqueue to handle any transient errors.
 				c.queue.AddRateLimited(key)
 				return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
@@ -194,6 +225,11 @@ func (c *controller[T]) runWorker() {
 }
 
 func (c *controller[T]) reconcile(key string) error {
+	if c.options.Leadership != nil && !c.options.Leadership.IsLeader() {
+		// The leader reconciles the object.
+		return nil
+	}
+
 	var newObj T
 	var err error
 	var namespace string
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeLeadership is a Leadership whose leader is set by the test. It counts
+// the calls of IsLeader.
+type fakeLeadership struct {
+	lock     sync.Mutex
+	leading  bool
+	checks   int
+	handlers []func()
+}
+
+func (l *fakeLeadership) IsLeader() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.checks++
+	return l.leading
+}
+
+func (l *fakeLeadership) OnStartedLeading(f func()) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.handlers = append(l.handlers, f)
+	if l.leading {
+		go f()
+	}
+}
+
+func (l *fakeLeadership) startLeading() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.leading = true
+	for _, f := range l.handlers {
+		go f()
+	}
+}
+
+func (l *fakeLeadership) checkCount() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.checks
+}
+
+func TestReconcileOnlyWhenLeading(t *testing.T) {
+	configMaps := &corev1.ConfigMapList{Items: []corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a", ResourceVersion: "1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "b", ResourceVersion: "1"}},
+	}}
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return configMaps, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}, &corev1.ConfigMap{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+	var lock sync.Mutex
+	var reconciled []string
+	leadership := &fakeLeadership{}
+	c := New[*corev1.ConfigMap](NewInformer[*corev1.ConfigMap](informer), func(namespace, name string, newObj *corev1.ConfigMap) error {
+		lock.Lock()
+		defer lock.Unlock()
+		reconciled = append(reconciled, namespace+"/"+name)
+		return nil
+	}, ControllerOptions{Name: "test", Leadership: leadership})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	go func() { _ = c.Run(ctx) }()
+
+	// Both objects are dequeued, but a follower does not reconcile them.
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return leadership.checkCount() >= len(configMaps.Items), nil
+	}); err != nil {
+		t.Fatalf("expected the objects to be dequeued: %v", err)
+	}
+	lock.Lock()
+	if len(reconciled) > 0 {
+		t.Errorf("expected a follower not to reconcile, got %v", reconciled)
+	}
+	lock.Unlock()
+
+	// Every object is requeued and reconciled once the replica leads.
+	leadership.startLeading()
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(reconciled) >= len(configMaps.Items), nil
+	}); err != nil {
+		t.Fatalf("expected the objects to be reconciled: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	sort.Strings(reconciled)
+	if actual := strings.Join(reconciled, ","); actual != "default/a,default/b" {
+		t.Errorf("expected default/a and default/b to be reconciled, got %v", actual)
+	}
+}
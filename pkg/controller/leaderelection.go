@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var leader = metrics.NewGauge(
+	&metrics.GaugeOpts{
+		Namespace:      "cel_admission_webhook",
+		Name:           "leader",
+		Help:           "1 when this replica holds the lease of the controllers which write to the cluster.",
+		StabilityLevel: metrics.ALPHA,
+	},
+)
+
+func init() {
+	legacyregistry.MustRegister(leader)
+}
+
+// Leadership tells whether this replica leads the controllers which write to
+// the cluster, such as the status of policies and bindings. Every replica
+// serves admission requests, leader or not.
+type Leadership interface {
+	IsLeader() bool
+
+	// OnStartedLeading registers a function called each time this replica
+	// starts leading, and right away if it already leads. It is called in a
+	// new goroutine.
+	OnStartedLeading(func())
+}
+
+// AlwaysLeader is the Leadership of a replica which runs without leader
+// election.
+var AlwaysLeader Leadership = alwaysLeader{}
+
+type alwaysLeader struct{}
+
+func (alwaysLeader) IsLeader() bool {
+	return true
+}
+
+func (alwaysLeader) OnStartedLeading(f func()) {
+	go f()
+}
+
+// LeaderElectionOptions configure the election of the leader among the
+// replicas of the webhook.
+type LeaderElectionOptions struct {
+	// Namespace and Name of the Lease.
+	Namespace, Name string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// LeaderElector elects the leader among the replicas of the webhook with a
+// coordination.k8s.io Lease.
+type LeaderElector struct {
+	client   kubernetes.Interface
+	options  LeaderElectionOptions
+	identity string
+
+	lock     sync.Mutex
+	leading  bool
+	elector  *leaderelection.LeaderElector
+	handlers []func()
+}
+
+var _ Leadership = &LeaderElector{}
+
+// NewLeaderElector returns a LeaderElector of this replica, identified by its
+// host name.
+func NewLeaderElector(client kubernetes.Interface, options LeaderElectionOptions) (*LeaderElector, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify the replica for leader election: %w", err)
+	}
+	return &LeaderElector{
+		client:  client,
+		options: options,
+		// The suffix tells replicas apart after a restart, when they may
+		// briefly run with the same host name.
+		identity: hostname + "_" + string(uuid.NewUUID()),
+	}, nil
+}
+
+// Run campaigns for the lease until the context is done. Unlike most
+// controllers, losing the lease does not stop the webhook, since it keeps
+// serving requests: the replica campaigns again instead.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock: &resourcelock.LeaseLock{
+				LeaseMeta:  metav1.ObjectMeta{Namespace: e.options.Namespace, Name: e.options.Name},
+				Client:     e.client.CoordinationV1(),
+				LockConfig: resourcelock.ResourceLockConfig{Identity: e.identity},
+			},
+			LeaseDuration: e.options.LeaseDuration,
+			RenewDeadline: e.options.RenewDeadline,
+			RetryPeriod:   e.options.RetryPeriod,
+			// Status writes are idempotent, so the next leader may take over
+			// as soon as this replica stops.
+			ReleaseOnCancel: true,
+			Name:            e.options.Name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(context.Context) { e.setLeading(true) },
+				OnStoppedLeading: func() { e.setLeading(false) },
+				OnNewLeader: func(identity string) {
+					klog.InfoS("Observed a new leader", "lease", klog.KRef(e.options.Namespace, e.options.Name), "leader", identity)
+				},
+			},
+		})
+		if err != nil {
+			klog.ErrorS(err, "Invalid leader election configuration")
+			return
+		}
+		e.lock.Lock()
+		e.elector = elector
+		e.lock.Unlock()
+		elector.Run(ctx)
+	}, e.options.RetryPeriod)
+	return ctx.Err()
+}
+
+func (e *LeaderElector) setLeading(leading bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if leading == e.leading {
+		return
+	}
+	e.leading = leading
+	if !leading {
+		leader.Set(0)
+		klog.InfoS("Stopped leading", "lease", klog.KRef(e.options.Namespace, e.options.Name), "identity", e.identity)
+		return
+	}
+	leader.Set(1)
+	klog.InfoS("Started leading", "lease", klog.KRef(e.options.Namespace, e.options.Name), "identity", e.identity)
+	for _, f := range e.handlers {
+		go f()
+	}
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.leading
+}
+
+func (e *LeaderElector) OnStartedLeading(f func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.handlers = append(e.handlers, f)
+	if e.leading {
+		go f()
+	}
+}
+
+// Name implements the health check of the webhook.
+func (e *LeaderElector) Name() string {
+	return "leader-election"
+}
+
+// Check reports the current leader. The replica is unhealthy if it failed to
+// renew the lease it holds for longer than the lease duration.
+func (e *LeaderElector) Check() (string, error) {
+	e.lock.Lock()
+	elector := e.elector
+	e.lock.Unlock()
+	if elector == nil {
+		return "campaigning", nil
+	}
+	if err := elector.Check(e.options.LeaseDuration); err != nil {
+		return "", err
+	}
+	if elector.IsLeader() {
+		return "leading", nil
+	}
+	return fmt.Sprintf("following %s", elector.GetLeader()), nil
+}
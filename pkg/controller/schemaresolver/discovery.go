@@ -0,0 +1,163 @@
+package schemaresolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/cel/openapi/resolver"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/handler3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// rootRefreshInterval is how long the root discovery document, which
+	// holds the URLs of the documents of all group versions, is reused.
+	// Documents whose ETag changed are only downloaded again after it is
+	// refreshed.
+	rootRefreshInterval = time.Minute
+
+	// rootMissRefreshInterval is how long the root discovery document must
+	// have been reused before a group version missing from it refreshes it,
+	// since the group version may have been added since.
+	rootMissRefreshInterval = 5 * time.Second
+)
+
+// document is the OpenAPI v3 document of a group version, as served at url.
+// The url of a document carries its ETag, so it changes with the document.
+type document struct {
+	url     string
+	schemas map[string]*spec.Schema
+
+	lock     sync.Mutex
+	resolved map[string]cacheEntry
+}
+
+// discoveryResolver resolves schemas from the OpenAPI v3 discovery of the
+// cluster, and only downloads the document of a group version again when its
+// ETag changes.
+type discoveryResolver struct {
+	discovery discovery.DiscoveryInterface
+	clock     clock.PassiveClock
+
+	lock      sync.Mutex
+	documents map[schema.GroupVersion]*document
+
+	rootLock sync.Mutex
+	// root is the root discovery document, fetched at rootFetched.
+	root        *handler3.OpenAPIV3Discovery
+	rootFetched time.Time
+}
+
+func newDiscoveryResolver(disco discovery.DiscoveryInterface) *discoveryResolver {
+	return &discoveryResolver{
+		discovery: disco,
+		clock:     clock.RealClock{},
+		documents: map[schema.GroupVersion]*document{},
+	}
+}
+
+func (r *discoveryResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	doc, err := r.document(gvk.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	doc.lock.Lock()
+	defer doc.lock.Unlock()
+	if entry, ok := doc.resolved[gvk.Kind]; ok {
+		return entry.schema, entry.error
+	}
+	s, err := resolveKind(doc.schemas, gvk)
+	doc.resolved[gvk.Kind] = cacheEntry{schema: s, error: err}
+	return s, err
+}
+
+// rootPath returns the path of the group version in the root discovery
+// document, which is refreshed when it is older than rootRefreshInterval, or
+// than rootMissRefreshInterval and lacks the group version.
+func (r *discoveryResolver) rootPath(ctx context.Context, gv schema.GroupVersion) (handler3.OpenAPIV3DiscoveryGroupVersion, bool, error) {
+	r.rootLock.Lock()
+	defer r.rootLock.Unlock()
+
+	key := resourcePathFromGV(gv)
+	age := r.clock.Since(r.rootFetched)
+	if r.root != nil && age < rootRefreshInterval {
+		if path, ok := r.root.Paths[key]; ok || age < rootMissRefreshInterval {
+			return path, ok, nil
+		}
+	}
+
+	data, err := r.discovery.RESTClient().Get().AbsPath("/openapi/v3").Do(ctx).Raw()
+	if err != nil {
+		return handler3.OpenAPIV3DiscoveryGroupVersion{}, false, err
+	}
+	root := &handler3.OpenAPIV3Discovery{}
+	if err := json.Unmarshal(data, root); err != nil {
+		return handler3.OpenAPIV3DiscoveryGroupVersion{}, false, err
+	}
+	r.root, r.rootFetched = root, r.clock.Now()
+	path, ok := root.Paths[key]
+	return path, ok, nil
+}
+
+// document returns the current OpenAPI v3 document of the group version.
+func (r *discoveryResolver) document(gv schema.GroupVersion) (*document, error) {
+	ctx := context.TODO()
+	path, ok, err := r.rootPath(ctx, gv)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve group version %q: %w", gv, resolver.ErrSchemaNotFound)
+	}
+
+	r.lock.Lock()
+	doc, ok := r.documents[gv]
+	r.lock.Unlock()
+	if ok && doc.url == path.ServerRelativeURL {
+		return doc, nil
+	}
+
+	data, err := r.discovery.RESTClient().Get().
+		RequestURI(path.ServerRelativeURL).
+		SetHeader("Accept", runtime.ContentTypeJSON).
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+	resp := new(schemaResponse)
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+	doc = &document{
+		url:      path.ServerRelativeURL,
+		schemas:  resp.Components.Schemas,
+		resolved: map[string]cacheEntry{},
+	}
+
+	r.lock.Lock()
+	r.documents[gv] = doc
+	r.lock.Unlock()
+	return doc, nil
+}
+
+func resourcePathFromGV(gv schema.GroupVersion) string {
+	if len(gv.Group) == 0 {
+		return fmt.Sprintf("api/%s", gv.Version)
+	}
+	return fmt.Sprintf("apis/%s/%s", gv.Group, gv.Version)
+}
+
+type schemaResponse struct {
+	Components struct {
+		Schemas map[string]*spec.Schema `json:"schemas"`
+	} `json:"components"`
+}
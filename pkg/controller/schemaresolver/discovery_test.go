@@ -0,0 +1,114 @@
+package schemaresolver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/cel/openapi/resolver"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/handler3"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// fakeOpenAPIServer serves the OpenAPI v3 discovery of the group versions in
+// paths, and counts the requests for each path.
+type fakeOpenAPIServer struct {
+	lock     sync.Mutex
+	paths    map[string]string
+	requests map[string]int
+}
+
+func (s *fakeOpenAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[r.URL.Path]++
+
+	if r.URL.Path == "/openapi/v3" {
+		root := &handler3.OpenAPIV3Discovery{Paths: map[string]handler3.OpenAPIV3DiscoveryGroupVersion{}}
+		for path, hash := range s.paths {
+			root.Paths[path] = handler3.OpenAPIV3DiscoveryGroupVersion{ServerRelativeURL: "/openapi/v3/" + path + "?hash=" + hash}
+		}
+		_ = json.NewEncoder(w).Encode(root)
+		return
+	}
+	// Every group version serves a ConfigMap, which is enough to resolve.
+	_, _ = w.Write([]byte(`{"components":{"schemas":{"ConfigMap":{"type":"object","x-kubernetes-group-version-kind":[{"group":"","version":"v1","kind":"ConfigMap"}]}}}}`))
+}
+
+func (s *fakeOpenAPIServer) setPath(path, hash string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.paths[path] = hash
+}
+
+func (s *fakeOpenAPIServer) requestCount(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[path]
+}
+
+func TestDiscoveryResolverCachesRoot(t *testing.T) {
+	fake := &fakeOpenAPIServer{paths: map[string]string{"api/v1": "a"}, requests: map[string]int{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	r := newDiscoveryResolver(discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: server.URL}))
+	r.clock = clock
+
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	resolve := func(gvk schema.GroupVersionKind) error {
+		t.Helper()
+		_, err := r.ResolveSchema(gvk)
+		return err
+	}
+	expectRequests := func(root, document int) {
+		t.Helper()
+		if count := fake.requestCount("/openapi/v3"); count != root {
+			t.Errorf("expected %d requests of the root discovery document, got %d", root, count)
+		}
+		if count := fake.requestCount("/openapi/v3/api/v1"); count != document {
+			t.Errorf("expected %d requests of the document of v1, got %d", document, count)
+		}
+	}
+
+	if err := resolve(configMap); err != nil {
+		t.Fatal(err)
+	}
+	if err := resolve(configMap); err != nil {
+		t.Fatal(err)
+	}
+	expectRequests(1, 1)
+
+	// A group version missing from the root refreshes it, but not more than
+	// once per rootMissRefreshInterval.
+	missing := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	clock.SetTime(clock.Now().Add(rootMissRefreshInterval))
+	if err := resolve(missing); !errors.Is(err, resolver.ErrSchemaNotFound) {
+		t.Fatalf("expected the schema not to be found, got %v", err)
+	}
+	if err := resolve(missing); !errors.Is(err, resolver.ErrSchemaNotFound) {
+		t.Fatalf("expected the schema not to be found, got %v", err)
+	}
+	expectRequests(2, 1)
+
+	// The document is only downloaded again once the refreshed root carries
+	// its new hash.
+	fake.setPath("api/v1", "b")
+	if err := resolve(configMap); err != nil {
+		t.Fatal(err)
+	}
+	expectRequests(2, 1)
+	clock.SetTime(clock.Now().Add(rootRefreshInterval))
+	if err := resolve(configMap); err != nil {
+		t.Fatal(err)
+	}
+	expectRequests(3, 2)
+}
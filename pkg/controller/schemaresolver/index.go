@@ -0,0 +1,51 @@
+package schemaresolver
+
+import (
+	"fmt"
+
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const crdGroupKindIndex = "groupKind"
+
+func crdGroupKind(obj interface{}) ([]string, error) {
+	crd, ok := obj.(*v1.CustomResourceDefinition)
+	if !ok {
+		return nil, nil
+	}
+	return []string{groupKindOf(crd).String()}, nil
+}
+
+func groupKindOf(crd *v1.CustomResourceDefinition) schema.GroupKind {
+	return schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}
+}
+
+// IndexCRDsByGroupKind indexes the CustomResourceDefinitions of the informer
+// by the group and kind of their resources, and returns the indexer to look
+// them up with CRDFor. It must be called before the informer is started.
+func IndexCRDsByGroupKind(crdInformer crdinformers.CustomResourceDefinitionInformer) cache.Indexer {
+	informer := crdInformer.Informer()
+	if _, exists := informer.GetIndexer().GetIndexers()[crdGroupKindIndex]; !exists {
+		if err := informer.AddIndexers(cache.Indexers{crdGroupKindIndex: crdGroupKind}); err != nil {
+			utilruntime.HandleError(err)
+		}
+	}
+	return informer.GetIndexer()
+}
+
+// CRDFor returns the CustomResourceDefinition of the group and kind from an
+// indexer returned by IndexCRDsByGroupKind, or nil if there is none.
+func CRDFor(indexer cache.Indexer, groupKind schema.GroupKind) (*v1.CustomResourceDefinition, error) {
+	objs, err := indexer.ByIndex(crdGroupKindIndex, groupKind.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the CustomResourceDefinition of %v: %w", groupKind, err)
+	}
+	if len(objs) == 0 {
+		return nil, nil
+	}
+	return objs[0].(*v1.CustomResourceDefinition), nil
+}
@@ -2,10 +2,12 @@ package schemaresolver
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	crdinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/controller/openapi/builder"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/cel/openapi/resolver"
 	"k8s.io/client-go/discovery"
@@ -20,13 +22,16 @@ type cacheEntry struct {
 
 type schemaCache = map[schema.GroupVersionKind]cacheEntry
 
-// Use the discovery-based schema resolver, paired with a CRD informer
-// that purges CRD GVs from cache when they are updated
+// Controller resolves the schemas of custom resources from the
+// CustomResourceDefinitions of a CRD informer, purging them from its cache
+// when the definitions change, and the schemas of built-in types from the
+// OpenAPI v3 discovery of the cluster.
 type Controller struct {
-	resolver.ClientDiscoveryResolver
 	lock        sync.RWMutex
 	cache       schemaCache
 	crdInformer crdinformers.CustomResourceDefinitionInformer
+	crdIndexer  cache.Indexer
+	builtins    *discoveryResolver
 }
 
 var _ resolver.SchemaResolver = (*Controller)(nil)
@@ -36,9 +41,10 @@ func New(
 	disco discovery.DiscoveryInterface,
 ) *Controller {
 	return &Controller{
-		ClientDiscoveryResolver: resolver.ClientDiscoveryResolver{Discovery: disco},
-		cache:                   schemaCache{},
-		crdInformer:             crdinformer,
+		cache:       schemaCache{},
+		crdInformer: crdinformer,
+		crdIndexer:  IndexCRDsByGroupKind(crdinformer),
+		builtins:    newDiscoveryResolver(disco),
 	}
 }
 
@@ -46,33 +52,39 @@ func (r *Controller) Run(ctx context.Context) error {
 	informer := r.crdInformer.Informer()
 	handle, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			crd := obj.(*v1.CustomResourceDefinition)
-			r.purgeCRDFromCache(crd.GetObjectKind().GroupVersionKind().GroupKind())
+			r.purgeCRDFromCache(obj)
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
-			oldCrd := oldObj.(*v1.CustomResourceDefinition)
-			newCrd := newObj.(*v1.CustomResourceDefinition)
-
-			// These should be the same groupkind. but whatever purge them all
-			r.purgeCRDFromCache(oldCrd.GetObjectKind().GroupVersionKind().GroupKind())
-			r.purgeCRDFromCache(newCrd.GetObjectKind().GroupVersionKind().GroupKind())
+			// The group and kind of a CRD are immutable, but purge both
+			// anyway.
+			r.purgeCRDFromCache(oldObj)
+			r.purgeCRDFromCache(newObj)
 		},
 		DeleteFunc: func(obj interface{}) {
-			crd := obj.(*v1.CustomResourceDefinition)
-			r.purgeCRDFromCache(crd.GetObjectKind().GroupVersionKind().GroupKind())
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			r.purgeCRDFromCache(obj)
 		},
 	})
-	defer informer.RemoveEventHandler(handle)
-
 	if err != nil {
 		return err
 	}
+	defer informer.RemoveEventHandler(handle)
 
 	<-ctx.Done()
 	return nil
 }
 
 func (r *Controller) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	crd, err := CRDFor(r.crdIndexer, gvk.GroupKind())
+	if err != nil {
+		return nil, err
+	}
+	if crd == nil {
+		return r.builtins.ResolveSchema(gvk)
+	}
+
 	if exists, schema, err := r.resolveSchemaFromCache(gvk); exists {
 		return schema, err
 	}
@@ -80,19 +92,50 @@ func (r *Controller) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, e
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	data, err := r.ClientDiscoveryResolver.ResolveSchema(gvk)
+	data, err := schemaForCRD(crd, gvk)
 	res := cacheEntry{schema: data, error: err}
 	r.cache[gvk] = res
 
 	return res.schema, res.error
 }
 
-func (r *Controller) purgeCRDFromCache(gk schema.GroupKind) {
+// schemaForCRD builds the schema of a served version of a custom resource the
+// way the API server publishes it, with its metadata and type meta.
+func schemaForCRD(crd *v1.CustomResourceDefinition, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if !servesVersion(crd, gvk.Version) {
+		return nil, fmt.Errorf("cannot resolve group version kind %q: %w", gvk, resolver.ErrSchemaNotFound)
+	}
+	openAPI, err := builder.BuildOpenAPIV3(crd, gvk.Version, builder.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the schema of %v from CustomResourceDefinition %s: %w", gvk, crd.Name, err)
+	}
+	if openAPI.Components == nil {
+		return nil, fmt.Errorf("cannot resolve group version kind %q: %w", gvk, resolver.ErrSchemaNotFound)
+	}
+	return resolveKind(openAPI.Components.Schemas, gvk)
+}
+
+func servesVersion(crd *v1.CustomResourceDefinition, version string) bool {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version {
+			return v.Served
+		}
+	}
+	return false
+}
+
+func (r *Controller) purgeCRDFromCache(obj interface{}) {
+	crd, ok := obj.(*v1.CustomResourceDefinition)
+	if !ok {
+		return
+	}
+	gk := groupKindOf(crd)
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
 	for k := range r.cache {
-		if k.Group == gk.Group && k.Kind == gk.Kind {
+		if k.GroupKind() == gk {
 			delete(r.cache, k)
 		}
 	}
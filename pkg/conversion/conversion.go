@@ -0,0 +1,196 @@
+package conversion
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/cel-admission-webhook/pkg/controller/schemaresolver"
+)
+
+// objectInterfaces are the admission.ObjectInterfaces of the shim. Unlike the
+// ones of a scheme, they know the versions of the custom resources served by
+// the cluster, so that policies which match an equivalent version of the
+// resource of a request, with matchPolicy Equivalent, are evaluated against
+// the objects of the request converted to that version.
+//
+// Built-in resources have no equivalents: the API server converts them
+// through their internal types, which the shim does not have, and the scheme
+// cannot convert between their external versions.
+type objectInterfaces struct {
+	scheme     *runtime.Scheme
+	restMapper meta.RESTMapper
+	crdIndexer cache.Indexer
+	webhooks   *webhookConverter
+
+	// ctx bounds the calls to conversion webhooks.
+	ctx context.Context
+}
+
+// NewObjectInterfaces returns admission.ObjectInterfaces which convert
+// built-in objects with the scheme, and custom resources according to the
+// conversion strategy of their CustomResourceDefinition.
+func NewObjectInterfaces(scheme *runtime.Scheme, restMapper meta.RESTMapper, crdInformer crdinformers.CustomResourceDefinitionInformer) admission.ObjectInterfaces {
+	return &objectInterfaces{
+		scheme:     scheme,
+		restMapper: restMapper,
+		crdIndexer: schemaresolver.IndexCRDsByGroupKind(crdInformer),
+		webhooks:   newWebhookConverter(),
+		ctx:        context.Background(),
+	}
+}
+
+// WithContext returns the objectInterfaces with calls to conversion webhooks
+// bound to ctx, the one of the review the objects are converted for.
+func (o *objectInterfaces) WithContext(ctx context.Context) admission.ObjectInterfaces {
+	bound := *o
+	bound.ctx = ctx
+	return &bound
+}
+
+func (o *objectInterfaces) GetObjectCreater() runtime.ObjectCreater                       { return o }
+func (o *objectInterfaces) GetObjectTyper() runtime.ObjectTyper                           { return o.scheme }
+func (o *objectInterfaces) GetObjectDefaulter() runtime.ObjectDefaulter                   { return o.scheme }
+func (o *objectInterfaces) GetObjectConvertor() runtime.ObjectConvertor                   { return o }
+func (o *objectInterfaces) GetEquivalentResourceMapper() runtime.EquivalentResourceMapper { return o }
+
+// New returns a new object of the kind, which is unstructured unless the kind
+// is known to the scheme.
+func (o *objectInterfaces) New(gvk schema.GroupVersionKind) (runtime.Object, error) {
+	if o.scheme.Recognizes(gvk) {
+		return o.scheme.New(gvk)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj, nil
+}
+
+// EquivalentResourcesFor returns the versions served by the cluster of a
+// custom resource, between which objects can be converted. Built-in resources
+// and subresources have no known equivalents.
+func (o *objectInterfaces) EquivalentResourcesFor(resource schema.GroupVersionResource, subresource string) []schema.GroupVersionResource {
+	if len(subresource) > 0 {
+		return []schema.GroupVersionResource{resource}
+	}
+	kind, err := o.restMapper.KindFor(resource)
+	if err != nil {
+		return []schema.GroupVersionResource{resource}
+	}
+	crd, err := schemaresolver.CRDFor(o.crdIndexer, kind.GroupKind())
+	if err != nil || crd == nil || !servesVersion(crd, resource.Version) {
+		return []schema.GroupVersionResource{resource}
+	}
+	var resources []schema.GroupVersionResource
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			resources = append(resources, schema.GroupVersionResource{Group: crd.Spec.Group, Version: v.Name, Resource: crd.Spec.Names.Plural})
+		}
+	}
+	return resources
+}
+
+// KindFor returns the kind served at the version of the resource.
+func (o *objectInterfaces) KindFor(resource schema.GroupVersionResource, subresource string) schema.GroupVersionKind {
+	if len(subresource) > 0 {
+		return schema.GroupVersionKind{}
+	}
+	kind, err := o.restMapper.KindFor(resource)
+	if err != nil {
+		return schema.GroupVersionKind{}
+	}
+	return kind
+}
+
+// Convert converts custom resources according to the conversion strategy of
+// their CustomResourceDefinition, and other objects with the scheme. The
+// objects of requests are unstructured: the scheme converts those of built-in
+// kinds to their types first, into the typed objects returned by New.
+func (o *objectInterfaces) Convert(in, out, context interface{}) error {
+	unstructuredIn, inOK := in.(*unstructured.Unstructured)
+	unstructuredOut, outOK := out.(*unstructured.Unstructured)
+	if !inOK || !outOK {
+		return o.scheme.Convert(in, out, context)
+	}
+	converted, err := o.convertCustomResource(unstructuredIn, unstructuredOut.GroupVersionKind().GroupVersion())
+	if err != nil {
+		return err
+	}
+	unstructuredOut.Object = converted.Object
+	return nil
+}
+
+// ConvertToVersion converts custom resources according to the conversion
+// strategy of their CustomResourceDefinition, and other objects with the
+// scheme.
+func (o *objectInterfaces) ConvertToVersion(in runtime.Object, target runtime.GroupVersioner) (runtime.Object, error) {
+	unstructuredIn, ok := in.(*unstructured.Unstructured)
+	if !ok || o.scheme.Recognizes(unstructuredIn.GroupVersionKind()) {
+		return o.scheme.ConvertToVersion(in, target)
+	}
+	gvk, ok := target.KindForGroupVersionKinds([]schema.GroupVersionKind{unstructuredIn.GroupVersionKind()})
+	if !ok {
+		return nil, fmt.Errorf("%v is not suitable for converting to %v", unstructuredIn.GroupVersionKind(), target)
+	}
+	return o.convertCustomResource(unstructuredIn, gvk.GroupVersion())
+}
+
+func (o *objectInterfaces) ConvertFieldLabel(gvk schema.GroupVersionKind, label, value string) (string, string, error) {
+	return o.scheme.ConvertFieldLabel(gvk, label, value)
+}
+
+func (o *objectInterfaces) convertCustomResource(in *unstructured.Unstructured, version schema.GroupVersion) (*unstructured.Unstructured, error) {
+	fromVersion := in.GroupVersionKind()
+	if fromVersion.GroupVersion() == version {
+		return in.DeepCopy(), nil
+	}
+	if fromVersion.Group != version.Group {
+		return nil, fmt.Errorf("unable to convert %v to %v: custom resources can only be converted between versions of their group", fromVersion, version)
+	}
+
+	crd, err := o.crdFor(fromVersion.GroupKind())
+	if err != nil {
+		return nil, err
+	}
+	if !servesVersion(crd, version.Version) {
+		return nil, fmt.Errorf("unable to convert %v to %v: the version is not served", fromVersion, version)
+	}
+
+	if crd.Spec.Conversion == nil || crd.Spec.Conversion.Strategy == apiextensionsv1.NoneConverter {
+		// Like the API server, only the apiVersion changes.
+		out := in.DeepCopy()
+		out.SetAPIVersion(version.String())
+		return out, nil
+	}
+	if crd.Spec.Conversion.Strategy != apiextensionsv1.WebhookConverter || crd.Spec.Conversion.Webhook == nil {
+		return nil, fmt.Errorf("unable to convert %v to %v: unknown conversion strategy %q", fromVersion, version, crd.Spec.Conversion.Strategy)
+	}
+	return o.webhooks.convert(o.ctx, crd, in, version)
+}
+
+func (o *objectInterfaces) crdFor(groupKind schema.GroupKind) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, err := schemaresolver.CRDFor(o.crdIndexer, groupKind)
+	if err != nil {
+		return nil, err
+	}
+	if crd == nil {
+		return nil, fmt.Errorf("no CustomResourceDefinition found for %v", groupKind)
+	}
+	return crd, nil
+}
+
+func servesVersion(crd *apiextensionsv1.CustomResourceDefinition, version string) bool {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version {
+			return v.Served
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package conversion
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	deploymentsV1      = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	deploymentsV1beta2 = schema.GroupVersionResource{Group: "apps", Version: "v1beta2", Resource: "deployments"}
+	widgetsV1          = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	widgetsV2          = schema.GroupVersionResource{Group: "example.com", Version: "v2", Resource: "widgets"}
+	widgetsV3          = schema.GroupVersionResource{Group: "example.com", Version: "v3", Resource: "widgets"}
+)
+
+// newTestObjectInterfaces returns the objectInterfaces of a cluster serving
+// deployments and the widgets of crd. The informer of CustomResourceDefinitions
+// is never started, the CustomResourceDefinition is added to its indexer.
+func newTestObjectInterfaces(t *testing.T, crd *apiextensionsv1.CustomResourceDefinition) admission.ObjectInterfaces {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvr := range []schema.GroupVersionResource{deploymentsV1, deploymentsV1beta2} {
+		restMapper.AddSpecific(gvr.GroupVersion().WithKind("Deployment"), gvr, gvr.GroupVersion().WithResource("deployment"), meta.RESTScopeNamespace)
+	}
+	for _, gvr := range []schema.GroupVersionResource{widgetsV1, widgetsV2, widgetsV3} {
+		restMapper.AddSpecific(gvr.GroupVersion().WithKind("Widget"), gvr, gvr.GroupVersion().WithResource("widget"), meta.RESTScopeNamespace)
+	}
+
+	client := apiextensionsclientset.NewForConfigOrDie(&rest.Config{Host: "https://127.0.0.1:0"})
+	crdInformer := apiextensionsinformers.NewSharedInformerFactory(client, 0).Apiextensions().V1().CustomResourceDefinitions()
+	o := NewObjectInterfaces(clientsetscheme.Scheme, restMapper, crdInformer)
+	if err := crdInformer.Informer().GetIndexer().Add(crd); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func widgetCRD(conversion *apiextensionsv1.CustomResourceConversion) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", Served: true},
+				{Name: "v2", Served: true, Storage: true},
+				{Name: "v3", Served: false},
+			},
+			Conversion: conversion,
+		},
+	}
+}
+
+func widget(version string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/" + version,
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "example"},
+		"spec":       map[string]interface{}{"size": int64(1)},
+	}}
+}
+
+func TestEquivalentResourcesFor(t *testing.T) {
+	o := newTestObjectInterfaces(t, widgetCRD(nil))
+
+	for _, testCase := range []struct {
+		name        string
+		resource    schema.GroupVersionResource
+		subresource string
+		expected    []schema.GroupVersionResource
+	}{
+		{
+			name:     "built-in",
+			resource: deploymentsV1beta2,
+			expected: []schema.GroupVersionResource{deploymentsV1beta2},
+		},
+		{
+			name:     "custom-resource",
+			resource: widgetsV1,
+			expected: []schema.GroupVersionResource{widgetsV1, widgetsV2},
+		},
+		{
+			name:     "custom-resource-version-not-served",
+			resource: widgetsV3,
+			expected: []schema.GroupVersionResource{widgetsV3},
+		},
+		{
+			name:        "subresource",
+			resource:    widgetsV1,
+			subresource: "status",
+			expected:    []schema.GroupVersionResource{widgetsV1},
+		},
+		{
+			name:     "unknown",
+			resource: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"},
+			expected: []schema.GroupVersionResource{{Group: "example.com", Version: "v1", Resource: "gadgets"}},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			resources := o.GetEquivalentResourceMapper().EquivalentResourcesFor(testCase.resource, testCase.subresource)
+			if !reflect.DeepEqual(resources, testCase.expected) {
+				t.Errorf("expected equivalents %v, got %v", testCase.expected, resources)
+			}
+		})
+	}
+}
+
+func TestConvertNone(t *testing.T) {
+	o := newTestObjectInterfaces(t, widgetCRD(&apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}))
+
+	out, err := admission.ConvertToGVK(widget("v1"), widgetsV2.GroupVersion().WithKind("Widget"), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := widget("v2"); !reflect.DeepEqual(out, runtime.Object(expected)) {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	if _, err := admission.ConvertToGVK(widget("v1"), widgetsV3.GroupVersion().WithKind("Widget"), o); err == nil || !strings.Contains(err.Error(), "not served") {
+		t.Errorf("expected an error converting to a version which is not served, got %v", err)
+	}
+}
+
+func webhookCRD(server *httptest.Server) *apiextensionsv1.CustomResourceDefinition {
+	url := server.URL
+	return widgetCRD(&apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				URL:      &url,
+				CABundle: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	})
+}
+
+func TestConvertWebhook(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		respond  func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse
+		expected *unstructured.Unstructured
+		err      string
+	}{
+		{
+			name: "converted",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				return &apiextensionsv1.ConversionResponse{
+					UID:              request.UID,
+					Result:           metav1.Status{Status: metav1.StatusSuccess},
+					ConvertedObjects: []runtime.RawExtension{{Object: widget("v2")}},
+				}
+			},
+		},
+		{
+			name: "failed",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				return &apiextensionsv1.ConversionResponse{
+					UID:    request.UID,
+					Result: metav1.Status{Status: metav1.StatusFailure, Message: "unsupported size"},
+				}
+			},
+			err: "unsupported size",
+		},
+		{
+			name: "other-request",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				return &apiextensionsv1.ConversionResponse{
+					UID:              "other",
+					Result:           metav1.Status{Status: metav1.StatusSuccess},
+					ConvertedObjects: []runtime.RawExtension{{Object: widget("v2")}},
+				}
+			},
+			err: "response of another request",
+		},
+		{
+			name: "wrong-version",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				return &apiextensionsv1.ConversionResponse{
+					UID:              request.UID,
+					Result:           metav1.Status{Status: metav1.StatusSuccess},
+					ConvertedObjects: []runtime.RawExtension{{Object: widget("v1")}},
+				}
+			},
+			err: "expected a example.com/v2, Kind=Widget",
+		},
+		{
+			// Only the labels and annotations of the converted object are
+			// kept, the rest of its metadata is the one of the original.
+			name: "metadata-restored",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				converted := widget("v2")
+				converted.SetLabels(map[string]string{"app": "example"})
+				converted.SetFinalizers([]string{"example.com/converted"})
+				return &apiextensionsv1.ConversionResponse{
+					UID:              request.UID,
+					Result:           metav1.Status{Status: metav1.StatusSuccess},
+					ConvertedObjects: []runtime.RawExtension{{Object: converted}},
+				}
+			},
+			expected: func() *unstructured.Unstructured {
+				expected := widget("v2")
+				expected.SetLabels(map[string]string{"app": "example"})
+				return expected
+			}(),
+		},
+		{
+			name: "invalid-labels",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				converted := widget("v2")
+				converted.SetLabels(map[string]string{"app": "not a valid value"})
+				return &apiextensionsv1.ConversionResponse{
+					UID:              request.UID,
+					Result:           metav1.Status{Status: metav1.StatusSuccess},
+					ConvertedObjects: []runtime.RawExtension{{Object: converted}},
+				}
+			},
+			err: "metadata.labels: Invalid value",
+		},
+		{
+			name: "oversized",
+			respond: func(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+				converted := widget("v2")
+				converted.Object["spec"] = map[string]interface{}{"padding": strings.Repeat("x", maxResponseSize)}
+				return &apiextensionsv1.ConversionResponse{
+					UID:              request.UID,
+					Result:           metav1.Status{Status: metav1.StatusSuccess},
+					ConvertedObjects: []runtime.RawExtension{{Object: converted}},
+				}
+			},
+			err: "invalid response",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				review := &apiextensionsv1.ConversionReview{}
+				if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				review.Response = testCase.respond(review.Request)
+				review.Request = nil
+				// The shim stops reading oversized responses.
+				_ = json.NewEncoder(w).Encode(review)
+			}))
+			defer server.Close()
+
+			o := newTestObjectInterfaces(t, webhookCRD(server))
+
+			out, err := admission.ConvertToGVK(widget("v1"), widgetsV2.GroupVersion().WithKind("Widget"), o)
+			if len(testCase.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := testCase.expected
+			if expected == nil {
+				expected = widget("v2")
+			}
+			if !reflect.DeepEqual(out, runtime.Object(expected)) {
+				t.Errorf("expected %v, got %v", expected, out)
+			}
+		})
+	}
+}
+
+func TestConvertWebhookContext(t *testing.T) {
+	// The webhook never responds, until the test ends.
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	o := newTestObjectInterfaces(t, webhookCRD(server))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	bound := o.(interface {
+		WithContext(ctx context.Context) admission.ObjectInterfaces
+	}).WithContext(ctx)
+
+	start := time.Now()
+	_, err := admission.ConvertToGVK(widget("v1"), widgetsV2.GroupVersion().WithKind("Widget"), bound)
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected the deadline of the context to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= webhookTimeout {
+		t.Errorf("expected the call to end with its context, it took %v", elapsed)
+	}
+}
+
+func TestWebhookClients(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	// A bundle adding a certificate, as when the CA is rotated.
+	rotatedCABundle := append(append([]byte{}, caBundle...), caBundle...)
+
+	c := newWebhookConverter()
+	client, err := c.client("widgets.example.com", caBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, _ := c.client("widgets.example.com", caBundle); cached != client {
+		t.Error("expected the client to be reused")
+	}
+	// Rotating the CA bundle replaces the client rather than adding one.
+	if rotated, _ := c.client("widgets.example.com", rotatedCABundle); rotated == client {
+		t.Error("expected the client to be replaced")
+	}
+	if _, err := c.client("gadgets.example.com", caBundle); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.clients) != 2 {
+		t.Errorf("expected 2 clients, got %d", len(c.clients))
+	}
+}
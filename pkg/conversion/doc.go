@@ -0,0 +1,4 @@
+// Package conversion converts the objects of admission requests to the
+// versions matched by policies, including equivalent versions of custom
+// resources served by the cluster.
+package conversion
@@ -0,0 +1,237 @@
+package conversion
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// webhookTimeout is the upper bound of the calls to conversion webhooks. It is
+// shorter than the one of the API server, since the shim is itself called by
+// the API server with a timeout; calls end earlier when the deadline of the
+// review is closer.
+const webhookTimeout = 5 * time.Second
+
+// maxResponseSize bounds the size of the responses of conversion webhooks
+// read by the shim, well above the size of any object the API server stores.
+const maxResponseSize = 10 * 1024 * 1024
+
+// webhookConverter converts custom resources with the conversion webhooks of
+// their CustomResourceDefinitions, like the API server does.
+type webhookConverter struct {
+	mutex sync.Mutex
+	// clients by the name of the CustomResourceDefinition they call the
+	// webhook of, replaced when its CA bundle changes.
+	clients map[string]webhookClient
+}
+
+// webhookClient is a client trusting a CA bundle.
+type webhookClient struct {
+	caBundle string
+	client   *http.Client
+}
+
+func newWebhookConverter() *webhookConverter {
+	return &webhookConverter{clients: map[string]webhookClient{}}
+}
+
+func (c *webhookConverter) convert(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, in *unstructured.Unstructured, version schema.GroupVersion) (*unstructured.Unstructured, error) {
+	webhook := crd.Spec.Conversion.Webhook
+	if !supportsV1Review(webhook.ConversionReviewVersions) {
+		return nil, fmt.Errorf("conversion webhook of %s does not support ConversionReview v1", crd.Name)
+	}
+	if webhook.ClientConfig == nil {
+		return nil, fmt.Errorf("conversion webhook of %s has no client config", crd.Name)
+	}
+	address, err := webhookURL(webhook.ClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s: %w", crd.Name, err)
+	}
+	client, err := c.client(crd.Name, webhook.ClientConfig.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s: %w", crd.Name, err)
+	}
+
+	raw, err := in.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	review := &apiextensionsv1.ConversionReview{
+		Request: &apiextensionsv1.ConversionRequest{
+			UID:               uuid.NewUUID(),
+			DesiredAPIVersion: version.String(),
+			Objects:           []runtime.RawExtension{{Raw: raw}},
+		},
+	}
+	review.APIVersion = apiextensionsv1.SchemeGroupVersion.String()
+	review.Kind = "ConversionReview"
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s: %w", crd.Name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s failed: %w", crd.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("conversion webhook of %s responded with status %d", crd.Name, resp.StatusCode)
+	}
+	result := &apiextensionsv1.ConversionReview{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(result); err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s returned an invalid response: %w", crd.Name, err)
+	}
+	return validateResponse(crd, review.Request, result.Response, in, version)
+}
+
+// validateResponse checks the response of a conversion webhook the way the
+// API server does, and returns the converted object. Like the API server,
+// only the labels and annotations of the metadata of the converted object
+// are kept, the rest is restored from the original object.
+func validateResponse(crd *apiextensionsv1.CustomResourceDefinition, request *apiextensionsv1.ConversionRequest, response *apiextensionsv1.ConversionResponse, in *unstructured.Unstructured, version schema.GroupVersion) (*unstructured.Unstructured, error) {
+	if response == nil {
+		return nil, fmt.Errorf("conversion webhook of %s returned no response", crd.Name)
+	}
+	if response.UID != request.UID {
+		return nil, fmt.Errorf("conversion webhook of %s returned the response of another request", crd.Name)
+	}
+	if response.Result.Status != "Success" {
+		return nil, fmt.Errorf("conversion webhook of %s failed: %s", crd.Name, response.Result.Message)
+	}
+	if len(response.ConvertedObjects) != 1 {
+		return nil, fmt.Errorf("conversion webhook of %s returned %d objects, expected 1", crd.Name, len(response.ConvertedObjects))
+	}
+
+	out := &unstructured.Unstructured{}
+	if err := out.UnmarshalJSON(response.ConvertedObjects[0].Raw); err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s returned an invalid object: %w", crd.Name, err)
+	}
+	expected := version.WithKind(in.GetKind())
+	if out.GroupVersionKind() != expected {
+		return nil, fmt.Errorf("conversion webhook of %s returned a %v, expected a %v", crd.Name, out.GroupVersionKind(), expected)
+	}
+	if out.GetName() != in.GetName() || out.GetNamespace() != in.GetNamespace() || out.GetUID() != in.GetUID() {
+		return nil, fmt.Errorf("conversion webhook of %s changed the identity of the object", crd.Name)
+	}
+	if err := restoreObjectMeta(in, out); err != nil {
+		return nil, fmt.Errorf("conversion webhook of %s returned an invalid object: %w", crd.Name, err)
+	}
+	return out, nil
+}
+
+// restoreObjectMeta replaces the metadata of converted with the one of
+// original, except for the labels and annotations of converted, which must be
+// valid.
+func restoreObjectMeta(original, converted *unstructured.Unstructured) error {
+	responseMetadata, ok := converted.Object["metadata"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid metadata of type %T", converted.Object["metadata"])
+	}
+	metadata, ok := runtime.DeepCopyJSONValue(original.Object["metadata"]).(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+	}
+	converted.Object["metadata"] = metadata
+
+	for _, key := range []string{"labels", "annotations"} {
+		values, found, err := unstructured.NestedStringMap(responseMetadata, key)
+		if err != nil {
+			return fmt.Errorf("invalid metadata.%s: %w", key, err)
+		}
+		if !found || values == nil {
+			delete(metadata, key)
+			continue
+		}
+		if err := unstructured.SetNestedStringMap(metadata, values, key); err != nil {
+			return err
+		}
+	}
+	errs := metav1validation.ValidateLabels(converted.GetLabels(), field.NewPath("metadata", "labels"))
+	errs = append(errs, apivalidation.ValidateAnnotations(converted.GetAnnotations(), field.NewPath("metadata", "annotations"))...)
+	return errs.ToAggregate()
+}
+
+func supportsV1Review(versions []string) bool {
+	for _, v := range versions {
+		if v == apiextensionsv1.SchemeGroupVersion.Version {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookURL(config *apiextensionsv1.WebhookClientConfig) (string, error) {
+	if config.URL != nil {
+		return *config.URL, nil
+	}
+	if config.Service == nil {
+		return "", fmt.Errorf("neither url nor service is set")
+	}
+	port := int32(443)
+	if config.Service.Port != nil {
+		port = *config.Service.Port
+	}
+	u := url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(config.Service.Name+"."+config.Service.Namespace+".svc", strconv.Itoa(int(port))),
+	}
+	if config.Service.Path != nil {
+		u.Path = *config.Service.Path
+	}
+	return u.String(), nil
+}
+
+// client returns the client of the webhook of a CustomResourceDefinition.
+// The client it replaces when the CA bundle changes is closed.
+func (c *webhookConverter) client(crdName string, caBundle []byte) (*http.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cached, ok := c.clients[crdName]
+	if ok && cached.caBundle == string(caBundle) {
+		return cached.client, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caBundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("invalid caBundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	if ok {
+		cached.client.CloseIdleConnections()
+	}
+	c.clients[crdName] = webhookClient{caBundle: string(caBundle), client: client}
+	return client, nil
+}
@@ -28,6 +28,10 @@ import (
 
 type AdmissionregistrationV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	EnforcementOverridesGetter
+	NamespacedValidatingAdmissionPoliciesGetter
+	NamespacedValidatingAdmissionPolicyBindingsGetter
+	PolicyExceptionsGetter
 	ValidatingAdmissionPoliciesGetter
 	ValidatingAdmissionPolicyBindingsGetter
 }
@@ -37,6 +41,22 @@ type AdmissionregistrationV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *AdmissionregistrationV1alpha1Client) EnforcementOverrides() EnforcementOverrideInterface {
+	return newEnforcementOverrides(c)
+}
+
+func (c *AdmissionregistrationV1alpha1Client) NamespacedValidatingAdmissionPolicies(namespace string) NamespacedValidatingAdmissionPolicyInterface {
+	return newNamespacedValidatingAdmissionPolicies(c, namespace)
+}
+
+func (c *AdmissionregistrationV1alpha1Client) NamespacedValidatingAdmissionPolicyBindings(namespace string) NamespacedValidatingAdmissionPolicyBindingInterface {
+	return newNamespacedValidatingAdmissionPolicyBindings(c, namespace)
+}
+
+func (c *AdmissionregistrationV1alpha1Client) PolicyExceptions(namespace string) PolicyExceptionInterface {
+	return newPolicyExceptions(c, namespace)
+}
+
 func (c *AdmissionregistrationV1alpha1Client) ValidatingAdmissionPolicies() ValidatingAdmissionPolicyInterface {
 	return newValidatingAdmissionPolicies(c)
 }
@@ -0,0 +1,168 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	scheme "k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+)
+
+// EnforcementOverridesGetter has a method to return a EnforcementOverrideInterface.
+// A group's client should implement this interface.
+type EnforcementOverridesGetter interface {
+	EnforcementOverrides() EnforcementOverrideInterface
+}
+
+// EnforcementOverrideInterface has methods to work with EnforcementOverride resources.
+type EnforcementOverrideInterface interface {
+	Create(ctx context.Context, enforcementOverride *v1alpha1.EnforcementOverride, opts v1.CreateOptions) (*v1alpha1.EnforcementOverride, error)
+	Update(ctx context.Context, enforcementOverride *v1alpha1.EnforcementOverride, opts v1.UpdateOptions) (*v1alpha1.EnforcementOverride, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.EnforcementOverride, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.EnforcementOverrideList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.EnforcementOverride, err error)
+	EnforcementOverrideExpansion
+}
+
+// enforcementOverrides implements EnforcementOverrideInterface
+type enforcementOverrides struct {
+	client rest.Interface
+}
+
+// newEnforcementOverrides returns a EnforcementOverrides
+func newEnforcementOverrides(c *AdmissionregistrationV1alpha1Client) *enforcementOverrides {
+	return &enforcementOverrides{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the enforcementOverride, and returns the corresponding enforcementOverride object, and an error if there is any.
+func (c *enforcementOverrides) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.EnforcementOverride, err error) {
+	result = &v1alpha1.EnforcementOverride{}
+	err = c.client.Get().
+		Resource("enforcementoverrides").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of EnforcementOverrides that match those selectors.
+func (c *enforcementOverrides) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.EnforcementOverrideList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.EnforcementOverrideList{}
+	err = c.client.Get().
+		Resource("enforcementoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested enforcementOverrides.
+func (c *enforcementOverrides) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("enforcementoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a enforcementOverride and creates it.  Returns the server's representation of the enforcementOverride, and an error, if there is any.
+func (c *enforcementOverrides) Create(ctx context.Context, enforcementOverride *v1alpha1.EnforcementOverride, opts v1.CreateOptions) (result *v1alpha1.EnforcementOverride, err error) {
+	result = &v1alpha1.EnforcementOverride{}
+	err = c.client.Post().
+		Resource("enforcementoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(enforcementOverride).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a enforcementOverride and updates it. Returns the server's representation of the enforcementOverride, and an error, if there is any.
+func (c *enforcementOverrides) Update(ctx context.Context, enforcementOverride *v1alpha1.EnforcementOverride, opts v1.UpdateOptions) (result *v1alpha1.EnforcementOverride, err error) {
+	result = &v1alpha1.EnforcementOverride{}
+	err = c.client.Put().
+		Resource("enforcementoverrides").
+		Name(enforcementOverride.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(enforcementOverride).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the enforcementOverride and deletes it. Returns an error if one occurs.
+func (c *enforcementOverrides) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("enforcementoverrides").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *enforcementOverrides) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("enforcementoverrides").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched enforcementOverride.
+func (c *enforcementOverrides) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.EnforcementOverride, err error) {
+	result = &v1alpha1.EnforcementOverride{}
+	err = c.client.Patch(pt).
+		Resource("enforcementoverrides").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
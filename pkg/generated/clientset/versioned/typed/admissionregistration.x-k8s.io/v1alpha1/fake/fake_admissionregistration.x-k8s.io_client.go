@@ -28,6 +28,22 @@ type FakeAdmissionregistrationV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeAdmissionregistrationV1alpha1) EnforcementOverrides() v1alpha1.EnforcementOverrideInterface {
+	return &FakeEnforcementOverrides{c}
+}
+
+func (c *FakeAdmissionregistrationV1alpha1) NamespacedValidatingAdmissionPolicies(namespace string) v1alpha1.NamespacedValidatingAdmissionPolicyInterface {
+	return &FakeNamespacedValidatingAdmissionPolicies{c, namespace}
+}
+
+func (c *FakeAdmissionregistrationV1alpha1) NamespacedValidatingAdmissionPolicyBindings(namespace string) v1alpha1.NamespacedValidatingAdmissionPolicyBindingInterface {
+	return &FakeNamespacedValidatingAdmissionPolicyBindings{c, namespace}
+}
+
+func (c *FakeAdmissionregistrationV1alpha1) PolicyExceptions(namespace string) v1alpha1.PolicyExceptionInterface {
+	return &FakePolicyExceptions{c, namespace}
+}
+
 func (c *FakeAdmissionregistrationV1alpha1) ValidatingAdmissionPolicies() v1alpha1.ValidatingAdmissionPolicyInterface {
 	return &FakeValidatingAdmissionPolicies{c}
 }
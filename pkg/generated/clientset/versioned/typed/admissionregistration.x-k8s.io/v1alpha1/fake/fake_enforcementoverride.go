@@ -0,0 +1,121 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeEnforcementOverrides implements EnforcementOverrideInterface
+type FakeEnforcementOverrides struct {
+	Fake *FakeAdmissionregistrationV1alpha1
+}
+
+var enforcementoverridesResource = v1alpha1.SchemeGroupVersion.WithResource("enforcementoverrides")
+
+var enforcementoverridesKind = v1alpha1.SchemeGroupVersion.WithKind("EnforcementOverride")
+
+// Get takes name of the enforcementOverride, and returns the corresponding enforcementOverride object, and an error if there is any.
+func (c *FakeEnforcementOverrides) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.EnforcementOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(enforcementoverridesResource, name), &v1alpha1.EnforcementOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.EnforcementOverride), err
+}
+
+// List takes label and field selectors, and returns the list of EnforcementOverrides that match those selectors.
+func (c *FakeEnforcementOverrides) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.EnforcementOverrideList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(enforcementoverridesResource, enforcementoverridesKind, opts), &v1alpha1.EnforcementOverrideList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.EnforcementOverrideList{ListMeta: obj.(*v1alpha1.EnforcementOverrideList).ListMeta}
+	for _, item := range obj.(*v1alpha1.EnforcementOverrideList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested enforcementOverrides.
+func (c *FakeEnforcementOverrides) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(enforcementoverridesResource, opts))
+}
+
+// Create takes the representation of a enforcementOverride and creates it.  Returns the server's representation of the enforcementOverride, and an error, if there is any.
+func (c *FakeEnforcementOverrides) Create(ctx context.Context, enforcementOverride *v1alpha1.EnforcementOverride, opts v1.CreateOptions) (result *v1alpha1.EnforcementOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(enforcementoverridesResource, enforcementOverride), &v1alpha1.EnforcementOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.EnforcementOverride), err
+}
+
+// Update takes the representation of a enforcementOverride and updates it. Returns the server's representation of the enforcementOverride, and an error, if there is any.
+func (c *FakeEnforcementOverrides) Update(ctx context.Context, enforcementOverride *v1alpha1.EnforcementOverride, opts v1.UpdateOptions) (result *v1alpha1.EnforcementOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(enforcementoverridesResource, enforcementOverride), &v1alpha1.EnforcementOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.EnforcementOverride), err
+}
+
+// Delete takes name of the enforcementOverride and deletes it. Returns an error if one occurs.
+func (c *FakeEnforcementOverrides) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteActionWithOptions(enforcementoverridesResource, name, opts), &v1alpha1.EnforcementOverride{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeEnforcementOverrides) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(enforcementoverridesResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.EnforcementOverrideList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched enforcementOverride.
+func (c *FakeEnforcementOverrides) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.EnforcementOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(enforcementoverridesResource, name, pt, data, subresources...), &v1alpha1.EnforcementOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.EnforcementOverride), err
+}
@@ -0,0 +1,141 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeNamespacedValidatingAdmissionPolicies implements NamespacedValidatingAdmissionPolicyInterface
+type FakeNamespacedValidatingAdmissionPolicies struct {
+	Fake *FakeAdmissionregistrationV1alpha1
+	ns   string
+}
+
+var namespacedvalidatingadmissionpoliciesResource = v1alpha1.SchemeGroupVersion.WithResource("namespacedvalidatingadmissionpolicies")
+
+var namespacedvalidatingadmissionpoliciesKind = v1alpha1.SchemeGroupVersion.WithKind("NamespacedValidatingAdmissionPolicy")
+
+// Get takes name of the namespacedValidatingAdmissionPolicy, and returns the corresponding namespacedValidatingAdmissionPolicy object, and an error if there is any.
+func (c *FakeNamespacedValidatingAdmissionPolicies) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(namespacedvalidatingadmissionpoliciesResource, c.ns, name), &v1alpha1.NamespacedValidatingAdmissionPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicy), err
+}
+
+// List takes label and field selectors, and returns the list of NamespacedValidatingAdmissionPolicies that match those selectors.
+func (c *FakeNamespacedValidatingAdmissionPolicies) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(namespacedvalidatingadmissionpoliciesResource, namespacedvalidatingadmissionpoliciesKind, c.ns, opts), &v1alpha1.NamespacedValidatingAdmissionPolicyList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.NamespacedValidatingAdmissionPolicyList{ListMeta: obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyList).ListMeta}
+	for _, item := range obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested namespacedValidatingAdmissionPolicies.
+func (c *FakeNamespacedValidatingAdmissionPolicies) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(namespacedvalidatingadmissionpoliciesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a namespacedValidatingAdmissionPolicy and creates it.  Returns the server's representation of the namespacedValidatingAdmissionPolicy, and an error, if there is any.
+func (c *FakeNamespacedValidatingAdmissionPolicies) Create(ctx context.Context, namespacedValidatingAdmissionPolicy *v1alpha1.NamespacedValidatingAdmissionPolicy, opts v1.CreateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(namespacedvalidatingadmissionpoliciesResource, c.ns, namespacedValidatingAdmissionPolicy), &v1alpha1.NamespacedValidatingAdmissionPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicy), err
+}
+
+// Update takes the representation of a namespacedValidatingAdmissionPolicy and updates it. Returns the server's representation of the namespacedValidatingAdmissionPolicy, and an error, if there is any.
+func (c *FakeNamespacedValidatingAdmissionPolicies) Update(ctx context.Context, namespacedValidatingAdmissionPolicy *v1alpha1.NamespacedValidatingAdmissionPolicy, opts v1.UpdateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(namespacedvalidatingadmissionpoliciesResource, c.ns, namespacedValidatingAdmissionPolicy), &v1alpha1.NamespacedValidatingAdmissionPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicy), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeNamespacedValidatingAdmissionPolicies) UpdateStatus(ctx context.Context, namespacedValidatingAdmissionPolicy *v1alpha1.NamespacedValidatingAdmissionPolicy, opts v1.UpdateOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicy, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(namespacedvalidatingadmissionpoliciesResource, "status", c.ns, namespacedValidatingAdmissionPolicy), &v1alpha1.NamespacedValidatingAdmissionPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicy), err
+}
+
+// Delete takes name of the namespacedValidatingAdmissionPolicy and deletes it. Returns an error if one occurs.
+func (c *FakeNamespacedValidatingAdmissionPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(namespacedvalidatingadmissionpoliciesResource, c.ns, name, opts), &v1alpha1.NamespacedValidatingAdmissionPolicy{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeNamespacedValidatingAdmissionPolicies) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(namespacedvalidatingadmissionpoliciesResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.NamespacedValidatingAdmissionPolicyList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched namespacedValidatingAdmissionPolicy.
+func (c *FakeNamespacedValidatingAdmissionPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedValidatingAdmissionPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(namespacedvalidatingadmissionpoliciesResource, c.ns, name, pt, data, subresources...), &v1alpha1.NamespacedValidatingAdmissionPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicy), err
+}
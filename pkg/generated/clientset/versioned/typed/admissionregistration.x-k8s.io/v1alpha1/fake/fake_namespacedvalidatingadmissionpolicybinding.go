@@ -0,0 +1,141 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeNamespacedValidatingAdmissionPolicyBindings implements NamespacedValidatingAdmissionPolicyBindingInterface
+type FakeNamespacedValidatingAdmissionPolicyBindings struct {
+	Fake *FakeAdmissionregistrationV1alpha1
+	ns   string
+}
+
+var namespacedvalidatingadmissionpolicybindingsResource = v1alpha1.SchemeGroupVersion.WithResource("namespacedvalidatingadmissionpolicybindings")
+
+var namespacedvalidatingadmissionpolicybindingsKind = v1alpha1.SchemeGroupVersion.WithKind("NamespacedValidatingAdmissionPolicyBinding")
+
+// Get takes name of the namespacedValidatingAdmissionPolicyBinding, and returns the corresponding namespacedValidatingAdmissionPolicyBinding object, and an error if there is any.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(namespacedvalidatingadmissionpolicybindingsResource, c.ns, name), &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding), err
+}
+
+// List takes label and field selectors, and returns the list of NamespacedValidatingAdmissionPolicyBindings that match those selectors.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBindingList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(namespacedvalidatingadmissionpolicybindingsResource, namespacedvalidatingadmissionpolicybindingsKind, c.ns, opts), &v1alpha1.NamespacedValidatingAdmissionPolicyBindingList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.NamespacedValidatingAdmissionPolicyBindingList{ListMeta: obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBindingList).ListMeta}
+	for _, item := range obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBindingList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested namespacedValidatingAdmissionPolicyBindings.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(namespacedvalidatingadmissionpolicybindingsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a namespacedValidatingAdmissionPolicyBinding and creates it.  Returns the server's representation of the namespacedValidatingAdmissionPolicyBinding, and an error, if there is any.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) Create(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.CreateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(namespacedvalidatingadmissionpolicybindingsResource, c.ns, namespacedValidatingAdmissionPolicyBinding), &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding), err
+}
+
+// Update takes the representation of a namespacedValidatingAdmissionPolicyBinding and updates it. Returns the server's representation of the namespacedValidatingAdmissionPolicyBinding, and an error, if there is any.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) Update(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(namespacedvalidatingadmissionpolicybindingsResource, c.ns, namespacedValidatingAdmissionPolicyBinding), &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) UpdateStatus(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(namespacedvalidatingadmissionpolicybindingsResource, "status", c.ns, namespacedValidatingAdmissionPolicyBinding), &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding), err
+}
+
+// Delete takes name of the namespacedValidatingAdmissionPolicyBinding and deletes it. Returns an error if one occurs.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(namespacedvalidatingadmissionpolicybindingsResource, c.ns, name, opts), &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(namespacedvalidatingadmissionpolicybindingsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.NamespacedValidatingAdmissionPolicyBindingList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched namespacedValidatingAdmissionPolicyBinding.
+func (c *FakeNamespacedValidatingAdmissionPolicyBindings) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(namespacedvalidatingadmissionpolicybindingsResource, c.ns, name, pt, data, subresources...), &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding), err
+}
@@ -95,6 +95,17 @@ func (c *FakeValidatingAdmissionPolicyBindings) Update(ctx context.Context, vali
 	return obj.(*v1alpha1.ValidatingAdmissionPolicyBinding), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeValidatingAdmissionPolicyBindings) UpdateStatus(ctx context.Context, validatingAdmissionPolicyBinding *v1alpha1.ValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (*v1alpha1.ValidatingAdmissionPolicyBinding, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(validatingadmissionpolicybindingsResource, "status", validatingAdmissionPolicyBinding), &v1alpha1.ValidatingAdmissionPolicyBinding{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ValidatingAdmissionPolicyBinding), err
+}
+
 // Delete takes name of the validatingAdmissionPolicyBinding and deletes it. Returns an error if one occurs.
 func (c *FakeValidatingAdmissionPolicyBindings) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
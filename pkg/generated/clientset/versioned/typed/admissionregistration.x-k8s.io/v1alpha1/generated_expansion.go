@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+type EnforcementOverrideExpansion interface{}
+
+type NamespacedValidatingAdmissionPolicyExpansion interface{}
+
+type NamespacedValidatingAdmissionPolicyBindingExpansion interface{}
+
+type PolicyExceptionExpansion interface{}
+
 type ValidatingAdmissionPolicyExpansion interface{}
 
 type ValidatingAdmissionPolicyBindingExpansion interface{}
@@ -0,0 +1,195 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	scheme "k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+)
+
+// NamespacedValidatingAdmissionPolicyBindingsGetter has a method to return a NamespacedValidatingAdmissionPolicyBindingInterface.
+// A group's client should implement this interface.
+type NamespacedValidatingAdmissionPolicyBindingsGetter interface {
+	NamespacedValidatingAdmissionPolicyBindings(namespace string) NamespacedValidatingAdmissionPolicyBindingInterface
+}
+
+// NamespacedValidatingAdmissionPolicyBindingInterface has methods to work with NamespacedValidatingAdmissionPolicyBinding resources.
+type NamespacedValidatingAdmissionPolicyBindingInterface interface {
+	Create(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.CreateOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error)
+	Update(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error)
+	UpdateStatus(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.NamespacedValidatingAdmissionPolicyBindingList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error)
+	NamespacedValidatingAdmissionPolicyBindingExpansion
+}
+
+// namespacedValidatingAdmissionPolicyBindings implements NamespacedValidatingAdmissionPolicyBindingInterface
+type namespacedValidatingAdmissionPolicyBindings struct {
+	client rest.Interface
+	ns     string
+}
+
+// newNamespacedValidatingAdmissionPolicyBindings returns a NamespacedValidatingAdmissionPolicyBindings
+func newNamespacedValidatingAdmissionPolicyBindings(c *AdmissionregistrationV1alpha1Client, namespace string) *namespacedValidatingAdmissionPolicyBindings {
+	return &namespacedValidatingAdmissionPolicyBindings{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the namespacedValidatingAdmissionPolicyBinding, and returns the corresponding namespacedValidatingAdmissionPolicyBinding object, and an error if there is any.
+func (c *namespacedValidatingAdmissionPolicyBindings) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	result = &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of NamespacedValidatingAdmissionPolicyBindings that match those selectors.
+func (c *namespacedValidatingAdmissionPolicyBindings) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBindingList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.NamespacedValidatingAdmissionPolicyBindingList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested namespacedValidatingAdmissionPolicyBindings.
+func (c *namespacedValidatingAdmissionPolicyBindings) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a namespacedValidatingAdmissionPolicyBinding and creates it.  Returns the server's representation of the namespacedValidatingAdmissionPolicyBinding, and an error, if there is any.
+func (c *namespacedValidatingAdmissionPolicyBindings) Create(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.CreateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	result = &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(namespacedValidatingAdmissionPolicyBinding).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a namespacedValidatingAdmissionPolicyBinding and updates it. Returns the server's representation of the namespacedValidatingAdmissionPolicyBinding, and an error, if there is any.
+func (c *namespacedValidatingAdmissionPolicyBindings) Update(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	result = &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		Name(namespacedValidatingAdmissionPolicyBinding.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(namespacedValidatingAdmissionPolicyBinding).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *namespacedValidatingAdmissionPolicyBindings) UpdateStatus(ctx context.Context, namespacedValidatingAdmissionPolicyBinding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, opts v1.UpdateOptions) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	result = &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		Name(namespacedValidatingAdmissionPolicyBinding.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(namespacedValidatingAdmissionPolicyBinding).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the namespacedValidatingAdmissionPolicyBinding and deletes it. Returns an error if one occurs.
+func (c *namespacedValidatingAdmissionPolicyBindings) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *namespacedValidatingAdmissionPolicyBindings) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched namespacedValidatingAdmissionPolicyBinding.
+func (c *namespacedValidatingAdmissionPolicyBindings) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	result = &v1alpha1.NamespacedValidatingAdmissionPolicyBinding{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("namespacedvalidatingadmissionpolicybindings").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -24,6 +24,14 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// EnforcementOverrides returns a EnforcementOverrideInformer.
+	EnforcementOverrides() EnforcementOverrideInformer
+	// NamespacedValidatingAdmissionPolicies returns a NamespacedValidatingAdmissionPolicyInformer.
+	NamespacedValidatingAdmissionPolicies() NamespacedValidatingAdmissionPolicyInformer
+	// NamespacedValidatingAdmissionPolicyBindings returns a NamespacedValidatingAdmissionPolicyBindingInformer.
+	NamespacedValidatingAdmissionPolicyBindings() NamespacedValidatingAdmissionPolicyBindingInformer
+	// PolicyExceptions returns a PolicyExceptionInformer.
+	PolicyExceptions() PolicyExceptionInformer
 	// ValidatingAdmissionPolicies returns a ValidatingAdmissionPolicyInformer.
 	ValidatingAdmissionPolicies() ValidatingAdmissionPolicyInformer
 	// ValidatingAdmissionPolicyBindings returns a ValidatingAdmissionPolicyBindingInformer.
@@ -41,6 +49,26 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// EnforcementOverrides returns a EnforcementOverrideInformer.
+func (v *version) EnforcementOverrides() EnforcementOverrideInformer {
+	return &enforcementOverrideInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// NamespacedValidatingAdmissionPolicies returns a NamespacedValidatingAdmissionPolicyInformer.
+func (v *version) NamespacedValidatingAdmissionPolicies() NamespacedValidatingAdmissionPolicyInformer {
+	return &namespacedValidatingAdmissionPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// NamespacedValidatingAdmissionPolicyBindings returns a NamespacedValidatingAdmissionPolicyBindingInformer.
+func (v *version) NamespacedValidatingAdmissionPolicyBindings() NamespacedValidatingAdmissionPolicyBindingInformer {
+	return &namespacedValidatingAdmissionPolicyBindingInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// PolicyExceptions returns a PolicyExceptionInformer.
+func (v *version) PolicyExceptions() PolicyExceptionInformer {
+	return &policyExceptionInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // ValidatingAdmissionPolicies returns a ValidatingAdmissionPolicyInformer.
 func (v *version) ValidatingAdmissionPolicies() ValidatingAdmissionPolicyInformer {
 	return &validatingAdmissionPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,68 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// EnforcementOverrideLister helps list EnforcementOverrides.
+// All objects returned here must be treated as read-only.
+type EnforcementOverrideLister interface {
+	// List lists all EnforcementOverrides in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.EnforcementOverride, err error)
+	// Get retrieves the EnforcementOverride from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.EnforcementOverride, error)
+	EnforcementOverrideListerExpansion
+}
+
+// enforcementOverrideLister implements the EnforcementOverrideLister interface.
+type enforcementOverrideLister struct {
+	indexer cache.Indexer
+}
+
+// NewEnforcementOverrideLister returns a new EnforcementOverrideLister.
+func NewEnforcementOverrideLister(indexer cache.Indexer) EnforcementOverrideLister {
+	return &enforcementOverrideLister{indexer: indexer}
+}
+
+// List lists all EnforcementOverrides in the indexer.
+func (s *enforcementOverrideLister) List(selector labels.Selector) (ret []*v1alpha1.EnforcementOverride, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.EnforcementOverride))
+	})
+	return ret, err
+}
+
+// Get retrieves the EnforcementOverride from the index for a given name.
+func (s *enforcementOverrideLister) Get(name string) (*v1alpha1.EnforcementOverride, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("enforcementoverride"), name)
+	}
+	return obj.(*v1alpha1.EnforcementOverride), nil
+}
@@ -18,6 +18,34 @@ limitations under the License.
 
 package v1alpha1
 
+// EnforcementOverrideListerExpansion allows custom methods to be added to
+// EnforcementOverrideLister.
+type EnforcementOverrideListerExpansion interface{}
+
+// NamespacedValidatingAdmissionPolicyListerExpansion allows custom methods to be added to
+// NamespacedValidatingAdmissionPolicyLister.
+type NamespacedValidatingAdmissionPolicyListerExpansion interface{}
+
+// NamespacedValidatingAdmissionPolicyNamespaceListerExpansion allows custom methods to be added to
+// NamespacedValidatingAdmissionPolicyNamespaceLister.
+type NamespacedValidatingAdmissionPolicyNamespaceListerExpansion interface{}
+
+// NamespacedValidatingAdmissionPolicyBindingListerExpansion allows custom methods to be added to
+// NamespacedValidatingAdmissionPolicyBindingLister.
+type NamespacedValidatingAdmissionPolicyBindingListerExpansion interface{}
+
+// NamespacedValidatingAdmissionPolicyBindingNamespaceListerExpansion allows custom methods to be added to
+// NamespacedValidatingAdmissionPolicyBindingNamespaceLister.
+type NamespacedValidatingAdmissionPolicyBindingNamespaceListerExpansion interface{}
+
+// PolicyExceptionListerExpansion allows custom methods to be added to
+// PolicyExceptionLister.
+type PolicyExceptionListerExpansion interface{}
+
+// PolicyExceptionNamespaceListerExpansion allows custom methods to be added to
+// PolicyExceptionNamespaceLister.
+type PolicyExceptionNamespaceListerExpansion interface{}
+
 // ValidatingAdmissionPolicyListerExpansion allows custom methods to be added to
 // ValidatingAdmissionPolicyLister.
 type ValidatingAdmissionPolicyListerExpansion interface{}
@@ -0,0 +1,99 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespacedValidatingAdmissionPolicyLister helps list NamespacedValidatingAdmissionPolicies.
+// All objects returned here must be treated as read-only.
+type NamespacedValidatingAdmissionPolicyLister interface {
+	// List lists all NamespacedValidatingAdmissionPolicies in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicy, err error)
+	// NamespacedValidatingAdmissionPolicies returns an object that can list and get NamespacedValidatingAdmissionPolicies.
+	NamespacedValidatingAdmissionPolicies(namespace string) NamespacedValidatingAdmissionPolicyNamespaceLister
+	NamespacedValidatingAdmissionPolicyListerExpansion
+}
+
+// namespacedValidatingAdmissionPolicyLister implements the NamespacedValidatingAdmissionPolicyLister interface.
+type namespacedValidatingAdmissionPolicyLister struct {
+	indexer cache.Indexer
+}
+
+// NewNamespacedValidatingAdmissionPolicyLister returns a new NamespacedValidatingAdmissionPolicyLister.
+func NewNamespacedValidatingAdmissionPolicyLister(indexer cache.Indexer) NamespacedValidatingAdmissionPolicyLister {
+	return &namespacedValidatingAdmissionPolicyLister{indexer: indexer}
+}
+
+// List lists all NamespacedValidatingAdmissionPolicies in the indexer.
+func (s *namespacedValidatingAdmissionPolicyLister) List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicy, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.NamespacedValidatingAdmissionPolicy))
+	})
+	return ret, err
+}
+
+// NamespacedValidatingAdmissionPolicies returns an object that can list and get NamespacedValidatingAdmissionPolicies.
+func (s *namespacedValidatingAdmissionPolicyLister) NamespacedValidatingAdmissionPolicies(namespace string) NamespacedValidatingAdmissionPolicyNamespaceLister {
+	return namespacedValidatingAdmissionPolicyNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// NamespacedValidatingAdmissionPolicyNamespaceLister helps list and get NamespacedValidatingAdmissionPolicies.
+// All objects returned here must be treated as read-only.
+type NamespacedValidatingAdmissionPolicyNamespaceLister interface {
+	// List lists all NamespacedValidatingAdmissionPolicies in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicy, err error)
+	// Get retrieves the NamespacedValidatingAdmissionPolicy from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.NamespacedValidatingAdmissionPolicy, error)
+	NamespacedValidatingAdmissionPolicyNamespaceListerExpansion
+}
+
+// namespacedValidatingAdmissionPolicyNamespaceLister implements the NamespacedValidatingAdmissionPolicyNamespaceLister
+// interface.
+type namespacedValidatingAdmissionPolicyNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all NamespacedValidatingAdmissionPolicies in the indexer for a given namespace.
+func (s namespacedValidatingAdmissionPolicyNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicy, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.NamespacedValidatingAdmissionPolicy))
+	})
+	return ret, err
+}
+
+// Get retrieves the NamespacedValidatingAdmissionPolicy from the indexer for a given namespace and name.
+func (s namespacedValidatingAdmissionPolicyNamespaceLister) Get(name string) (*v1alpha1.NamespacedValidatingAdmissionPolicy, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("namespacedvalidatingadmissionpolicy"), name)
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicy), nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	v1alpha1 "k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespacedValidatingAdmissionPolicyBindingLister helps list NamespacedValidatingAdmissionPolicyBindings.
+// All objects returned here must be treated as read-only.
+type NamespacedValidatingAdmissionPolicyBindingLister interface {
+	// List lists all NamespacedValidatingAdmissionPolicyBindings in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error)
+	// NamespacedValidatingAdmissionPolicyBindings returns an object that can list and get NamespacedValidatingAdmissionPolicyBindings.
+	NamespacedValidatingAdmissionPolicyBindings(namespace string) NamespacedValidatingAdmissionPolicyBindingNamespaceLister
+	NamespacedValidatingAdmissionPolicyBindingListerExpansion
+}
+
+// namespacedValidatingAdmissionPolicyBindingLister implements the NamespacedValidatingAdmissionPolicyBindingLister interface.
+type namespacedValidatingAdmissionPolicyBindingLister struct {
+	indexer cache.Indexer
+}
+
+// NewNamespacedValidatingAdmissionPolicyBindingLister returns a new NamespacedValidatingAdmissionPolicyBindingLister.
+func NewNamespacedValidatingAdmissionPolicyBindingLister(indexer cache.Indexer) NamespacedValidatingAdmissionPolicyBindingLister {
+	return &namespacedValidatingAdmissionPolicyBindingLister{indexer: indexer}
+}
+
+// List lists all NamespacedValidatingAdmissionPolicyBindings in the indexer.
+func (s *namespacedValidatingAdmissionPolicyBindingLister) List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding))
+	})
+	return ret, err
+}
+
+// NamespacedValidatingAdmissionPolicyBindings returns an object that can list and get NamespacedValidatingAdmissionPolicyBindings.
+func (s *namespacedValidatingAdmissionPolicyBindingLister) NamespacedValidatingAdmissionPolicyBindings(namespace string) NamespacedValidatingAdmissionPolicyBindingNamespaceLister {
+	return namespacedValidatingAdmissionPolicyBindingNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// NamespacedValidatingAdmissionPolicyBindingNamespaceLister helps list and get NamespacedValidatingAdmissionPolicyBindings.
+// All objects returned here must be treated as read-only.
+type NamespacedValidatingAdmissionPolicyBindingNamespaceLister interface {
+	// List lists all NamespacedValidatingAdmissionPolicyBindings in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error)
+	// Get retrieves the NamespacedValidatingAdmissionPolicyBinding from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error)
+	NamespacedValidatingAdmissionPolicyBindingNamespaceListerExpansion
+}
+
+// namespacedValidatingAdmissionPolicyBindingNamespaceLister implements the NamespacedValidatingAdmissionPolicyBindingNamespaceLister
+// interface.
+type namespacedValidatingAdmissionPolicyBindingNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all NamespacedValidatingAdmissionPolicyBindings in the indexer for a given namespace.
+func (s namespacedValidatingAdmissionPolicyBindingNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding))
+	})
+	return ret, err
+}
+
+// Get retrieves the NamespacedValidatingAdmissionPolicyBinding from the indexer for a given namespace and name.
+func (s namespacedValidatingAdmissionPolicyBindingNamespaceLister) Get(name string) (*v1alpha1.NamespacedValidatingAdmissionPolicyBinding, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("namespacedvalidatingadmissionpolicybinding"), name)
+	}
+	return obj.(*v1alpha1.NamespacedValidatingAdmissionPolicyBinding), nil
+}
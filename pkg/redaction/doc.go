@@ -0,0 +1,8 @@
+// Package redaction keeps the sensitive content of the objects of admission
+// requests, such as the data of Secrets, out of the text the shim emits.
+//
+// Rather than tracking where a value flows, such as into the message of a
+// messageExpression, the sensitive values of the objects of a request are
+// collected up front, and replaced wherever they appear in logs, messages,
+// warnings and audit annotations.
+package redaction
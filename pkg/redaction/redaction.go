@@ -0,0 +1,225 @@
+package redaction
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	// Redacted replaces sensitive values.
+	Redacted = "<redacted>"
+
+	// minValueLength is the length of the shortest values which are redacted.
+	// Values are replaced wherever they appear, so shorter values, such as
+	// true, admin or default, would garble unrelated text of messages
+	// without protecting anything worth protecting.
+	minValueLength = 8
+)
+
+// Rule selects the sensitive fields of the objects of a resource.
+type Rule struct {
+	// Resource of the objects. An empty resource selects every resource.
+	Resource schema.GroupResource
+	// Path is a JSONPath template selecting the sensitive fields, such as
+	// {.data.*}. All the values nested in the selected fields are sensitive.
+	Path string
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s:%s", r.Resource.String(), r.Path)
+}
+
+// DefaultRules redact the data of Secrets.
+var DefaultRules = []Rule{
+	{Resource: schema.GroupResource{Resource: "secrets"}, Path: "{.data.*}"},
+	{Resource: schema.GroupResource{Resource: "secrets"}, Path: "{.stringData.*}"},
+}
+
+// ParseRules parses a semicolon separated list of rules, each written as
+// resource.group:{jsonpath}, such as configmaps:{.data.password}. An empty
+// resource, as in :{.spec.token}, selects every resource.
+func ParseRules(value string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		resource, path, found := strings.Cut(item, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid redaction rule %q, expected resource.group:{jsonpath}", item)
+		}
+		rules = append(rules, Rule{Resource: schema.ParseGroupResource(resource), Path: path})
+	}
+	return rules, nil
+}
+
+// Redactor finds the sensitive values of the objects of requests according to
+// its rules.
+type Redactor struct {
+	// rules are a []Rule, replaced as a whole when they are reconfigured.
+	rules atomic.Value
+}
+
+// NewRedactor returns a Redactor which applies the given rules, or an error if
+// one of their paths is invalid.
+func NewRedactor(rules ...Rule) (*Redactor, error) {
+	r := &Redactor{}
+	if err := r.SetRules(rules...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetRules replaces the rules of the Redactor, for the requests handled from
+// then on. The rules are left unchanged if one of the paths is invalid.
+func (r *Redactor) SetRules(rules ...Rule) error {
+	for _, rule := range rules {
+		if err := jsonpath.New(rule.String()).Parse(rule.Path); err != nil {
+			return fmt.Errorf("invalid redaction rule %q: %w", rule.String(), err)
+		}
+	}
+	r.rules.Store(rules)
+	return nil
+}
+
+// ForRequest returns the Redaction of the sensitive values of the objects of
+// a request for the given resource.
+func (r *Redactor) ForRequest(resource schema.GroupResource, objects ...runtime.Object) *Redaction {
+	if r == nil {
+		return nil
+	}
+	values := map[string]bool{}
+	for _, rule := range r.rules.Load().([]Rule) {
+		if len(rule.Resource.Resource) > 0 && rule.Resource != resource {
+			continue
+		}
+		for _, obj := range objects {
+			collectValues(rule, obj, values)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	redaction := &Redaction{}
+	for value := range values {
+		redaction.values = append(redaction.values, value)
+	}
+	// Replace longer values first, in case they contain shorter ones.
+	sort.Slice(redaction.values, func(i, j int) bool {
+		return len(redaction.values[i]) > len(redaction.values[j])
+	})
+	return redaction
+}
+
+func collectValues(rule Rule, obj runtime.Object, values map[string]bool) {
+	if obj == nil || reflect.ValueOf(obj).IsNil() {
+		return
+	}
+	var content map[string]interface{}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return
+		}
+	}
+
+	path := jsonpath.New(rule.String()).AllowMissingKeys(true)
+	if err := path.Parse(rule.Path); err != nil {
+		return
+	}
+	results, err := path.FindResults(content)
+	if err != nil {
+		return
+	}
+	for _, result := range results {
+		for _, value := range result {
+			collectValue(value.Interface(), values)
+		}
+	}
+}
+
+func collectValue(value interface{}, values map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			collectValue(item, values)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectValue(item, values)
+		}
+	case string:
+		addValue(v, values)
+		// The data of Secrets is base64 encoded in requests, while
+		// expressions may decode it.
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil && utf8.Valid(decoded) {
+			addValue(string(decoded), values)
+		}
+	case nil:
+	default:
+		addValue(fmt.Sprint(v), values)
+	}
+}
+
+func addValue(value string, values map[string]bool) {
+	if len(value) >= minValueLength {
+		values[value] = true
+	}
+}
+
+// Redaction replaces the sensitive values of the objects of a request in the
+// text which leaves the process, such as logs, messages, warnings and audit
+// annotations. A nil Redaction redacts nothing.
+type Redaction struct {
+	values []string
+}
+
+// String returns s with every sensitive value replaced.
+func (r *Redaction) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, value := range r.values {
+		if strings.Contains(s, value) {
+			s = strings.ReplaceAll(s, value, Redacted)
+		}
+	}
+	return s
+}
+
+// Strings redacts every string of a list in place, and returns it.
+func (r *Redaction) Strings(list []string) []string {
+	for i := range list {
+		list[i] = r.String(list[i])
+	}
+	return list
+}
+
+type redactionKey struct{}
+
+// WithRedaction returns a context carrying the redaction of the request it
+// is the context of.
+func WithRedaction(ctx context.Context, redaction *Redaction) context.Context {
+	return context.WithValue(ctx, redactionKey{}, redaction)
+}
+
+// FromContext returns the redaction of the request of the context, if any.
+func FromContext(ctx context.Context) *Redaction {
+	redaction, _ := ctx.Value(redactionKey{}).(*Redaction)
+	return redaction
+}
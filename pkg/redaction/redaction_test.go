@@ -0,0 +1,103 @@
+package redaction
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRedaction(t *testing.T) {
+	configRules, err := ParseRules("configmaps:{.data.password}; widgets.example.com:{.spec.credentials}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, err := NewRedactor(append(DefaultRules, configRules...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t-token")},
+		StringData: map[string]string{"password": "hunter22"},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings"},
+		Data:       map[string]string{"password": "letmein!", "user": "admin-user"},
+	}
+	// Short values would blank unrelated text wherever it appears.
+	shortSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "short"},
+		StringData: map[string]string{"enabled": "true", "user": "admin", "namespace": "default"},
+	}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"credentials": map[string]interface{}{"keys": []interface{}{"first-key", "second-key"}, "port": int64(12345678)},
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		resource schema.GroupResource
+		objects  []runtime.Object
+		input    string
+		expected string
+	}{
+		{
+			name:     "secret data, encoded and decoded",
+			resource: schema.GroupResource{Resource: "secrets"},
+			objects:  []runtime.Object{secret, nil},
+			input:    "token czNjcjN0LXRva2Vu is s3cr3t-token, password hunter22",
+			expected: "token <redacted> is <redacted>, password <redacted>",
+		},
+		{
+			name:     "configured path",
+			resource: schema.GroupResource{Resource: "configmaps"},
+			objects:  []runtime.Object{configMap},
+			input:    "admin-user may not use letmein!",
+			expected: "admin-user may not use <redacted>",
+		},
+		{
+			name:     "nested values",
+			resource: schema.GroupResource{Group: "example.com", Resource: "widgets"},
+			objects:  []runtime.Object{widget},
+			input:    "first-key,second-key:12345678",
+			expected: "<redacted>,<redacted>:<redacted>",
+		},
+		{
+			name:     "short values",
+			resource: schema.GroupResource{Resource: "secrets"},
+			objects:  []runtime.Object{shortSecret},
+			input:    "admin may not use the default namespace: true",
+			expected: "admin may not use the default namespace: true",
+		},
+		{
+			name:     "other resource",
+			resource: schema.GroupResource{Resource: "configmaps"},
+			objects:  []runtime.Object{secret},
+			input:    "s3cr3t-token",
+			expected: "s3cr3t-token",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := redactor.ForRequest(tc.resource, tc.objects...).String(tc.input)
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	if _, err := ParseRules("configmaps"); err == nil {
+		t.Error("expected an error for a rule without path")
+	}
+	if _, err := NewRedactor(Rule{Resource: schema.GroupResource{Resource: "configmaps"}, Path: "{.data["}); err == nil {
+		t.Error("expected an error for an invalid path")
+	}
+}
@@ -0,0 +1,4 @@
+// Package replicastatus publishes the policies and bindings loaded by each
+// replica of the webhook, so that a rollout of a policy change can be
+// confirmed across replicas.
+package replicastatus
@@ -0,0 +1,116 @@
+package replicastatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// publishInterval is the interval at which the loaded generations are checked
+// for changes.
+const publishInterval = time.Second
+
+// Publisher publishes the generations loaded by this replica in its own Lease.
+type Publisher struct {
+	client        kubernetes.Interface
+	namespace     string
+	name          string
+	identity      string
+	leaseDuration time.Duration
+	loaded        func() Generations
+
+	// lease is the Lease last written, nil if it must be read again.
+	lease     *coordinationv1.Lease
+	published string
+}
+
+// NewPublisher returns a Publisher of the generations returned by loaded, in a
+// Lease named after the host name of the replica. The replica counts as live
+// for leaseDuration after each renewal.
+func NewPublisher(client kubernetes.Interface, namespace string, leaseDuration time.Duration, loaded func() Generations) (*Publisher, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify the replica: %w", err)
+	}
+	return &Publisher{
+		client:        client,
+		namespace:     namespace,
+		name:          "cel-admission-webhook-" + hostname,
+		identity:      hostname,
+		leaseDuration: leaseDuration,
+		loaded:        loaded,
+	}, nil
+}
+
+// Run publishes the loaded generations as they change until the context is
+// done, then deletes the Lease so that the replica is no longer counted.
+func (p *Publisher) Run(ctx context.Context) error {
+	wait.UntilWithContext(ctx, p.publish, publishInterval)
+
+	deleteCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := p.client.CoordinationV1().Leases(p.namespace).Delete(deleteCtx, p.name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		klog.ErrorS(err, "Failed to delete the Lease of the replica", "lease", klog.KRef(p.namespace, p.name))
+	}
+	return nil
+}
+
+func (p *Publisher) publish(ctx context.Context) {
+	data, err := json.Marshal(p.loaded())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	now := time.Now()
+	// Renew the Lease well before it expires, even if nothing changed.
+	if p.lease != nil && string(data) == p.published &&
+		p.lease.Spec.RenewTime != nil && now.Sub(p.lease.Spec.RenewTime.Time) < p.leaseDuration/3 {
+		return
+	}
+
+	leases := p.client.CoordinationV1().Leases(p.namespace)
+	lease, create := p.lease, false
+	if lease == nil {
+		lease, err = leases.Get(ctx, p.name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			lease, create = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: p.namespace, Name: p.name}}, true
+		} else if err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to get the Lease of the replica: %w", err))
+			return
+		}
+	}
+
+	updated := lease.DeepCopy()
+	metav1.SetMetaDataLabel(&updated.ObjectMeta, ReplicaLabel, "true")
+	metav1.SetMetaDataAnnotation(&updated.ObjectMeta, LoadedGenerationsAnnotation, string(data))
+	leaseDurationSeconds := int32(p.leaseDuration / time.Second)
+	updated.Spec = coordinationv1.LeaseSpec{
+		HolderIdentity:       &p.identity,
+		LeaseDurationSeconds: &leaseDurationSeconds,
+		RenewTime:            &metav1.MicroTime{Time: now},
+	}
+	if create {
+		lease, err = leases.Create(ctx, updated, metav1.CreateOptions{})
+	} else {
+		lease, err = leases.Update(ctx, updated, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		// Read the Lease again, in case it was changed or deleted.
+		p.lease = nil
+		utilruntime.HandleError(fmt.Errorf("failed to publish the loaded policies in the Lease of the replica: %w", err))
+		return
+	}
+	p.lease = lease
+	p.published = string(data)
+}
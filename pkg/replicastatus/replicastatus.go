@@ -0,0 +1,65 @@
+package replicastatus
+
+import (
+	"encoding/json"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+const (
+	// ReplicaLabel marks the Leases of the replicas.
+	ReplicaLabel = v1alpha1.GroupName + "/replica"
+
+	// LoadedGenerationsAnnotation holds the Generations loaded by the
+	// replica holding the Lease, as JSON.
+	LoadedGenerationsAnnotation = v1alpha1.GroupName + "/loaded-generations"
+)
+
+// Generations are the generations of the policies and bindings loaded by a
+// replica, keyed by name, or by namespace/name for namespaced ones.
+//
+// Generations rather than resourceVersions are published, since writing the
+// status of a policy changes its resourceVersion but not how it is enforced.
+type Generations struct {
+	Policies map[string]int64 `json:"policies,omitempty"`
+	Bindings map[string]int64 `json:"bindings,omitempty"`
+}
+
+// LiveReplicas returns the generations loaded by the replicas whose Lease has
+// not expired. A replica whose annotation cannot be parsed counts as having
+// loaded nothing.
+func LiveReplicas(leases []*coordinationv1.Lease, now time.Time) []Generations {
+	var replicas []Generations
+	for _, lease := range leases {
+		if Expired(lease, now) {
+			continue
+		}
+		var generations Generations
+		_ = json.Unmarshal([]byte(lease.Annotations[LoadedGenerationsAnnotation]), &generations)
+		replicas = append(replicas, generations)
+	}
+	return replicas
+}
+
+// Ready returns on how many of the replicas the given generation of the
+// policy, or a later one, is loaded.
+func Ready(replicas []Generations, policy string, generation int64) int {
+	ready := 0
+	for _, replica := range replicas {
+		if loaded, ok := replica.Policies[policy]; ok && loaded >= generation {
+			ready++
+		}
+	}
+	return ready
+}
+
+// Expired tells whether the replica holding the Lease stopped renewing it.
+func Expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second).Before(now)
+}
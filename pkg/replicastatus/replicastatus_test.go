@@ -0,0 +1,158 @@
+package replicastatus
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newLease(renewed time.Time, durationSeconds int32, annotation string) *coordinationv1.Lease {
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{LoadedGenerationsAnnotation: annotation}},
+		Spec: coordinationv1.LeaseSpec{
+			RenewTime:            &metav1.MicroTime{Time: renewed},
+			LeaseDurationSeconds: &durationSeconds,
+		},
+	}
+	return lease
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+
+	for _, testCase := range []struct {
+		name     string
+		lease    *coordinationv1.Lease
+		expected bool
+	}{
+		{
+			name:  "renewed",
+			lease: newLease(now.Add(-10*time.Second), 30, ""),
+		},
+		{
+			name:  "expiring-now",
+			lease: newLease(now.Add(-30*time.Second), 30, ""),
+		},
+		{
+			name:     "expired",
+			lease:    newLease(now.Add(-31*time.Second), 30, ""),
+			expected: true,
+		},
+		{
+			name:     "never-renewed",
+			lease:    &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{LeaseDurationSeconds: new(int32)}},
+			expected: true,
+		},
+		{
+			name:     "no-duration",
+			lease:    &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{RenewTime: &metav1.MicroTime{Time: now}}},
+			expected: true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := Expired(testCase.lease, now); actual != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLiveReplicas(t *testing.T) {
+	now := time.Now()
+
+	for _, testCase := range []struct {
+		name     string
+		leases   []*coordinationv1.Lease
+		expected []Generations
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "live",
+			leases: []*coordinationv1.Lease{
+				newLease(now, 30, `{"policies":{"a":1},"bindings":{"b":2}}`),
+				newLease(now, 30, `{"policies":{"a":2}}`),
+			},
+			expected: []Generations{
+				{Policies: map[string]int64{"a": 1}, Bindings: map[string]int64{"b": 2}},
+				{Policies: map[string]int64{"a": 2}},
+			},
+		},
+		{
+			name: "expired-skipped",
+			leases: []*coordinationv1.Lease{
+				newLease(now.Add(-time.Minute), 30, `{"policies":{"a":1}}`),
+				newLease(now, 30, `{"policies":{"a":2}}`),
+			},
+			expected: []Generations{{Policies: map[string]int64{"a": 2}}},
+		},
+		{
+			name: "invalid-annotation",
+			leases: []*coordinationv1.Lease{
+				newLease(now, 30, `not json`),
+				newLease(now, 30, ""),
+			},
+			expected: []Generations{{}, {}},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := LiveReplicas(testCase.leases, now); !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReady(t *testing.T) {
+	replicas := []Generations{
+		{Policies: map[string]int64{"a": 1, "team/b": 3}},
+		{Policies: map[string]int64{"a": 2}},
+		{},
+	}
+
+	for _, testCase := range []struct {
+		name       string
+		policy     string
+		generation int64
+		expected   int
+	}{
+		{
+			name:       "loaded-everywhere-but-one",
+			policy:     "a",
+			generation: 1,
+			expected:   2,
+		},
+		{
+			name:       "later-generation-loaded",
+			policy:     "a",
+			generation: 2,
+			expected:   1,
+		},
+		{
+			name:       "not-loaded-yet",
+			policy:     "a",
+			generation: 3,
+		},
+		{
+			name:       "namespaced",
+			policy:     "team/b",
+			generation: 3,
+			expected:   1,
+		},
+		{
+			name:       "unknown",
+			policy:     "c",
+			generation: 1,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := Ready(replicas, testCase.policy, testCase.generation); actual != testCase.expected {
+				t.Errorf("expected %d, got %d", testCase.expected, actual)
+			}
+		})
+	}
+}
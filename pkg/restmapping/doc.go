@@ -0,0 +1,3 @@
+// Package restmapping provides the RESTMapper of the webhook, which follows
+// the resources served by the cluster as they are installed and removed.
+package restmapping
@@ -0,0 +1,249 @@
+package restmapping
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	crdinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// APIServicesResource is the resource to build the APIService informer of
+// New with, from a dynamic informer factory.
+var APIServicesResource = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+
+// onDemandRefreshInterval is the minimum interval between the refreshes
+// triggered by lookups of unknown resources and kinds, so that requests for
+// resources which really do not exist do not rerun discovery each time.
+const onDemandRefreshInterval = 10 * time.Second
+
+// RESTMapper is a RESTMapper backed by discovery. It is refreshed when
+// CustomResourceDefinitions or APIServices change, and when a lookup misses,
+// at most once per onDemandRefreshInterval.
+//
+// Discovery failing for some groups, typically those of unavailable
+// aggregated APIs, only degrades the RESTMapper: the groups which were
+// discovered remain mapped.
+type RESTMapper struct {
+	disco   discovery.DiscoveryInterface
+	limiter *rate.Limiter
+
+	lock sync.RWMutex
+	// mapper holds the discovered mappings, nil until the first lookup and
+	// after a reset.
+	mapper    meta.RESTMapper
+	refreshed time.Time
+	err       error
+	// degraded is the error of the groups whose discovery failed, when
+	// discovery otherwise succeeded.
+	degraded error
+}
+
+var _ meta.ResettableRESTMapper = (*RESTMapper)(nil)
+
+// New returns a RESTMapper refreshed on the events of the informers. It must
+// be called before the informers are started.
+func New(disco discovery.DiscoveryInterface, crdInformer crdinformers.CustomResourceDefinitionInformer, apiServiceInformer informers.GenericInformer) *RESTMapper {
+	m := newRESTMapper(disco)
+	for _, informer := range []cache.SharedIndexInformer{crdInformer.Informer(), apiServiceInformer.Informer()} {
+		if _, err := informer.AddEventHandler(m.eventHandler()); err != nil {
+			utilruntime.HandleError(err)
+		}
+	}
+	return m
+}
+
+func newRESTMapper(disco discovery.DiscoveryInterface) *RESTMapper {
+	return &RESTMapper{
+		disco:   disco,
+		limiter: rate.NewLimiter(rate.Every(onDemandRefreshInterval), 1),
+	}
+}
+
+// load returns the discovered mappings, running discovery if they were not
+// discovered yet or were reset. Failed discoveries are run again by the next
+// lookup.
+func (m *RESTMapper) load() (meta.RESTMapper, error) {
+	m.lock.RLock()
+	mapper := m.mapper
+	m.lock.RUnlock()
+	if mapper != nil {
+		return mapper, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.mapper != nil {
+		return m.mapper, nil
+	}
+	recording := &partialDiscovery{DiscoveryInterface: m.disco}
+	groupResources, err := restmapper.GetAPIGroupResources(recording)
+	if discovery.IsGroupDiscoveryFailedError(err) && len(groupResources) > 0 {
+		recording.err, err = err, nil
+	}
+	m.err = err
+	if err != nil {
+		return nil, err
+	}
+	m.degraded = recording.err
+	if m.degraded != nil {
+		klog.InfoS("Discovery failed for some groups, they are not mapped", "err", m.degraded)
+	}
+	m.refreshed = time.Now()
+	m.mapper = restmapper.NewDiscoveryRESTMapper(groupResources)
+	return m.mapper, nil
+}
+
+// partialDiscovery records the partial failures of discovery, which
+// restmapper.GetAPIGroupResources drops.
+type partialDiscovery struct {
+	discovery.DiscoveryInterface
+	err error
+}
+
+func (d *partialDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	groups, resources, err := d.DiscoveryInterface.ServerGroupsAndResources()
+	if discovery.IsGroupDiscoveryFailedError(err) {
+		d.err = err
+	}
+	return groups, resources, err
+}
+
+// eventHandler resets the RESTMapper when the objects of an informer change.
+func (m *RESTMapper) eventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			m.Reset()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Skip the resyncs of the informers.
+			if resourceVersion(oldObj) != resourceVersion(newObj) {
+				m.Reset()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			m.Reset()
+		},
+	}
+}
+
+func resourceVersion(obj interface{}) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
+
+// Reset discards the discovered mappings, so that the next lookup runs
+// discovery again.
+func (m *RESTMapper) Reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.mapper = nil
+}
+
+// Name implements the health check of the webhook.
+func (m *RESTMapper) Name() string {
+	return "restmapper"
+}
+
+// Check reports how long ago the mappings were discovered, and fails when the
+// last discovery did. Groups whose discovery failed are reported, but do not
+// fail the check.
+func (m *RESTMapper) Check() (string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.err != nil {
+		return "", fmt.Errorf("discovery failed: %w", m.err)
+	}
+	if m.refreshed.IsZero() {
+		return "not discovered yet", nil
+	}
+	message := fmt.Sprintf("refreshed %v ago", time.Since(m.refreshed).Round(time.Second))
+	if m.degraded != nil {
+		message += fmt.Sprintf(", degraded: %v", m.degraded)
+	}
+	return message, nil
+}
+
+// retry refreshes the mappings if the lookup missed and refreshing is not
+// rate limited, and reports whether the lookup should be retried.
+func (m *RESTMapper) retry(err error) bool {
+	if !meta.IsNoMatchError(err) || !m.limiter.Allow() {
+		return false
+	}
+	klog.V(2).InfoS("Refreshing REST mappings after a lookup miss", "err", err)
+	m.Reset()
+	return true
+}
+
+// lookup runs fn with the discovered mappings, and once more with refreshed
+// mappings if it missed.
+func lookup[T any](m *RESTMapper, fn func(meta.RESTMapper) (T, error)) (T, error) {
+	var zero T
+	mapper, err := m.load()
+	if err != nil {
+		return zero, err
+	}
+	result, err := fn(mapper)
+	if !m.retry(err) {
+		return result, err
+	}
+	if mapper, err = m.load(); err != nil {
+		return zero, err
+	}
+	return fn(mapper)
+}
+
+func (m *RESTMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	return lookup(m, func(mapper meta.RESTMapper) (schema.GroupVersionKind, error) {
+		return mapper.KindFor(resource)
+	})
+}
+
+func (m *RESTMapper) KindsFor(resource schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	return lookup(m, func(mapper meta.RESTMapper) ([]schema.GroupVersionKind, error) {
+		return mapper.KindsFor(resource)
+	})
+}
+
+func (m *RESTMapper) ResourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	return lookup(m, func(mapper meta.RESTMapper) (schema.GroupVersionResource, error) {
+		return mapper.ResourceFor(input)
+	})
+}
+
+func (m *RESTMapper) ResourcesFor(input schema.GroupVersionResource) ([]schema.GroupVersionResource, error) {
+	return lookup(m, func(mapper meta.RESTMapper) ([]schema.GroupVersionResource, error) {
+		return mapper.ResourcesFor(input)
+	})
+}
+
+func (m *RESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	return lookup(m, func(mapper meta.RESTMapper) (*meta.RESTMapping, error) {
+		return mapper.RESTMapping(gk, versions...)
+	})
+}
+
+func (m *RESTMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	return lookup(m, func(mapper meta.RESTMapper) ([]*meta.RESTMapping, error) {
+		return mapper.RESTMappings(gk, versions...)
+	})
+}
+
+func (m *RESTMapper) ResourceSingularizer(resource string) (string, error) {
+	return lookup(m, func(mapper meta.RESTMapper) (string, error) {
+		return mapper.ResourceSingularizer(resource)
+	})
+}
@@ -0,0 +1,185 @@
+package restmapping
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// fakeDiscovery serves the resources of its lists, and fails the discovery of
+// the group versions of failed. It counts the discoveries.
+type fakeDiscovery struct {
+	discovery.DiscoveryInterface
+	lists       []*metav1.APIResourceList
+	failed      []schema.GroupVersion
+	err         error
+	discoveries int
+}
+
+func (d *fakeDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	d.discoveries++
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+	var groups []*metav1.APIGroup
+	for _, list := range d.lists {
+		gv := schema.FromAPIVersionAndKind(list.GroupVersion, "").GroupVersion()
+		version := metav1.GroupVersionForDiscovery{GroupVersion: list.GroupVersion, Version: gv.Version}
+		groups = append(groups, &metav1.APIGroup{Name: gv.Group, Versions: []metav1.GroupVersionForDiscovery{version}, PreferredVersion: version})
+	}
+	if len(d.failed) == 0 {
+		return groups, d.lists, nil
+	}
+	failed := map[schema.GroupVersion]error{}
+	for _, gv := range d.failed {
+		failed[gv] = errors.New("service unavailable")
+	}
+	return groups, d.lists, &discovery.ErrGroupDiscoveryFailed{Groups: failed}
+}
+
+var (
+	widgets = &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "widgets", SingularName: "widget", Namespaced: true, Kind: "Widget", Verbs: metav1.Verbs{"get"}}},
+	}
+	gadgets = &metav1.APIResourceList{
+		GroupVersion: "example.com/v2",
+		APIResources: []metav1.APIResource{{Name: "gadgets", SingularName: "gadget", Namespaced: true, Kind: "Gadget", Verbs: metav1.Verbs{"get"}}},
+	}
+	widgetKind = schema.GroupKind{Group: "example.com", Kind: "Widget"}
+	gadgetKind = schema.GroupKind{Group: "example.com", Kind: "Gadget"}
+)
+
+func TestRESTMapperCheck(t *testing.T) {
+	for _, testCase := range []struct {
+		name            string
+		disco           *fakeDiscovery
+		expectedMessage string
+		expectedErr     string
+		expectedMapped  bool
+	}{
+		{
+			name:            "discovered",
+			disco:           &fakeDiscovery{lists: []*metav1.APIResourceList{widgets}},
+			expectedMessage: "refreshed",
+			expectedMapped:  true,
+		},
+		{
+			name:            "partial-failure",
+			disco:           &fakeDiscovery{lists: []*metav1.APIResourceList{widgets}, failed: []schema.GroupVersion{{Group: "metrics.k8s.io", Version: "v1beta1"}}},
+			expectedMessage: "degraded: unable to retrieve the complete list of server APIs: metrics.k8s.io/v1beta1",
+			expectedMapped:  true,
+		},
+		{
+			name:        "failure",
+			disco:       &fakeDiscovery{err: errors.New("connection refused")},
+			expectedErr: "discovery failed: connection refused",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := newRESTMapper(testCase.disco)
+			if message, err := m.Check(); message != "not discovered yet" || err != nil {
+				t.Errorf("expected the mappings not to be discovered yet, got %q, %v", message, err)
+			}
+
+			_, err := m.RESTMapping(widgetKind, "v1")
+			if testCase.expectedMapped && err != nil {
+				t.Errorf("expected widgets to be mapped, got %v", err)
+			} else if !testCase.expectedMapped && err == nil {
+				t.Error("expected widgets not to be mapped")
+			}
+
+			message, err := m.Check()
+			if len(testCase.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !strings.Contains(message, testCase.expectedMessage) {
+				t.Errorf("expected message containing %q, got %q", testCase.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestRESTMapperRefresh(t *testing.T) {
+	disco := &fakeDiscovery{lists: []*metav1.APIResourceList{widgets}}
+	m := newRESTMapper(disco)
+	handler := m.eventHandler()
+	expectDiscoveries := func(expected int) {
+		t.Helper()
+		if disco.discoveries != expected {
+			t.Errorf("expected %d discoveries, got %d", expected, disco.discoveries)
+		}
+	}
+
+	if _, err := m.RESTMapping(widgetKind, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.RESTMapping(widgetKind, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	expectDiscoveries(1)
+
+	// A lookup miss refreshes the mappings, but not more than once per
+	// onDemandRefreshInterval.
+	if _, err := m.RESTMapping(gadgetKind, "v2"); !meta.IsNoMatchError(err) {
+		t.Fatalf("expected gadgets not to be mapped, got %v", err)
+	}
+	expectDiscoveries(2)
+	if _, err := m.RESTMapping(gadgetKind, "v2"); !meta.IsNoMatchError(err) {
+		t.Fatalf("expected gadgets not to be mapped, got %v", err)
+	}
+	expectDiscoveries(2)
+
+	// The resyncs of the informers do not reset the mappings, while changes
+	// do, and are not rate limited.
+	crd := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "gadgets.example.com", ResourceVersion: "1"}}
+	handler.OnUpdate(crd, crd)
+	if _, err := m.RESTMapping(gadgetKind, "v2"); !meta.IsNoMatchError(err) {
+		t.Fatalf("expected gadgets not to be mapped, got %v", err)
+	}
+	expectDiscoveries(2)
+
+	disco.lists = append(disco.lists, gadgets)
+	handler.OnAdd(crd, false)
+	if _, err := m.RESTMapping(gadgetKind, "v2"); err != nil {
+		t.Errorf("expected gadgets to be mapped after the event, got %v", err)
+	}
+	expectDiscoveries(3)
+
+	updated := crd.DeepCopy()
+	updated.ResourceVersion = "2"
+	handler.OnUpdate(crd, updated)
+	if _, err := m.RESTMapping(gadgetKind, "v2"); err != nil {
+		t.Fatal(err)
+	}
+	expectDiscoveries(4)
+}
+
+func TestRESTMapperRecovers(t *testing.T) {
+	disco := &fakeDiscovery{err: errors.New("connection refused")}
+	m := newRESTMapper(disco)
+	if _, err := m.RESTMapping(widgetKind, "v1"); err == nil {
+		t.Fatal("expected discovery to fail")
+	}
+
+	// The next lookup discovers again.
+	disco.err = nil
+	disco.lists = []*metav1.APIResourceList{widgets}
+	if _, err := m.RESTMapping(widgetKind, "v1"); err != nil {
+		t.Errorf("expected widgets to be mapped, got %v", err)
+	}
+	if _, err := m.Check(); err != nil {
+		t.Errorf("expected the check to pass, got %v", err)
+	}
+}
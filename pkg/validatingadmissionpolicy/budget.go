@@ -0,0 +1,119 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apiserver/pkg/admission"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// CostBudgets are the runtime CEL cost budgets of the evaluation of policies.
+// When a budget is exceeded, the evaluation stops and the failure policy of
+// the policy applies.
+type CostBudgets struct {
+	// Policy is the budget of the evaluation of a policy for one of its
+	// bindings, shared by its validations, messageExpressions and
+	// auditAnnotations. Policies may override it with the
+	// v1alpha1.CostBudgetAnnotation.
+	Policy int64
+	// Request is the budget of the evaluation of all policies for a request.
+	// Zero means unlimited.
+	Request int64
+}
+
+// DefaultCostBudgets are the budgets of the Kubernetes API server, which has
+// no budget per request.
+var DefaultCostBudgets = CostBudgets{Policy: celconfig.RuntimeCELCostBudget}
+
+// policyCostBudget returns the budget of the evaluation of the policy for one
+// of its bindings.
+func (b CostBudgets) policyCostBudget(definition *v1alpha1.ValidatingAdmissionPolicy) (int64, error) {
+	value, ok := definition.Annotations[v1alpha1.CostBudgetAnnotation]
+	if !ok {
+		return b.Policy, nil
+	}
+	budget, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || budget <= 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a positive integer", v1alpha1.CostBudgetAnnotation, value)
+	}
+	if len(definition.Namespace) > 0 && budget > b.Policy {
+		return 0, fmt.Errorf("invalid %s annotation %q: namespaced policies may not raise their cost budget above %d", v1alpha1.CostBudgetAnnotation, value, b.Policy)
+	}
+	return budget, nil
+}
+
+// requestCostBudget tracks the cost budget which remains for the evaluation of
+// the policies of a request.
+type requestCostBudget struct {
+	// remaining is negative when the budget is unlimited.
+	remaining int64
+}
+
+func newRequestCostBudget(budgets CostBudgets) *requestCostBudget {
+	if budgets.Request <= 0 {
+		return &requestCostBudget{remaining: -1}
+	}
+	return &requestCostBudget{remaining: budgets.Request}
+}
+
+// forPolicy returns the budget of the evaluation of a policy whose own budget
+// is policyBudget, and whether it is limited by the budget of the request.
+func (b *requestCostBudget) forPolicy(policyBudget int64) (int64, bool) {
+	if b.remaining < 0 || policyBudget <= b.remaining {
+		return policyBudget, false
+	}
+	return b.remaining, true
+}
+
+// exhausted returns true when no policy can be evaluated anymore.
+func (b *requestCostBudget) exhausted() bool {
+	return b.remaining == 0
+}
+
+// consume deducts the cost of the evaluation of a policy.
+func (b *requestCostBudget) consume(cost int64) {
+	if b.remaining < 0 {
+		return
+	}
+	b.remaining -= cost
+	if b.remaining < 0 {
+		b.remaining = 0
+	}
+}
+
+// isCostBudgetError returns true if the evaluation of expressions stopped
+// because it ran out of cost budget.
+func isCostBudgetError(err error) bool {
+	celErr, ok := err.(*apiservercel.Error)
+	return ok && celErr.Type == apiservercel.ErrorTypeInvalid &&
+		strings.HasPrefix(celErr.Detail, "validation failed due to running out of cost budget")
+}
+
+// warnCostBudgetExceeded logs and warns about the evaluation of a policy which
+// ran out of cost budget, either its own or the one of the request.
+func (c *celAdmissionController) warnCostBudgetExceeded(ctx context.Context, a admission.Attributes, definition *v1alpha1.ValidatingAdmissionPolicy, binding *v1alpha1.ValidatingAdmissionPolicyBinding, budget int64, requestLimited bool) {
+	var message string
+	if requestLimited {
+		message = fmt.Sprintf("ValidatingAdmissionPolicy '%s' with binding '%s' ran out of the CEL cost budget of the request of %d", definition.Name, binding.Name, c.currentCostBudgets().Request)
+	} else {
+		message = fmt.Sprintf("ValidatingAdmissionPolicy '%s' with binding '%s' exceeded its CEL cost budget of %d", definition.Name, binding.Name, budget)
+	}
+	klog.InfoS("CEL cost budget exceeded",
+		"policy", policyKey(definition),
+		"binding", binding.Name,
+		"budget", budget,
+		"requestLimited", requestLimited,
+		"resource", a.GetResource().String(),
+		"namespace", a.GetNamespace(),
+		"name", a.GetName(),
+	)
+	warning.AddWarning(ctx, "", message)
+}
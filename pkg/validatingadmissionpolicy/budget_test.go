@@ -0,0 +1,212 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/cel"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"k8s.io/apiserver/pkg/authentication/user"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/scheme"
+)
+
+func TestPolicyCostBudget(t *testing.T) {
+	budgets := CostBudgets{Policy: 10000}
+
+	for _, testCase := range []struct {
+		name        string
+		namespace   string
+		annotation  *string
+		expected    int64
+		expectedErr string
+	}{
+		{
+			name:     "default",
+			expected: 10000,
+		},
+		{
+			name:       "raised",
+			annotation: stringPtr("20000"),
+			expected:   20000,
+		},
+		{
+			name:       "namespaced-lowered",
+			namespace:  "team-a",
+			annotation: stringPtr(" 5000 "),
+			expected:   5000,
+		},
+		{
+			name:        "namespaced-raised",
+			namespace:   "team-a",
+			annotation:  stringPtr("20000"),
+			expectedErr: "namespaced policies may not raise their cost budget above 10000",
+		},
+		{
+			name:        "not-a-number",
+			annotation:  stringPtr("unlimited"),
+			expectedErr: "must be a positive integer",
+		},
+		{
+			name:        "zero",
+			annotation:  stringPtr("0"),
+			expectedErr: "must be a positive integer",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			definition := &v1alpha1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: testCase.namespace, Name: "policy"}}
+			if testCase.annotation != nil {
+				definition.Annotations = map[string]string{v1alpha1.CostBudgetAnnotation: *testCase.annotation}
+			}
+			budget, err := budgets.policyCostBudget(definition)
+			if len(testCase.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if budget != testCase.expected {
+				t.Errorf("expected a budget of %d, got %d", testCase.expected, budget)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestRequestCostBudget(t *testing.T) {
+	unlimited := newRequestCostBudget(CostBudgets{Policy: 100})
+	unlimited.consume(1000)
+	if budget, limited := unlimited.forPolicy(100); budget != 100 || limited || unlimited.exhausted() {
+		t.Errorf("expected an unlimited budget, got %d, %v", budget, limited)
+	}
+
+	b := newRequestCostBudget(CostBudgets{Policy: 100, Request: 250})
+	for _, step := range []struct {
+		consume         int64
+		expectedBudget  int64
+		expectedLimited bool
+	}{
+		{consume: 0, expectedBudget: 100},
+		{consume: 100, expectedBudget: 100},
+		// The policy only gets what remains of the budget of the request.
+		{consume: 100, expectedBudget: 50, expectedLimited: true},
+		{consume: 30, expectedBudget: 20, expectedLimited: true},
+	} {
+		b.consume(step.consume)
+		if budget, limited := b.forPolicy(100); budget != step.expectedBudget || limited != step.expectedLimited {
+			t.Errorf("after consuming %d, expected %d, %v, got %d, %v", step.consume, step.expectedBudget, step.expectedLimited, budget, limited)
+		}
+	}
+	if b.exhausted() {
+		t.Error("expected the budget not to be exhausted")
+	}
+	// Consuming more than remains exhausts the budget.
+	b.consume(1000)
+	if !b.exhausted() {
+		t.Error("expected the budget to be exhausted")
+	}
+}
+
+func TestIsCostBudgetError(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "cost-budget",
+			err:      &apiservercel.Error{Type: apiservercel.ErrorTypeInvalid, Detail: "validation failed due to running out of cost budget, no further validation rules will be run"},
+			expected: true,
+		},
+		{
+			name: "other-invalid",
+			err:  &apiservercel.Error{Type: apiservercel.ErrorTypeInvalid, Detail: "compilation error: undeclared reference"},
+		},
+		{
+			name: "internal",
+			err:  &apiservercel.Error{Type: apiservercel.ErrorTypeInternal, Detail: "validation failed due to running out of cost budget"},
+		},
+		{
+			name: "not-cel",
+			err:  errors.New("validation failed due to running out of cost budget"),
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := isCostBudgetError(testCase.err); actual != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+// TestCostBudgetTestcase evaluates the policy of testcases/cost_budget.yaml
+// with the budget of its annotation.
+func TestCostBudgetTestcase(t *testing.T) {
+	content, err := os.ReadFile("../../testcases/cost_budget.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policy *v1alpha1.ValidatingAdmissionPolicy
+	for _, document := range strings.Split(string(content), "\n---\n") {
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(document), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, ok := obj.(*v1alpha1.ValidatingAdmissionPolicy); ok {
+			policy = p
+		}
+	}
+	if policy == nil {
+		t.Fatal("expected the testcase to hold a ValidatingAdmissionPolicy")
+	}
+	budget, err := CostBudgets{Policy: celconfig.RuntimeCELCostBudget}.policyCostBudget(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evaluate := func(keys int) error {
+		data := map[string]interface{}{}
+		for i := 0; i < keys; i++ {
+			data[fmt.Sprintf("key-%d", i)] = "value"
+		}
+		object := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"namespace": "default", "name": "example"},
+			"data":       data,
+		}}
+		kind := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+		attributes := admission.NewAttributesRecord(object, nil, kind, "default", "example", schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "", admission.Create, nil, false, &user.DefaultInfo{Name: "alice"})
+		versionedAttr := &admission.VersionedAttributes{Attributes: attributes, VersionedObject: object, VersionedKind: kind}
+
+		filter := webhookcel.NewFilterCompiler(webhookcel.CurrentEnvironmentVersion).Compile(
+			[]cel.ExpressionAccessor{&ValidationCondition{Expression: policy.Spec.Validations[0].Expression}},
+			cel.OptionalVariableDeclarations{}, celconfig.PerCallLimit)
+		_, _, err := filter.ForInput(context.Background(), versionedAttr, cel.CreateAdmissionRequest(attributes), cel.OptionalVariableBindings{}, budget)
+		return err
+	}
+
+	if err := evaluate(10); err != nil {
+		t.Errorf("expected a small ConfigMap to be evaluated within the budget, got %v", err)
+	}
+	if err := evaluate(1000); !isCostBudgetError(err) {
+		t.Errorf("expected a large ConfigMap to exceed the budget of %d, got %v", budget, err)
+	}
+}
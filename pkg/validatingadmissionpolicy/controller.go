@@ -0,0 +1,694 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/klog/v2"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/admission"
+	celmetrics "k8s.io/apiserver/pkg/admission/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/validatingadmissionpolicy/matching"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/cel/openapi/resolver"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/tracing"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/controller"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned"
+	"k8s.io/cel-admission-webhook/pkg/generated/informers/externalversions"
+	listers "k8s.io/cel-admission-webhook/pkg/generated/listers/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/validatingadmissionpolicy/internal/generic"
+)
+
+var _ CELPolicyEvaluator = &celAdmissionController{}
+
+// celAdmissionController is the top-level controller for admission control using CEL
+// it is responsible for watching policy definitions, bindings, and config param CRDs
+type celAdmissionController struct {
+	// Controller which manages book-keeping for the cluster's dynamic policy
+	// information.
+	policyController *policyController
+
+	// atomic []policyData
+	// list of every known policy definition, and all informatoin required to
+	// validate its bindings against an object.
+	// A snapshot of the current policy configuration is synced with this field
+	// asynchronously
+	definitions atomic.Value
+
+	// EnforcementOverrides which relax the enforcement of policies.
+	overrideLister  listers.EnforcementOverrideLister
+	overridesSynced cache.InformerSynced
+
+	// PolicyExceptions indexed by the name of the policy they apply to.
+	exceptionIndexer cache.Indexer
+	exceptionsSynced cache.InformerSynced
+
+	// Additional CEL libraries available to the expressions of policies.
+	libraries []webhookcel.Library
+
+	// Runtime CEL cost budgets of the evaluation of policies, a CostBudgets
+	// replaced as a whole when they are reconfigured.
+	costBudgets atomic.Value
+}
+
+// Everything someone might need to validate a single ValidatingPolicyDefinition
+// against all of its registered bindings.
+type policyData struct {
+	definitionInfo
+	paramController generic.Controller[runtime.Object]
+	bindings        []bindingInfo
+
+	// Policies which declare themselves a shadow of this definition. They are
+	// evaluated against this definition's bindings but never enforced.
+	shadows []shadowData
+}
+
+// Everything needed to evaluate a shadow policy next to the policy it is
+// shadowing.
+type shadowData struct {
+	definitionInfo
+	paramController generic.Controller[runtime.Object]
+}
+
+// contains the cel PolicyDecisions along with the ValidatingAdmissionPolicy and ValidatingAdmissionPolicyBinding
+// that determined the decision
+type policyDecisionWithMetadata struct {
+	PolicyDecision
+	Definition *v1alpha1.ValidatingAdmissionPolicy
+	Binding    *v1alpha1.ValidatingAdmissionPolicyBinding
+}
+
+// namespaceName is used as a key in definitionInfo and bindingInfos
+type namespacedName struct {
+	namespace, name string
+}
+
+type definitionInfo struct {
+	// Error about the state of the definition's configuration and the cluster
+	// preventing its enforcement or compilation.
+	// Reset every reconciliation
+	configurationError error
+
+	// Last value seen by this controller to be used in policy enforcement
+	// May not be nil
+	lastReconciledValue *v1alpha1.ValidatingAdmissionPolicy
+
+	// Compiled CEL expressions of a shadow policy. Shadow policies are
+	// evaluated with the bindings of the policy they shadow, so unlike
+	// enforced policies their validator is kept on the definition.
+	// Nil for policies which are not a shadow.
+	shadowValidator Validator
+}
+
+type bindingInfo struct {
+	// Compiled CEL expression turned into an validator
+	validator Validator
+
+	// Last value seen by this controller to be used in policy enforcement
+	// May not be nil
+	lastReconciledValue *v1alpha1.ValidatingAdmissionPolicyBinding
+}
+
+type paramInfo struct {
+	// Controller which is watching this param CRD
+	controller generic.Controller[runtime.Object]
+
+	// Function to call to stop the informer and clean up the controller
+	stop func()
+
+	// Policy Definitions which refer to this param CRD
+	dependentDefinitions sets.Set[namespacedName]
+}
+
+func NewAdmissionController(
+	// Injected Dependencies
+	informerFactory informers.SharedInformerFactory,
+	client kubernetes.Interface,
+	policyInformerFactory externalversions.SharedInformerFactory,
+	policyClient versioned.Interface,
+	restMapper meta.RESTMapper,
+	schemaResolver resolver.SchemaResolver,
+	dynamicClient dynamic.Interface,
+	authz authorizer.Authorizer,
+	libraries []webhookcel.Library,
+	costBudgets CostBudgets,
+	leadership controller.Leadership,
+	recorder record.EventRecorder,
+) CELPolicyEvaluator {
+	if leadership == nil {
+		leadership = controller.AlwaysLeader
+	}
+	var typeChecker *TypeChecker
+	if schemaResolver != nil {
+		typeChecker = &TypeChecker{schemaResolver: schemaResolver, restMapper: restMapper, libraries: libraries}
+	}
+	overrideInformer := policyInformerFactory.Admissionregistration().V1alpha1().EnforcementOverrides()
+	var environmentCompilers []webhookcel.Compiler
+	for version := webhookcel.EnvironmentV1; version <= webhookcel.CurrentEnvironmentVersion; version++ {
+		environmentCompilers = append(environmentCompilers, webhookcel.NewFilterCompiler(version, libraries...))
+	}
+	exceptionInformer := policyInformerFactory.Admissionregistration().V1alpha1().PolicyExceptions().Informer()
+	if err := exceptionInformer.AddIndexers(cache.Indexers{exceptionPolicyNameIndex: exceptionPolicyName}); err != nil {
+		utilruntime.HandleError(err)
+	}
+	c := &celAdmissionController{
+		definitions:      atomic.Value{},
+		libraries:        libraries,
+		overrideLister:   overrideInformer.Lister(),
+		overridesSynced:  overrideInformer.Informer().HasSynced,
+		exceptionIndexer: exceptionInformer.GetIndexer(),
+		exceptionsSynced: exceptionInformer.HasSynced,
+		policyController: newPolicyController(
+			restMapper,
+			client,
+			policyClient,
+			dynamicClient,
+			typeChecker,
+			// Stored policies are compiled in the latest version of the
+			// environment, whatever version they were written against.
+			webhookcel.NewFilterCompilers(webhookcel.CurrentEnvironmentVersion, libraries...),
+			environmentCompilers,
+			NewMatcher(matching.NewMatcher(informerFactory.Core().V1().Namespaces().Lister(), client)),
+			generic.NewInformer[*v1alpha1.ValidatingAdmissionPolicy](
+				policyInformerFactory.Admissionregistration().V1alpha1().ValidatingAdmissionPolicies().Informer()),
+			generic.NewInformer[*v1alpha1.ValidatingAdmissionPolicyBinding](
+				policyInformerFactory.Admissionregistration().V1alpha1().ValidatingAdmissionPolicyBindings().Informer()),
+			generic.NewInformer[*v1alpha1.NamespacedValidatingAdmissionPolicy](
+				policyInformerFactory.Admissionregistration().V1alpha1().NamespacedValidatingAdmissionPolicies().Informer()),
+			generic.NewInformer[*v1alpha1.NamespacedValidatingAdmissionPolicyBinding](
+				policyInformerFactory.Admissionregistration().V1alpha1().NamespacedValidatingAdmissionPolicyBindings().Informer()),
+			authz,
+			leadership,
+			recorder,
+		),
+	}
+	c.costBudgets.Store(costBudgets)
+	return c
+}
+
+// SetCostBudgets replaces the cost budgets of the evaluation of policies, for
+// the requests handled from then on.
+func (c *celAdmissionController) SetCostBudgets(costBudgets CostBudgets) {
+	c.costBudgets.Store(costBudgets)
+}
+
+func (c *celAdmissionController) currentCostBudgets() CostBudgets {
+	return c.costBudgets.Load().(CostBudgets)
+}
+
+func (c *celAdmissionController) Run(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.policyController.Run(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// Wait indefinitely until policies/bindings are listed & handled before
+		// allowing policies to be refreshed
+		if !cache.WaitForNamedCacheSync("cel-admission-controller", ctx.Done(), c.policyController.HasSynced) {
+			return
+		}
+
+		// Loop every 1 second until context is cancelled, refreshing policies
+		wait.Until(c.refreshPolicies, 1*time.Second, ctx.Done())
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if !cache.WaitForNamedCacheSync("cel-expression-profiles", ctx.Done(), c.policyController.HasSynced) {
+			return
+		}
+
+		wait.UntilWithContext(ctx, c.policyController.publishExpressionProfiles, expressionProfilesPeriod)
+	}()
+
+	<-stopCh
+	cancel()
+	wg.Wait()
+}
+
+const maxAuditAnnotationValueLength = 10 * 1024
+
+func (c *celAdmissionController) Validate(
+	ctx context.Context,
+	a admission.Attributes,
+	o admission.ObjectInterfaces,
+) (err error) {
+	if !c.HasSynced() {
+		return admission.NewForbidden(a, fmt.Errorf("not yet ready to handle request"))
+	}
+
+	var deniedDecisions []policyDecisionWithMetadata
+
+	overrides := listEnforcementOverrides(c.overrideLister)
+	var overridden overriddenDecisions
+	defer func() { overridden.publish(a) }()
+	var excepted appliedExceptions
+	defer func() { excepted.publish(a) }()
+	requestBudget := newRequestCostBudget(c.currentCostBudgets())
+
+	addConfigError := func(err error, definition *v1alpha1.ValidatingAdmissionPolicy, binding *v1alpha1.ValidatingAdmissionPolicyBinding) {
+		// we always default the FailurePolicy if it is unset and validate it in API level
+		var policy v1alpha1.FailurePolicyType
+		if definition.Spec.FailurePolicy == nil {
+			policy = v1alpha1.Fail
+		} else {
+			policy = *definition.Spec.FailurePolicy
+		}
+
+		// An overridden policy never denies requests, not even when it is
+		// misconfigured.
+		if override := overrides.forPolicy(policyKey(definition)); policy != v1alpha1.Ignore && override != nil {
+			overridden.add(ctx, override, definition, binding, err.Error())
+			override.warn(ctx, definition, err.Error())
+			return
+		}
+
+		// apply FailurePolicy specified in ValidatingAdmissionPolicy, the default would be Fail
+		switch policy {
+		case v1alpha1.Ignore:
+			// TODO: add metrics for ignored error here
+			return
+		case v1alpha1.Fail:
+			var message string
+			if binding == nil {
+				message = fmt.Errorf("failed to configure policy: %w", err).Error()
+			} else {
+				message = fmt.Errorf("failed to configure binding: %w", err).Error()
+			}
+			deniedDecisions = append(deniedDecisions, policyDecisionWithMetadata{
+				PolicyDecision: PolicyDecision{
+					Action:  ActionDeny,
+					Message: message,
+				},
+				Definition: definition,
+				Binding:    binding,
+			})
+		default:
+			deniedDecisions = append(deniedDecisions, policyDecisionWithMetadata{
+				PolicyDecision: PolicyDecision{
+					Action:  ActionDeny,
+					Message: fmt.Errorf("unrecognized failure policy: '%v'", policy).Error(),
+				},
+				Definition: definition,
+				Binding:    binding,
+			})
+		}
+	}
+	policyDatas := c.definitions.Load().([]policyData)
+
+	// The evaluation of the matching policies is nested in the span of the
+	// matching, which only accounts for the remainder.
+	ctx, span := tracing.Start(ctx, "Match policies", attribute.Int("policies", len(policyDatas)))
+	defer span.End(500 * time.Millisecond)
+
+	for _, definitionInfo := range policyDatas {
+		definition := definitionInfo.lastReconciledValue
+		if !inPolicyNamespace(a, definition) {
+			continue
+		}
+		matches, matchKind, err := c.policyController.matcher.DefinitionMatches(a, o, definition)
+		if err != nil {
+			// Configuration error.
+			addConfigError(err, definition, nil)
+			continue
+		}
+		if !matches {
+			// Policy definition does not match request
+			continue
+		}
+
+		override := overrides.forPolicy(policyKey(definition))
+		if override != nil && override.disabled {
+			overridden.add(ctx, override, definition, nil, "")
+			continue
+		} else if definitionInfo.configurationError != nil {
+			// Configuration error.
+			addConfigError(definitionInfo.configurationError, definition, nil)
+			continue
+		}
+		policyBudget, err := c.currentCostBudgets().policyCostBudget(definition)
+		if err != nil {
+			// Configuration error.
+			addConfigError(err, definition, nil)
+			continue
+		}
+
+		auditAnnotationCollector := newAuditAnnotationCollector()
+		for _, bindingInfo := range definitionInfo.bindings {
+			// If the key is inside dependentBindings, there is guaranteed to
+			// be a bindingInfo for it
+			binding := bindingInfo.lastReconciledValue
+			matches, err := c.policyController.matcher.BindingMatches(a, o, binding)
+			if err != nil {
+				// Configuration error.
+				addConfigError(err, definition, binding)
+				continue
+			}
+			if !matches {
+				continue
+			}
+
+			if exception := c.exceptionFor(a, definition, binding); exception != nil {
+				excepted.add(exception, definition, binding)
+				continue
+			}
+
+			var param runtime.Object
+
+			// versionedAttributes will be set to non-nil inside of the loop, but
+			// is scoped outside of the param loop so we only convert once. We defer
+			// conversion so that it is only performed when we know a policy matches,
+			// saving the cost of converting non-matching requests.
+			var versionedAttr *admission.VersionedAttributes
+
+			// If definition has paramKind, paramRef is required in binding.
+			// If definition has no paramKind, paramRef set in binding will be ignored.
+			paramKind := definition.Spec.ParamKind
+			paramRef := binding.Spec.ParamRef
+			if paramKind != nil && paramRef != nil {
+				if err := checkParamNamespace(binding); err != nil {
+					addConfigError(err, definition, binding)
+					continue
+				}
+				param, err = c.getParam(definitionInfo.paramController, paramKind, paramRef)
+				if err != nil {
+					// Apply failure policy
+					addConfigError(err, definition, binding)
+
+					if errors.Is(err, errParamNotReady) || k8serrors.IsInvalid(err) {
+						// Param mis-configured
+						// require to set paramRef.namespace for namespaced resource and unset paramRef.namespace for cluster scoped resource
+						continue
+					} else if k8serrors.IsNotFound(err) {
+						// Param not yet available. User may need to wait a bit
+						// before being able to use it for validation.
+						continue
+					}
+
+					// There was a bad internal error
+					utilruntime.HandleError(err)
+					continue
+				}
+			}
+
+			if versionedAttr == nil {
+				va, err := admission.NewVersionedAttributes(a, matchKind, o)
+				if err != nil {
+					wrappedErr := fmt.Errorf("failed to convert object version: %w", err)
+					addConfigError(wrappedErr, definition, binding)
+					continue
+				}
+				versionedAttr = va
+			}
+
+			budget, requestLimited := requestBudget.forPolicy(policyBudget)
+			if requestBudget.exhausted() {
+				// Apply failure policy
+				c.warnCostBudgetExceeded(ctx, a, definition, binding, budget, requestLimited)
+				addConfigError(fmt.Errorf("the CEL cost budget of the request of %d was exhausted by other policies", c.currentCostBudgets().Request), definition, binding)
+				continue
+			}
+			validateCtx, explainer := explainerFor(ctx, a, binding)
+			validateCtx, evaluationSpan := tracing.Start(validateCtx, "Evaluate policy",
+				attribute.String("policy", policyKey(definition)),
+				attribute.String("binding", bindingKey(binding)))
+			validationResult := bindingInfo.validator.Validate(validateCtx, versionedAttr, param, budget)
+			evaluationSpan.AddEvent("Evaluated",
+				attribute.Int("decisions", len(validationResult.Decisions)),
+				attribute.Int64("cost", validationResult.Cost))
+			evaluationSpan.End(500 * time.Millisecond)
+			requestBudget.consume(validationResult.Cost)
+			if explainer != nil {
+				// Explaining evaluates the failed validations again.
+				requestBudget.consume(explainer.explanation.Cost())
+				explainer.explain(ctx, a, definition, binding, validationResult)
+			}
+			if validationResult.BudgetExceeded {
+				c.warnCostBudgetExceeded(ctx, a, definition, binding, budget, requestLimited)
+			}
+
+			for _, shadow := range definitionInfo.shadows {
+				c.evaluateShadow(ctx, a, o, definition, binding, param, validationResult, shadow)
+			}
+
+			validationActions, _ := binding.Spec.EffectiveValidationActions(time.Now())
+			if override != nil {
+				validationActions = override.validationActions
+			}
+			for i, decision := range validationResult.Decisions {
+				switch decision.Action {
+				case ActionAdmit:
+					if decision.Evaluation == EvalError {
+						celmetrics.Metrics.ObserveAdmissionWithError(ctx, decision.Elapsed, definition.Name, binding.Name, "active")
+					}
+				case ActionDeny:
+					if override != nil {
+						overridden.add(ctx, override, definition, binding, decision.Message)
+					}
+					for _, action := range validationActions {
+						switch action {
+						case v1alpha1.Deny:
+							deniedDecisions = append(deniedDecisions, policyDecisionWithMetadata{
+								Definition:     definition,
+								Binding:        binding,
+								PolicyDecision: decision,
+							})
+							celmetrics.Metrics.ObserveRejection(ctx, decision.Elapsed, definition.Name, binding.Name, "active")
+						case v1alpha1.Audit:
+							c.publishValidationFailureAnnotation(binding, validationActions, i, decision, versionedAttr)
+							celmetrics.Metrics.ObserveAudit(ctx, decision.Elapsed, definition.Name, binding.Name, "active")
+						case v1alpha1.Warn:
+							warning.AddWarning(ctx, "", fmt.Sprintf("Validation failed for ValidatingAdmissionPolicy '%s' with binding '%s': %s", definition.Name, binding.Name, decision.Message))
+							celmetrics.Metrics.ObserveWarn(ctx, decision.Elapsed, definition.Name, binding.Name, "active")
+						}
+					}
+				default:
+					return fmt.Errorf("unrecognized evaluation decision '%s' for ValidatingAdmissionPolicyBinding '%s' with ValidatingAdmissionPolicy '%s'",
+						decision.Action, binding.Name, definition.Name)
+				}
+			}
+
+			for _, auditAnnotation := range validationResult.AuditAnnotations {
+				switch auditAnnotation.Action {
+				case AuditAnnotationActionPublish:
+					value := auditAnnotation.Value
+					if len(auditAnnotation.Value) > maxAuditAnnotationValueLength {
+						value = value[:maxAuditAnnotationValueLength]
+					}
+					auditAnnotationCollector.add(auditAnnotation.Key, value)
+				case AuditAnnotationActionError:
+					if override != nil {
+						overridden.add(ctx, override, definition, binding, auditAnnotation.Error)
+						override.warn(ctx, definition, auditAnnotation.Error)
+						continue
+					}
+					// When failurePolicy=fail, audit annotation errors result in deny
+					deniedDecisions = append(deniedDecisions, policyDecisionWithMetadata{
+						Definition: definition,
+						Binding:    binding,
+						PolicyDecision: PolicyDecision{
+							Action:     ActionDeny,
+							Evaluation: EvalError,
+							Message:    auditAnnotation.Error,
+							Elapsed:    auditAnnotation.Elapsed,
+						},
+					})
+					celmetrics.Metrics.ObserveRejection(ctx, auditAnnotation.Elapsed, definition.Name, binding.Name, "active")
+				case AuditAnnotationActionExclude: // skip it
+				default:
+					return fmt.Errorf("unsupported AuditAnnotation Action: %s", auditAnnotation.Action)
+				}
+			}
+		}
+		auditAnnotationCollector.publish(definition.Name, a)
+	}
+
+	if len(deniedDecisions) > 0 {
+		// TODO: refactor admission.NewForbidden so the name extraction is reusable but the code/reason is customizable
+		var message string
+		deniedDecision := deniedDecisions[0]
+		if deniedDecision.Binding != nil {
+			message = fmt.Sprintf("ValidatingAdmissionPolicy '%s' with binding '%s' denied request: %s", deniedDecision.Definition.Name, deniedDecision.Binding.Name, deniedDecision.Message)
+		} else {
+			message = fmt.Sprintf("ValidatingAdmissionPolicy '%s' denied request: %s", deniedDecision.Definition.Name, deniedDecision.Message)
+		}
+		err := admission.NewForbidden(a, errors.New(message)).(*k8serrors.StatusError)
+		reason := deniedDecision.Reason
+		if len(reason) == 0 {
+			reason = metav1.StatusReasonInvalid
+		}
+		err.ErrStatus.Reason = reason
+		err.ErrStatus.Code = reasonToCode(reason)
+		err.ErrStatus.Details.Causes = append(err.ErrStatus.Details.Causes, metav1.StatusCause{Message: message})
+		return err
+	}
+	return nil
+}
+
+// errParamNotReady is returned by getParam when the param informer of a policy
+// is missing or has not yet synced.
+var errParamNotReady = errors.New("param not ready")
+
+// getParam fetches the param object referred to by a binding from the param
+// informer of its policy.
+func (c *celAdmissionController) getParam(paramController generic.Controller[runtime.Object], paramKind *v1alpha1.ParamKind, paramRef *v1alpha1.ParamRef) (runtime.Object, error) {
+	if paramController == nil {
+		return nil, fmt.Errorf("%w: paramKind kind `%v` not known", errParamNotReady, paramKind.String())
+	}
+
+	// If the param informer for this admission policy has not yet
+	// had time to perform an initial listing, don't attempt to use
+	// it.
+	timeoutCtx, cancel := context.WithTimeout(c.policyController.context, 1*time.Second)
+	defer cancel()
+
+	if !cache.WaitForCacheSync(timeoutCtx.Done(), paramController.HasSynced) {
+		return nil, fmt.Errorf("%w: paramKind kind `%v` not yet synced to use for admission", errParamNotReady, paramKind.String())
+	}
+
+	if len(paramRef.Namespace) == 0 {
+		return paramController.Informer().Get(paramRef.Name)
+	}
+	return paramController.Informer().Namespaced(paramRef.Namespace).Get(paramRef.Name)
+}
+
+func (c *celAdmissionController) publishValidationFailureAnnotation(binding *v1alpha1.ValidatingAdmissionPolicyBinding, validationActions []v1alpha1.ValidationAction, expressionIndex int, decision PolicyDecision, attributes admission.Attributes) {
+	// Unlike in the API server, the key has no prefix: the API server
+	// prefixes the audit annotations of webhooks with the name of the webhook.
+	key := "validation_failure"
+	// Marshal to a list of failures since, in the future, we may need to support multiple failures
+	valueJson, err := utiljson.Marshal([]validationFailureValue{{
+		ExpressionIndex:   expressionIndex,
+		Message:           decision.Message,
+		ValidationActions: validationActions,
+		Binding:           binding.Name,
+		Policy:            binding.Spec.PolicyName,
+	}})
+	if err != nil {
+		klog.Warningf("Failed to set admission audit annotation %s for ValidatingAdmissionPolicy %s and ValidatingAdmissionPolicyBinding %s: %v", key, binding.Spec.PolicyName, binding.Name, err)
+	}
+	value := string(valueJson)
+	if err := attributes.AddAnnotation(key, value); err != nil {
+		klog.Warningf("Failed to set admission audit annotation %s to %s for ValidatingAdmissionPolicy %s and ValidatingAdmissionPolicyBinding %s: %v", key, value, binding.Spec.PolicyName, binding.Name, err)
+	}
+}
+
+func (c *celAdmissionController) HasSynced() bool {
+	return c.policyController.HasSynced() && c.overridesSynced() && c.exceptionsSynced() && c.librariesSynced() && c.definitions.Load() != nil
+}
+
+// librariesSynced returns true once the libraries which observe the cluster
+// have synced, so that expressions do not see a partial view of it.
+func (c *celAdmissionController) librariesSynced() bool {
+	for _, l := range c.libraries {
+		if synced, ok := l.(interface{ HasSynced() bool }); ok && !synced.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *celAdmissionController) ValidateInitialization() error {
+	return c.policyController.matcher.ValidateInitialization()
+}
+
+func (c *celAdmissionController) refreshPolicies() {
+	c.definitions.Store(c.policyController.latestPolicyData())
+}
+
+// validationFailureValue defines the JSON format of a "validation_failure" audit
+// annotation value.
+type validationFailureValue struct {
+	Message           string                      `json:"message"`
+	Policy            string                      `json:"policy"`
+	Binding           string                      `json:"binding"`
+	ExpressionIndex   int                         `json:"expressionIndex"`
+	ValidationActions []v1alpha1.ValidationAction `json:"validationActions"`
+}
+
+type auditAnnotationCollector struct {
+	annotations map[string][]string
+}
+
+func newAuditAnnotationCollector() auditAnnotationCollector {
+	return auditAnnotationCollector{annotations: map[string][]string{}}
+}
+
+func (a auditAnnotationCollector) add(key, value string) {
+	// If multiple bindings produces the exact same key and value for an audit annotation,
+	// ignore the duplicates.
+	for _, v := range a.annotations[key] {
+		if v == value {
+			return
+		}
+	}
+	a.annotations[key] = append(a.annotations[key], value)
+}
+
+func (a auditAnnotationCollector) publish(policyName string, attributes admission.Attributes) {
+	for key, bindingAnnotations := range a.annotations {
+		var value string
+		if len(bindingAnnotations) == 1 {
+			value = bindingAnnotations[0]
+		} else {
+			// Multiple distinct values can exist when binding params are used in the valueExpression of an auditAnnotation.
+			// When this happens, the values are concatenated into a comma-separated list.
+			value = strings.Join(bindingAnnotations, ", ")
+		}
+		// The API server prefixes the audit annotations of webhooks with the
+		// name of the webhook and "/", so the policy name is joined with "_",
+		// which policy names cannot contain, instead.
+		if err := attributes.AddAnnotation(policyName+"_"+key, value); err != nil {
+			klog.Warningf("Failed to set admission audit annotation %s to %s for ValidatingAdmissionPolicy %s: %v", key, value, policyName, err)
+		}
+	}
+}
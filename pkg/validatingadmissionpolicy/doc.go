@@ -0,0 +1,13 @@
+// Package validatingadmissionpolicy is a copy of the ValidatingAdmissionPolicy
+// evaluator from k8s.io/apiserver/pkg/admission/plugin/validatingadmissionpolicy.
+//
+// COPIED FROM K8S SOURCE
+// Modified to evaluate shadow policies next to the policies they shadow, to
+// honor EnforcementOverrides and PolicyExceptions, to compile expressions with
+// the additional CEL libraries of k8s.io/cel-admission-webhook/pkg/cel, and to
+// read policies and bindings as the admissionregistration.x-k8s.io CRDs so
+// that the extensions of their schema, such as validationActionsSchedule, are
+// honored.
+// Keep changes to the copied files small so that the package can be rebased
+// onto newer versions of k8s.io/apiserver.
+package validatingadmissionpolicy
@@ -0,0 +1,117 @@
+package validatingadmissionpolicy
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/matchconditions"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+// policyExpression is an expression of a policy, with the variables it may
+// use.
+type policyExpression struct {
+	path     *field.Path
+	accessor cel.ExpressionAccessor
+	options  cel.OptionalVariableDeclarations
+}
+
+// expressionPath returns the path to an expression of the i-th element of a
+// list in the spec of a policy, such as spec.validations[0].expression.
+func expressionPath(list string, i int, child string) *field.Path {
+	return field.NewPath("spec", list).Index(i).Child(child)
+}
+
+func policyExpressions(spec *v1alpha1.ValidatingAdmissionPolicySpec) []policyExpression {
+	hasParams := spec.ParamKind != nil
+	options := cel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: true}
+	messageOptions := cel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: false}
+
+	var expressions []policyExpression
+	for i := range spec.MatchConditions {
+		expressions = append(expressions, policyExpression{
+			path:     expressionPath("matchConditions", i, "expression"),
+			accessor: (*matchconditions.MatchCondition)(&spec.MatchConditions[i]),
+			options:  options,
+		})
+	}
+	for i, validation := range spec.Validations {
+		expressions = append(expressions, policyExpression{
+			path:     expressionPath("validations", i, "expression"),
+			accessor: &ValidationCondition{Expression: validation.Expression},
+			options:  options,
+		})
+		if len(validation.MessageExpression) > 0 {
+			expressions = append(expressions, policyExpression{
+				path:     expressionPath("validations", i, "messageExpression"),
+				accessor: &MessageExpressionCondition{MessageExpression: validation.MessageExpression},
+				options:  messageOptions,
+			})
+		}
+	}
+	for i, auditAnnotation := range spec.AuditAnnotations {
+		expressions = append(expressions, policyExpression{
+			path:     expressionPath("auditAnnotations", i, "valueExpression"),
+			accessor: &AuditAnnotationCondition{Key: auditAnnotation.Key, ValueExpression: auditAnnotation.ValueExpression},
+			options:  options,
+		})
+	}
+	return expressions
+}
+
+// ValidateNewExpressions compiles the expressions of a policy which are not
+// part of its previous spec, if any, and returns an error for every one of
+// them which fails to compile.
+//
+// New expressions are compiled in the compatibility version of the CEL
+// environment, which every replica of the shim supports, while expressions
+// which are already stored keep being compiled in the latest version.
+func ValidateNewExpressions(compiler webhookcel.Compiler, spec, oldSpec *v1alpha1.ValidatingAdmissionPolicySpec) field.ErrorList {
+	stored := sets.New[string]()
+	if oldSpec != nil {
+		for _, e := range policyExpressions(oldSpec) {
+			stored.Insert(e.accessor.GetExpression())
+		}
+	}
+
+	var errs field.ErrorList
+	for _, e := range policyExpressions(spec) {
+		if stored.Has(e.accessor.GetExpression()) {
+			continue
+		}
+		result := compiler.CompileExpression(e.accessor, e.options, celconfig.PerCallLimit)
+		if result.Error != nil {
+			errs = append(errs, field.Invalid(e.path, e.accessor.GetExpression(),
+				fmt.Sprintf("%s (CEL environment version %d)", result.Error.Detail, compiler.Version())))
+		}
+	}
+	return errs
+}
+
+// environmentStatus returns the versions of the CEL environment the
+// expressions of the policy compile in.
+func (c *policyController) environmentStatus(definition *v1alpha1.ValidatingAdmissionPolicy) *v1alpha1.CELEnvironment {
+	status := &v1alpha1.CELEnvironment{Version: int32(webhookcel.CurrentEnvironmentVersion)}
+	expressions := policyExpressions(&definition.Spec)
+	for _, compiler := range c.environmentCompilers {
+		if compilesIn(compiler, expressions) {
+			status.MinimumVersion = int32(compiler.Version())
+			break
+		}
+	}
+	return status
+}
+
+func compilesIn(compiler webhookcel.Compiler, expressions []policyExpression) bool {
+	for _, e := range expressions {
+		if result := compiler.CompileExpression(e.accessor, e.options, celconfig.PerCallLimit); result.Error != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package validatingadmissionpolicy
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/cel/library"
+)
+
+const (
+	// v1Expression compiles in every version of the environment.
+	v1Expression = `object.metadata.name.startsWith("app-")`
+	// v2Expression only compiles from EnvironmentV2, which adds semverCompare.
+	v2Expression = `semverCompare(object.metadata.labels["version"], "1.0.0") >= 0`
+)
+
+func specOf(expressions ...string) *v1alpha1.ValidatingAdmissionPolicySpec {
+	spec := &v1alpha1.ValidatingAdmissionPolicySpec{}
+	for _, expression := range expressions {
+		spec.Validations = append(spec.Validations, v1alpha1.Validation{Expression: expression})
+	}
+	return spec
+}
+
+func TestValidateNewExpressions(t *testing.T) {
+	libraries := []webhookcel.Library{library.Semver()}
+
+	for _, testCase := range []struct {
+		name     string
+		version  webhookcel.EnvironmentVersion
+		spec     *v1alpha1.ValidatingAdmissionPolicySpec
+		oldSpec  *v1alpha1.ValidatingAdmissionPolicySpec
+		expected []string
+	}{
+		{
+			name:    "v1",
+			version: webhookcel.EnvironmentV1,
+			spec:    specOf(v1Expression),
+		},
+		{
+			name:     "v2-expression-in-v1",
+			version:  webhookcel.EnvironmentV1,
+			spec:     specOf(v1Expression, v2Expression),
+			expected: []string{"spec.validations[1].expression"},
+		},
+		{
+			name:    "v2-expression-in-v2",
+			version: webhookcel.EnvironmentV2,
+			spec:    specOf(v1Expression, v2Expression),
+		},
+		{
+			// Stored expressions are not validated again, so that policies
+			// written against a later version can still be updated.
+			name:    "stored-v2-expression-in-v1",
+			version: webhookcel.EnvironmentV1,
+			spec:    specOf(v2Expression, v1Expression),
+			oldSpec: specOf(v2Expression),
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			compiler := webhookcel.NewFilterCompiler(testCase.version, libraries...)
+			errs := ValidateNewExpressions(compiler, testCase.spec, testCase.oldSpec)
+			var paths []string
+			for _, err := range errs {
+				paths = append(paths, err.Field)
+			}
+			if !reflect.DeepEqual(paths, testCase.expected) {
+				t.Errorf("expected errors for %v, got %v", testCase.expected, errs)
+			}
+		})
+	}
+}
+
+func TestEnvironmentStatus(t *testing.T) {
+	libraries := []webhookcel.Library{library.Semver()}
+	c := &policyController{}
+	for version := webhookcel.EnvironmentV1; version <= webhookcel.CurrentEnvironmentVersion; version++ {
+		c.environmentCompilers = append(c.environmentCompilers, webhookcel.NewFilterCompiler(version, libraries...))
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		spec     *v1alpha1.ValidatingAdmissionPolicySpec
+		expected v1alpha1.CELEnvironment
+	}{
+		{
+			name:     "v1",
+			spec:     specOf(v1Expression),
+			expected: v1alpha1.CELEnvironment{Version: int32(webhookcel.CurrentEnvironmentVersion), MinimumVersion: int32(webhookcel.EnvironmentV1)},
+		},
+		{
+			name:     "v2",
+			spec:     specOf(v1Expression, v2Expression),
+			expected: v1alpha1.CELEnvironment{Version: int32(webhookcel.CurrentEnvironmentVersion), MinimumVersion: int32(webhookcel.EnvironmentV2)},
+		},
+		{
+			// Policies which compile in no version have no minimum version.
+			name:     "invalid",
+			spec:     specOf(`object.metadata.name.unknownFunction()`),
+			expected: v1alpha1.CELEnvironment{Version: int32(webhookcel.CurrentEnvironmentVersion)},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			status := c.environmentStatus(&v1alpha1.ValidatingAdmissionPolicy{Spec: *testCase.spec})
+			if !reflect.DeepEqual(*status, testCase.expected) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, *status)
+			}
+		})
+	}
+}
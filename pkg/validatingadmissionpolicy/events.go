@@ -0,0 +1,57 @@
+package validatingadmissionpolicy
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+const (
+	// ReasonCompileFailed is the reason of the Event recorded on a policy
+	// whose expressions fail to compile.
+	ReasonCompileFailed = "CompileFailed"
+
+	// ReasonTypeCheckFailed is the reason of the Event recorded on a policy
+	// whose expressions have type checking warnings.
+	ReasonTypeCheckFailed = "TypeCheckFailed"
+)
+
+// recordPolicyEvents records an Event on the policy for the expressions of its
+// current generation which fail to compile, and another one for those with
+// type checking warnings. It is called once per generation, along with the
+// update of the status.
+func (c *policyController) recordPolicyEvents(definition *v1alpha1.ValidatingAdmissionPolicy, status *v1alpha1.ValidatingAdmissionPolicyStatus) {
+	if c.recorder == nil {
+		return
+	}
+	var object runtime.Object = definition
+	if len(definition.Namespace) > 0 {
+		object = policyToNamespacedPolicy(definition)
+	}
+
+	compiler := c.filterCompilers.ForNamespace(definition.Namespace)
+	var compileErrors []string
+	for _, e := range policyExpressions(&definition.Spec) {
+		if result := compiler.CompileExpression(e.accessor, e.options, celconfig.PerCallLimit); result.Error != nil {
+			compileErrors = append(compileErrors, fmt.Sprintf("%s: %s", e.path, result.Error.Detail))
+		}
+	}
+	if len(compileErrors) > 0 {
+		c.recorder.Eventf(object, corev1.EventTypeWarning, ReasonCompileFailed,
+			"Generation %d fails to compile: %s", definition.Generation, strings.Join(compileErrors, "; "))
+	}
+
+	if status.TypeChecking != nil && len(status.TypeChecking.ExpressionWarnings) > 0 {
+		warnings := make([]string, len(status.TypeChecking.ExpressionWarnings))
+		for i, w := range status.TypeChecking.ExpressionWarnings {
+			warnings[i] = fmt.Sprintf("%s: %s", w.FieldRef, w.Warning)
+		}
+		c.recorder.Eventf(object, corev1.EventTypeWarning, ReasonTypeCheckFailed,
+			"Generation %d has type checking warnings: %s", definition.Generation, strings.Join(warnings, "; "))
+	}
+}
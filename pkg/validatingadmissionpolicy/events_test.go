@@ -0,0 +1,84 @@
+package validatingadmissionpolicy
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+func TestRecordPolicyEvents(t *testing.T) {
+	typeChecking := &v1alpha1.TypeChecking{ExpressionWarnings: []v1alpha1.ExpressionWarning{
+		{FieldRef: "spec.validations[0].expression", Warning: "no such key: nme"},
+	}}
+
+	for _, testCase := range []struct {
+		name      string
+		namespace string
+		spec      *v1alpha1.ValidatingAdmissionPolicySpec
+		status    v1alpha1.ValidatingAdmissionPolicyStatus
+		expected  []string
+	}{
+		{
+			name: "valid",
+			spec: specOf(v1Expression),
+		},
+		{
+			name:     "compile-failed",
+			spec:     specOf(v1Expression, `object.metadata.name.unknownFunction()`),
+			expected: []string{"Warning CompileFailed Generation 3 fails to compile: spec.validations[1].expression: "},
+		},
+		{
+			name:      "namespaced-compile-failed",
+			namespace: "team-a",
+			spec:      specOf(`object.metadata.name.unknownFunction()`),
+			expected:  []string{"Warning CompileFailed Generation 3 fails to compile: spec.validations[0].expression: "},
+		},
+		{
+			name:     "type-check-failed",
+			spec:     specOf(v1Expression),
+			status:   v1alpha1.ValidatingAdmissionPolicyStatus{TypeChecking: typeChecking},
+			expected: []string{"Warning TypeCheckFailed Generation 3 has type checking warnings: spec.validations[0].expression: no such key: nme"},
+		},
+		{
+			name:   "both",
+			spec:   specOf(`object.metadata.name.unknownFunction()`),
+			status: v1alpha1.ValidatingAdmissionPolicyStatus{TypeChecking: typeChecking},
+			expected: []string{
+				"Warning CompileFailed Generation 3 fails to compile: spec.validations[0].expression: ",
+				"Warning TypeCheckFailed Generation 3 has type checking warnings: ",
+			},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			c := &policyController{
+				filterCompilers: webhookcel.NewFilterCompilers(webhookcel.CurrentEnvironmentVersion),
+				recorder:        recorder,
+			}
+			definition := &v1alpha1.ValidatingAdmissionPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testCase.namespace, Name: "policy", Generation: 3},
+				Spec:       *testCase.spec,
+			}
+
+			c.recordPolicyEvents(definition, &testCase.status)
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if len(events) != len(testCase.expected) {
+				t.Fatalf("expected %d events, got %v", len(testCase.expected), events)
+			}
+			for i, event := range events {
+				if !strings.HasPrefix(event, testCase.expected[i]) {
+					t.Errorf("expected an event starting with %q, got %q", testCase.expected[i], event)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package validatingadmissionpolicy
+
+import (
+	"fmt"
+	"time"
+
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/predicates/rules"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// policyExceptionAnnotation is the audit annotation listing the
+// PolicyExceptions which exempted a request from a policy. The API server
+// prefixes it with the name of the webhook.
+const policyExceptionAnnotation = "policy_exception"
+
+// exceptionPolicyNameIndex indexes PolicyExceptions by the name of the policy
+// they apply to.
+const exceptionPolicyNameIndex = "policyName"
+
+func exceptionPolicyName(obj interface{}) ([]string, error) {
+	exception, ok := obj.(*v1alpha1.PolicyException)
+	if !ok {
+		return nil, fmt.Errorf("expected PolicyException but got %T", obj)
+	}
+	// A PolicyException refers to a cluster scoped policy, or to a namespaced
+	// policy in its own namespace, never to both.
+	if exception.Spec.PolicyKind == v1alpha1.NamespacedValidatingAdmissionPolicyKind {
+		return []string{exception.Namespace + "/" + exception.Spec.PolicyName}, nil
+	}
+	return []string{exception.Spec.PolicyName}, nil
+}
+
+// exceptionFor returns the first PolicyException which exempts the request
+// from the binding of the policy, or nil if there is none.
+func (c *celAdmissionController) exceptionFor(a admission.Attributes, definition *v1alpha1.ValidatingAdmissionPolicy, binding *v1alpha1.ValidatingAdmissionPolicyBinding) *v1alpha1.PolicyException {
+	objs, err := c.exceptionIndexer.ByIndex(exceptionPolicyNameIndex, policyKey(definition))
+	if err != nil {
+		klog.Errorf("failed to list PolicyExceptions of ValidatingAdmissionPolicy %s: %v", definition.Name, err)
+		return nil
+	}
+
+	now := time.Now()
+	for _, obj := range objs {
+		exception := obj.(*v1alpha1.PolicyException)
+		if exceptionMatches(exception, a, binding, now) {
+			return exception
+		}
+	}
+	return nil
+}
+
+func exceptionMatches(exception *v1alpha1.PolicyException, a admission.Attributes, binding *v1alpha1.ValidatingAdmissionPolicyBinding, now time.Time) bool {
+	spec := &exception.Spec
+	if len(spec.BindingName) > 0 && spec.BindingName != binding.Name {
+		return false
+	}
+	if spec.ExpiresAt != nil && !now.Before(spec.ExpiresAt.Time) {
+		return false
+	}
+
+	// Exceptions are confined to their namespace, so requests for cluster
+	// scoped resources are never exempt. Requests for Namespace objects carry
+	// the name of the Namespace as their namespace, but Namespaces are
+	// cluster scoped.
+	if resource := a.GetResource(); resource.Group == "" && resource.Resource == "namespaces" {
+		return false
+	}
+	if a.GetNamespace() != exception.Namespace {
+		return false
+	}
+
+	if len(spec.Users) > 0 {
+		if a.GetUserInfo() == nil || !sets.New(spec.Users...).Has(a.GetUserInfo().GetName()) {
+			return false
+		}
+	}
+
+	if len(spec.ResourceRules) == 0 {
+		return true
+	}
+	for _, rule := range spec.ResourceRules {
+		if len(rule.ResourceNames) > 0 && !sets.New(rule.ResourceNames...).Has(a.GetName()) {
+			continue
+		}
+		matcher := rules.Matcher{Rule: rule.RuleWithOperations, Attr: a}
+		if matcher.Matches() {
+			return true
+		}
+	}
+	return false
+}
+
+// appliedExceptionValue defines the JSON format of an entry of the policy
+// exception audit annotation.
+type appliedExceptionValue struct {
+	Policy    string `json:"policy"`
+	Binding   string `json:"binding"`
+	Exception string `json:"exception"`
+}
+
+// appliedExceptions collects the PolicyExceptions which exempted a request.
+type appliedExceptions []appliedExceptionValue
+
+func (e *appliedExceptions) add(exception *v1alpha1.PolicyException, definition *v1alpha1.ValidatingAdmissionPolicy, binding *v1alpha1.ValidatingAdmissionPolicyBinding) {
+	*e = append(*e, appliedExceptionValue{
+		Policy:    policyKey(definition),
+		Binding:   binding.Name,
+		Exception: exception.Namespace + "/" + exception.Name,
+	})
+}
+
+func (e appliedExceptions) publish(attributes admission.Attributes) {
+	if len(e) == 0 {
+		return
+	}
+	valueJson, err := utiljson.Marshal(e)
+	if err != nil {
+		klog.Warningf("Failed to set admission audit annotation %s: %v", policyExceptionAnnotation, err)
+		return
+	}
+	value := string(valueJson)
+	if err := attributes.AddAnnotation(policyExceptionAnnotation, value); err != nil {
+		klog.Warningf("Failed to set admission audit annotation %s to %s: %v", policyExceptionAnnotation, value, err)
+	}
+}
@@ -0,0 +1,151 @@
+package validatingadmissionpolicy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+func TestExceptionMatches(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	past := metav1.NewTime(now.Add(-time.Hour))
+	future := metav1.NewTime(now.Add(time.Hour))
+
+	binding := &v1alpha1.ValidatingAdmissionPolicyBinding{ObjectMeta: metav1.ObjectMeta{Name: "binding"}}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	attributes := func(resource schema.GroupVersionResource, namespace, name string) admission.Attributes {
+		return admission.NewAttributesRecord(nil, nil, schema.GroupVersionKind{}, namespace, name, resource, "", admission.Create, nil, false, &user.DefaultInfo{Name: "alice"})
+	}
+	deploymentRule := v1alpha1.NamedRuleWithOperations{
+		ResourceNames: []string{"legacy-app"},
+		RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"apps"},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"deployments"},
+			},
+		},
+	}
+
+	for _, testCase := range []struct {
+		name       string
+		spec       v1alpha1.PolicyExceptionSpec
+		attributes admission.Attributes
+		expected   bool
+	}{
+		{
+			name:       "own-namespace",
+			attributes: attributes(deployments, "team-a", "example"),
+			expected:   true,
+		},
+		{
+			name:       "other-namespace",
+			attributes: attributes(deployments, "team-b", "example"),
+		},
+		{
+			// The API server sets the namespace of requests for Namespaces
+			// to their name.
+			name:       "namespace-object",
+			attributes: attributes(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, "team-a", "team-a"),
+		},
+		{
+			name:       "cluster-scoped",
+			attributes: attributes(schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, "", "team-a"),
+		},
+		{
+			name:       "not-expired",
+			spec:       v1alpha1.PolicyExceptionSpec{ExpiresAt: &future},
+			attributes: attributes(deployments, "team-a", "example"),
+			expected:   true,
+		},
+		{
+			name:       "expired",
+			spec:       v1alpha1.PolicyExceptionSpec{ExpiresAt: &past},
+			attributes: attributes(deployments, "team-a", "example"),
+		},
+		{
+			name:       "binding",
+			spec:       v1alpha1.PolicyExceptionSpec{BindingName: "binding"},
+			attributes: attributes(deployments, "team-a", "example"),
+			expected:   true,
+		},
+		{
+			name:       "other-binding",
+			spec:       v1alpha1.PolicyExceptionSpec{BindingName: "other"},
+			attributes: attributes(deployments, "team-a", "example"),
+		},
+		{
+			name:       "user",
+			spec:       v1alpha1.PolicyExceptionSpec{Users: []string{"alice"}},
+			attributes: attributes(deployments, "team-a", "example"),
+			expected:   true,
+		},
+		{
+			name:       "other-user",
+			spec:       v1alpha1.PolicyExceptionSpec{Users: []string{"bob"}},
+			attributes: attributes(deployments, "team-a", "example"),
+		},
+		{
+			name:       "resource-rule",
+			spec:       v1alpha1.PolicyExceptionSpec{ResourceRules: []v1alpha1.NamedRuleWithOperations{deploymentRule}},
+			attributes: attributes(deployments, "team-a", "legacy-app"),
+			expected:   true,
+		},
+		{
+			name:       "resource-rule-other-name",
+			spec:       v1alpha1.PolicyExceptionSpec{ResourceRules: []v1alpha1.NamedRuleWithOperations{deploymentRule}},
+			attributes: attributes(deployments, "team-a", "example"),
+		},
+		{
+			name:       "resource-rule-other-resource",
+			spec:       v1alpha1.PolicyExceptionSpec{ResourceRules: []v1alpha1.NamedRuleWithOperations{deploymentRule}},
+			attributes: attributes(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}, "team-a", "legacy-app"),
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			exception := &v1alpha1.PolicyException{
+				ObjectMeta: metav1.ObjectMeta{Name: "exception", Namespace: "team-a"},
+				Spec:       testCase.spec,
+			}
+			exception.Spec.PolicyName = "policy"
+			if matches := exceptionMatches(exception, testCase.attributes, binding, now); matches != testCase.expected {
+				t.Errorf("expected match %v, got %v", testCase.expected, matches)
+			}
+		})
+	}
+}
+
+func TestExceptionPolicyName(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		kind     v1alpha1.PolicyKind
+		expected []string
+	}{
+		{name: "default", expected: []string{"policy"}},
+		{name: "cluster-scoped", kind: v1alpha1.ValidatingAdmissionPolicyKind, expected: []string{"policy"}},
+		{name: "namespaced", kind: v1alpha1.NamespacedValidatingAdmissionPolicyKind, expected: []string{"team-a/policy"}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			exception := &v1alpha1.PolicyException{
+				ObjectMeta: metav1.ObjectMeta{Name: "exception", Namespace: "team-a"},
+				Spec:       v1alpha1.PolicyExceptionSpec{PolicyName: "policy", PolicyKind: testCase.kind},
+			}
+			keys, err := exceptionPolicyName(exception)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(keys, testCase.expected) {
+				t.Errorf("expected index keys %v, got %v", testCase.expected, keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+)
+
+var secretsResource = schema.GroupResource{Resource: "secrets"}
+
+// explainer explains the failed validations of the evaluation of a binding
+// for a request.
+type explainer struct {
+	explanation webhookcel.Explanation
+	// warn is true when the explanations are returned as response warnings
+	// besides being logged.
+	warn bool
+}
+
+// explainerFor returns the explainer of the evaluation of the binding for the
+// request, and the context to evaluate it with, or a nil explainer when
+// neither the binding nor the object of the request enable the explain mode.
+func explainerFor(ctx context.Context, a admission.Attributes, binding *v1alpha1.ValidatingAdmissionPolicyBinding) (context.Context, *explainer) {
+	var e *explainer
+	if binding.Annotations[v1alpha1.ExplainAnnotation] == "true" {
+		e = &explainer{warn: true}
+	} else if explainLabelSet(a.GetObject()) || explainLabelSet(a.GetOldObject()) {
+		e = &explainer{}
+	} else {
+		return ctx, nil
+	}
+	return webhookcel.WithExplanation(ctx, &e.explanation), e
+}
+
+func explainLabelSet(obj runtime.Object) bool {
+	if obj == nil {
+		return false
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return accessor.GetLabels()[v1alpha1.ExplainLabel] == "true"
+}
+
+// explain logs, and warns about if requested, the values of the
+// sub-expressions of the validations which failed.
+func (e *explainer) explain(ctx context.Context, a admission.Attributes, definition *v1alpha1.ValidatingAdmissionPolicy, binding *v1alpha1.ValidatingAdmissionPolicyBinding, result ValidateResult) {
+	// The object of a request for a Secret is never revealed, and neither is
+	// anything computed from it.
+	redactAll := a.GetResource().GroupResource() == secretsResource
+
+	for i, decision := range result.Decisions {
+		if decision.Action != ActionDeny || decision.Evaluation == EvalError || i >= len(definition.Spec.Validations) {
+			continue
+		}
+		expression := definition.Spec.Validations[i].Expression
+		operands := e.explanation.Operands(expression)
+		if len(operands) == 0 {
+			continue
+		}
+		values := make([]string, len(operands))
+		for j, operand := range operands {
+			value := operand.Value
+			if redactAll && value != "true" && value != "false" {
+				value = redaction.Redacted
+			}
+			values[j] = fmt.Sprintf("`%s` = %s", operand.Expression, value)
+		}
+		explanation := redaction.FromContext(ctx).String(strings.Join(values, ", "))
+
+		klog.InfoS("Explained failed validation",
+			"policy", policyKey(definition),
+			"binding", binding.Name,
+			"validation", i,
+			"expression", strings.TrimSpace(expression),
+			"operands", explanation,
+			"resource", a.GetResource().String(),
+			"namespace", a.GetNamespace(),
+			"name", a.GetName(),
+		)
+		if e.warn {
+			warning.AddWarning(ctx, "", fmt.Sprintf("ValidatingAdmissionPolicy '%s' with binding '%s' failed validation %d: %s", definition.Name, binding.Name, i, explanation))
+		}
+	}
+}
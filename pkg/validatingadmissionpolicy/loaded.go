@@ -0,0 +1,33 @@
+package validatingadmissionpolicy
+
+import (
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// LoadedGenerations returns the generations of the policies and bindings
+// which requests are evaluated against, keyed by name, or by namespace/name
+// for namespaced ones. Shadow policies are included, bindings of policies
+// which do not exist are not.
+func (c *celAdmissionController) LoadedGenerations() (policies, bindings map[string]int64) {
+	policies = map[string]int64{}
+	bindings = map[string]int64{}
+	policyDatas, _ := c.definitions.Load().([]policyData)
+	for _, data := range policyDatas {
+		definition := data.lastReconciledValue
+		policies[policyKey(definition)] = definition.Generation
+		for _, shadow := range data.shadows {
+			policies[policyKey(shadow.lastReconciledValue)] = shadow.lastReconciledValue.Generation
+		}
+		for _, binding := range data.bindings {
+			bindings[bindingKey(binding.lastReconciledValue)] = binding.lastReconciledValue.Generation
+		}
+	}
+	return policies, bindings
+}
+
+func bindingKey(binding *v1alpha1.ValidatingAdmissionPolicyBinding) string {
+	if len(binding.Namespace) == 0 {
+		return binding.Name
+	}
+	return binding.Namespace + "/" + binding.Name
+}
@@ -0,0 +1,124 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+const (
+	metricsNamespace = "cel_admission_webhook"
+	metricsSubsystem = "validating_admission_policy"
+)
+
+var (
+	// ShadowMetrics provides access to shadow policy evaluation metrics.
+	ShadowMetrics = newShadowMetrics()
+
+	// policyGenerations are reported by every replica, unlike the status of
+	// policies which only the leader writes.
+	policyGenerations = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "observed_generation",
+			Help:           "Generation of each policy loaded by this replica, labeled by policy.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"policy"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(policyGenerations)
+}
+
+// observePolicyGeneration reports the generation of the policy loaded by this
+// replica, or forgets it once the policy is deleted.
+func observePolicyGeneration(namespace, name string, definition *v1alpha1.ValidatingAdmissionPolicy) {
+	key := name
+	if len(namespace) > 0 {
+		key = namespace + "/" + name
+	}
+	if definition == nil {
+		policyGenerations.DeleteLabelValues(key)
+		return
+	}
+	policyGenerations.WithLabelValues(key).Set(float64(definition.Generation))
+}
+
+// ShadowPolicyMetrics aggregates Prometheus metrics related to the evaluation
+// of shadow policies.
+type ShadowPolicyMetrics struct {
+	evaluations   *metrics.CounterVec
+	errors        *metrics.CounterVec
+	disagreements *metrics.CounterVec
+}
+
+func newShadowMetrics() *ShadowPolicyMetrics {
+	evaluations := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "shadow_evaluations_total",
+			Help:           "Shadow policy evaluation total, labeled by the enforced policy and its shadow.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"policy", "shadow"},
+	)
+	errors := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "shadow_errors_total",
+			Help:           "Shadow policy evaluations which could not be completed, labeled by the enforced policy and its shadow.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"policy", "shadow"},
+	)
+	disagreements := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "shadow_disagreements_total",
+			Help:           "Requests on which a shadow policy reached a different decision than the enforced policy, labeled by policy, shadow, binding and the enforced decision.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"policy", "shadow", "policy_binding", "enforced_decision"},
+	)
+
+	legacyregistry.MustRegister(evaluations)
+	legacyregistry.MustRegister(errors)
+	legacyregistry.MustRegister(disagreements)
+	return &ShadowPolicyMetrics{evaluations: evaluations, errors: errors, disagreements: disagreements}
+}
+
+// Reset resets all shadow policy Prometheus metrics.
+func (m *ShadowPolicyMetrics) Reset() {
+	m.evaluations.Reset()
+	m.errors.Reset()
+	m.disagreements.Reset()
+}
+
+// ObserveEvaluation observes a completed evaluation of a shadow policy.
+func (m *ShadowPolicyMetrics) ObserveEvaluation(ctx context.Context, policy, shadow string) {
+	m.evaluations.WithContext(ctx).WithLabelValues(policy, shadow).Inc()
+}
+
+// ObserveError observes a shadow policy evaluation which failed to complete.
+func (m *ShadowPolicyMetrics) ObserveError(ctx context.Context, policy, shadow string) {
+	m.errors.WithContext(ctx).WithLabelValues(policy, shadow).Inc()
+}
+
+// ObserveDisagreement observes a request on which the shadow and enforced
+// policies reached different decisions.
+func (m *ShadowPolicyMetrics) ObserveDisagreement(ctx context.Context, policy, shadow, binding string, enforcedDenied bool) {
+	decision := "allow"
+	if enforcedDenied {
+		decision = "deny"
+	}
+	m.disagreements.WithContext(ctx).WithLabelValues(policy, shadow, binding, decision).Inc()
+}
@@ -0,0 +1,88 @@
+package validatingadmissionpolicy
+
+import (
+	"fmt"
+
+	"k8s.io/apiserver/pkg/admission"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// NamespacedValidatingAdmissionPolicies and their bindings are evaluated as
+// ValidatingAdmissionPolicies and bindings which carry the namespace of the
+// original object. The namespace keeps them apart from cluster scoped policies
+// of the same name and confines them to requests in that namespace.
+
+func namespacedPolicyToPolicy(policy *v1alpha1.NamespacedValidatingAdmissionPolicy) *v1alpha1.ValidatingAdmissionPolicy {
+	if policy == nil {
+		return nil
+	}
+	return &v1alpha1.ValidatingAdmissionPolicy{
+		TypeMeta:   policy.TypeMeta,
+		ObjectMeta: policy.ObjectMeta,
+		Spec:       policy.Spec,
+		Status:     policy.Status,
+	}
+}
+
+func policyToNamespacedPolicy(policy *v1alpha1.ValidatingAdmissionPolicy) *v1alpha1.NamespacedValidatingAdmissionPolicy {
+	return &v1alpha1.NamespacedValidatingAdmissionPolicy{
+		TypeMeta:   policy.TypeMeta,
+		ObjectMeta: policy.ObjectMeta,
+		Spec:       policy.Spec,
+		Status:     policy.Status,
+	}
+}
+
+func namespacedBindingToBinding(binding *v1alpha1.NamespacedValidatingAdmissionPolicyBinding) *v1alpha1.ValidatingAdmissionPolicyBinding {
+	if binding == nil {
+		return nil
+	}
+	result := &v1alpha1.ValidatingAdmissionPolicyBinding{
+		TypeMeta:   binding.TypeMeta,
+		ObjectMeta: binding.ObjectMeta,
+		Spec:       binding.Spec,
+		Status:     binding.Status,
+	}
+	// Params of a namespaced binding default to the namespace of the binding.
+	if paramRef := binding.Spec.ParamRef; paramRef != nil && len(paramRef.Namespace) == 0 {
+		result.Spec.ParamRef = paramRef.DeepCopy()
+		result.Spec.ParamRef.Namespace = binding.Namespace
+	}
+	return result
+}
+
+// policyKey identifies a policy in EnforcementOverrides and PolicyExceptions.
+// Namespaced policies are identified by their namespace and name, cluster
+// scoped policies by their name.
+func policyKey(definition *v1alpha1.ValidatingAdmissionPolicy) string {
+	if len(definition.Namespace) == 0 {
+		return definition.Name
+	}
+	return definition.Namespace + "/" + definition.Name
+}
+
+// inPolicyNamespace reports whether a request may be evaluated against the
+// policy. Namespaced policies only apply to namespaced resources in their own
+// namespace.
+func inPolicyNamespace(a admission.Attributes, definition *v1alpha1.ValidatingAdmissionPolicy) bool {
+	if len(definition.Namespace) == 0 {
+		return true
+	}
+	resource := a.GetResource()
+	if resource.Group == "" && resource.Resource == "namespaces" {
+		// The Namespace object itself is cluster scoped.
+		return false
+	}
+	return a.GetNamespace() == definition.Namespace
+}
+
+// checkParamNamespace returns an error if a namespaced binding refers to a
+// param of another namespace.
+func checkParamNamespace(binding *v1alpha1.ValidatingAdmissionPolicyBinding) error {
+	paramRef := binding.Spec.ParamRef
+	if len(binding.Namespace) == 0 || paramRef == nil || paramRef.Namespace == binding.Namespace {
+		return nil
+	}
+	return fmt.Errorf("paramRef of a namespaced binding must refer to a param in namespace %q", binding.Namespace)
+}
@@ -0,0 +1,107 @@
+package validatingadmissionpolicy
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+func TestInPolicyNamespace(t *testing.T) {
+	attributes := func(resource schema.GroupVersionResource, namespace, name string) admission.Attributes {
+		return admission.NewAttributesRecord(nil, nil, schema.GroupVersionKind{}, namespace, name, resource, "", admission.Create, nil, false, &user.DefaultInfo{Name: "alice"})
+	}
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	clusterRoles := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	namespaces := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+	for _, testCase := range []struct {
+		name            string
+		policyNamespace string
+		attributes      admission.Attributes
+		expected        bool
+	}{
+		{
+			name:       "cluster-policy",
+			attributes: attributes(clusterRoles, "", "example"),
+			expected:   true,
+		},
+		{
+			name:            "own-namespace",
+			policyNamespace: "team-a",
+			attributes:      attributes(configMaps, "team-a", "example"),
+			expected:        true,
+		},
+		{
+			name:            "other-namespace",
+			policyNamespace: "team-a",
+			attributes:      attributes(configMaps, "team-b", "example"),
+		},
+		{
+			name:            "cluster-scoped",
+			policyNamespace: "team-a",
+			attributes:      attributes(clusterRoles, "", "example"),
+		},
+		{
+			// The API server sets the namespace of requests for Namespaces
+			// to their name.
+			name:            "namespace-object",
+			policyNamespace: "team-a",
+			attributes:      attributes(namespaces, "team-a", "team-a"),
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			definition := &v1alpha1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: testCase.policyNamespace, Name: "policy"}}
+			if actual := inPolicyNamespace(testCase.attributes, definition); actual != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckParamNamespace(t *testing.T) {
+	for _, testCase := range []struct {
+		name      string
+		binding   *v1alpha1.ValidatingAdmissionPolicyBinding
+		expectErr bool
+	}{
+		{
+			name: "cluster-binding",
+			binding: &v1alpha1.ValidatingAdmissionPolicyBinding{
+				Spec: v1alpha1.ValidatingAdmissionPolicyBindingSpec{ParamRef: &v1alpha1.ParamRef{Namespace: "team-b", Name: "params"}},
+			},
+		},
+		{
+			// Params of namespaced bindings default to their namespace.
+			name: "defaulted-namespace",
+			binding: namespacedBindingToBinding(&v1alpha1.NamespacedValidatingAdmissionPolicyBinding{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "binding"},
+				Spec:       v1alpha1.ValidatingAdmissionPolicyBindingSpec{ParamRef: &v1alpha1.ParamRef{Name: "params"}},
+			}),
+		},
+		{
+			name: "cross-namespace",
+			binding: namespacedBindingToBinding(&v1alpha1.NamespacedValidatingAdmissionPolicyBinding{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "binding"},
+				Spec:       v1alpha1.ValidatingAdmissionPolicyBindingSpec{ParamRef: &v1alpha1.ParamRef{Namespace: "team-b", Name: "params"}},
+			}),
+			expectErr: true,
+		},
+		{
+			name: "no-params",
+			binding: namespacedBindingToBinding(&v1alpha1.NamespacedValidatingAdmissionPolicyBinding{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "binding"},
+			}),
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if err := checkParamNamespace(testCase.binding); (err != nil) != testCase.expectErr {
+				t.Errorf("expected error %v, got %v", testCase.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	listers "k8s.io/cel-admission-webhook/pkg/generated/listers/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// enforcementOverrideAnnotation is the audit annotation listing every decision
+// of a request which was affected by an EnforcementOverride. The API server
+// prefixes it with the name of the webhook.
+const enforcementOverrideAnnotation = "enforcement_override"
+
+// enforcementOverride is the combined effect of all EnforcementOverrides
+// which apply to a policy.
+type enforcementOverride struct {
+	// Names of the EnforcementOverrides which apply to the policy.
+	names []string
+
+	// Whether the policy must not be evaluated at all.
+	disabled bool
+
+	// validationActions to use instead of those of the bindings of the policy.
+	validationActions []v1alpha1.ValidationAction
+}
+
+// enforcementOverrides resolves the EnforcementOverrides in effect for a
+// single request.
+type enforcementOverrides []*v1alpha1.EnforcementOverride
+
+func listEnforcementOverrides(lister listers.EnforcementOverrideLister) enforcementOverrides {
+	overrides, err := lister.List(labels.Everything())
+	if err != nil {
+		// The lister of an informer never fails.
+		klog.Errorf("failed to list EnforcementOverrides: %v", err)
+		return nil
+	}
+	return overrides
+}
+
+// forPolicy returns the override which applies to the named policy, or nil if
+// the policy is not overridden.
+func (o enforcementOverrides) forPolicy(policyName string) *enforcementOverride {
+	var result *enforcementOverride
+	actions := sets.New[v1alpha1.ValidationAction]()
+	for _, override := range o {
+		if len(override.Spec.PolicyNames) > 0 && !sets.New(override.Spec.PolicyNames...).Has(policyName) {
+			continue
+		}
+		if result == nil {
+			result = &enforcementOverride{}
+		}
+		result.names = append(result.names, override.Name)
+		if len(override.Spec.ValidationActions) == 0 {
+			result.disabled = true
+		}
+		actions.Insert(override.Spec.ValidationActions...)
+	}
+	if result == nil {
+		return nil
+	}
+	sort.Strings(result.names)
+	if !result.disabled {
+		result.validationActions = sets.List(actions)
+	}
+	return result
+}
+
+// warn adds a warning for a failure which would have denied the request, if
+// the override asks for warnings. Failures of validations are handled by the
+// validationActions of the override like those of a binding instead.
+func (o *enforcementOverride) warn(ctx context.Context, definition *v1alpha1.ValidatingAdmissionPolicy, message string) {
+	for _, action := range o.validationActions {
+		if action == v1alpha1.Warn {
+			warning.AddWarning(ctx, "", fmt.Sprintf("ValidatingAdmissionPolicy '%s' failed with enforcement overridden by %v: %s", definition.Name, o.names, message))
+			return
+		}
+	}
+}
+
+// overriddenDecisionValue defines the JSON format of an entry of the
+// enforcement override audit annotation.
+type overriddenDecisionValue struct {
+	Policy            string                      `json:"policy"`
+	Binding           string                      `json:"binding,omitempty"`
+	Overrides         []string                    `json:"overrides"`
+	Disabled          bool                        `json:"disabled,omitempty"`
+	ValidationActions []v1alpha1.ValidationAction `json:"validationActions,omitempty"`
+	Message           string                      `json:"message,omitempty"`
+}
+
+// overriddenDecisions collects the decisions of a request which were affected
+// by an EnforcementOverride.
+type overriddenDecisions []overriddenDecisionValue
+
+// add records and logs a decision which was affected by an override. The
+// binding is nil for decisions made for the policy as a whole.
+func (d *overriddenDecisions) add(ctx context.Context, override *enforcementOverride, definition *v1alpha1.ValidatingAdmissionPolicy, binding *v1alpha1.ValidatingAdmissionPolicyBinding, message string) {
+	value := overriddenDecisionValue{
+		Policy:            policyKey(definition),
+		Overrides:         override.names,
+		Disabled:          override.disabled,
+		ValidationActions: override.validationActions,
+		Message:           message,
+	}
+	if binding != nil {
+		value.Binding = binding.Name
+	}
+	*d = append(*d, value)
+
+	klog.FromContext(ctx).Info("enforcement override applied",
+		"policy", value.Policy,
+		"binding", value.Binding,
+		"overrides", value.Overrides,
+		"disabled", value.Disabled,
+		"validationActions", value.ValidationActions,
+		"message", value.Message,
+	)
+}
+
+func (d overriddenDecisions) publish(attributes admission.Attributes) {
+	if len(d) == 0 {
+		return
+	}
+	valueJson, err := utiljson.Marshal(d)
+	if err != nil {
+		klog.Warningf("Failed to set admission audit annotation %s: %v", enforcementOverrideAnnotation, err)
+		return
+	}
+	value := string(valueJson)
+	if err := attributes.AddAnnotation(enforcementOverrideAnnotation, value); err != nil {
+		klog.Warningf("Failed to set admission audit annotation %s to %s: %v", enforcementOverrideAnnotation, value, err)
+	}
+}
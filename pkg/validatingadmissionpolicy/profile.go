@@ -0,0 +1,86 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+const (
+	// expressionProfilesPeriod is how often the profiles of the expressions
+	// of policies are published into their status.
+	expressionProfilesPeriod = time.Minute
+
+	// maxExpressionProfiles is the number of expressions whose profile is
+	// published into the status of a policy.
+	maxExpressionProfiles = 5
+)
+
+// publishExpressionProfiles writes the profiles of the most expensive
+// expressions of every policy into its status, and forgets the profiles of
+// deleted policies. Every replica profiles the requests it evaluates, but only
+// the leader publishes its profiles.
+func (c *policyController) publishExpressionProfiles(ctx context.Context) {
+	c.mutex.RLock()
+	definitions := make([]*v1alpha1.ValidatingAdmissionPolicy, 0, len(c.definitionInfo))
+	for _, info := range c.definitionInfo {
+		if info.lastReconciledValue != nil {
+			definitions = append(definitions, info.lastReconciledValue)
+		}
+	}
+	c.mutex.RUnlock()
+
+	leader := c.leadership.IsLeader()
+	retained := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		key := policyKey(definition)
+		retained[key] = true
+		if !leader {
+			continue
+		}
+
+		// Wait for the status of the current generation to be reconciled,
+		// which resets the profiles of the previous one.
+		if definition.Status.ObservedGeneration != definition.Generation {
+			continue
+		}
+		top := c.profiles.Top(key, definition.Generation, maxExpressionProfiles)
+		if len(top) == 0 {
+			continue
+		}
+		profiles := make([]v1alpha1.ExpressionProfile, len(top))
+		for i, p := range top {
+			profiles[i] = v1alpha1.ExpressionProfile{
+				FieldRef:       p.FieldRef,
+				Evaluations:    p.Evaluations,
+				AverageCost:    int64(p.AverageCost()),
+				MaxCost:        int64(p.MaxCost),
+				AverageLatency: metav1.Duration{Duration: p.AverageElapsed()},
+				MaxLatency:     metav1.Duration{Duration: p.MaxElapsed},
+			}
+		}
+		if apiequality.Semantic.DeepEqual(definition.Status.ExpressionProfiles, profiles) {
+			continue
+		}
+
+		newDefinition := definition.DeepCopy()
+		newDefinition.Status.ExpressionProfiles = profiles
+		var err error
+		if len(newDefinition.Namespace) == 0 {
+			_, err = c.policyClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().UpdateStatus(ctx, newDefinition, metav1.UpdateOptions{})
+		} else {
+			_, err = c.policyClient.AdmissionregistrationV1alpha1().NamespacedValidatingAdmissionPolicies(newDefinition.Namespace).UpdateStatus(ctx, policyToNamespacedPolicy(newDefinition), metav1.UpdateOptions{})
+		}
+		if err != nil {
+			// Conflicts are retried in the next period, with the latest
+			// version of the policy.
+			utilruntime.HandleError(err)
+		}
+	}
+	c.profiles.Retain(retained)
+}
@@ -0,0 +1,133 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/cel"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+	"k8s.io/cel-admission-webhook/pkg/generated/clientset/versioned/fake"
+)
+
+// fakeLeadership leads when leader is true.
+type fakeLeadership struct {
+	leader bool
+}
+
+func (l fakeLeadership) IsLeader() bool {
+	return l.leader
+}
+
+func (l fakeLeadership) OnStartedLeading(f func()) {}
+
+// evaluateProfiled evaluates the validations of the given generation of the
+// policy once, recording their profiles.
+func evaluateProfiled(t *testing.T, profiles *webhookcel.Profiles, policy string, generation int64, expressions ...string) {
+	t.Helper()
+	compiler := profiles.Compiler(webhookcel.NewFilterCompiler(webhookcel.CurrentEnvironmentVersion), policy, generation, func(i int) string {
+		return fmt.Sprintf("spec.validations[%d].expression", i)
+	})
+	accessors := make([]cel.ExpressionAccessor, len(expressions))
+	for i, expression := range expressions {
+		accessors[i] = &ValidationCondition{Expression: expression}
+	}
+	filter := compiler.Compile(accessors, cel.OptionalVariableDeclarations{}, celconfig.PerCallLimit)
+
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "example"},
+	}}
+	kind := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	attributes := admission.NewAttributesRecord(object, nil, kind, "default", "example", schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "", admission.Create, nil, false, &user.DefaultInfo{Name: "alice"})
+	versionedAttr := &admission.VersionedAttributes{Attributes: attributes, VersionedObject: object, VersionedKind: kind}
+	results, _, err := filter.ForInput(context.Background(), versionedAttr, cel.CreateAdmissionRequest(attributes), cel.OptionalVariableBindings{}, celconfig.RuntimeCELCostBudget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			t.Fatal(result.Error)
+		}
+	}
+}
+
+func TestPublishExpressionProfiles(t *testing.T) {
+	cheap := `object.metadata.name == "example"`
+	expensive := `[1, 2, 3, 4, 5, 6, 7, 8].all(x, [1, 2, 3, 4, 5, 6, 7, 8].all(y, x + y > 0))`
+
+	for _, testCase := range []struct {
+		name               string
+		leader             bool
+		observedGeneration int64
+		expected           []string
+	}{
+		{
+			name:               "leader",
+			leader:             true,
+			observedGeneration: 2,
+			expected:           []string{"spec.validations[1].expression", "spec.validations[0].expression"},
+		},
+		{
+			name:               "follower",
+			observedGeneration: 2,
+		},
+		{
+			// The profiles of a generation are only published once its
+			// status has been reconciled.
+			name:               "generation-not-observed",
+			leader:             true,
+			observedGeneration: 1,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			policy := &v1alpha1.ValidatingAdmissionPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy", Generation: 2},
+				Status:     v1alpha1.ValidatingAdmissionPolicyStatus{ObservedGeneration: testCase.observedGeneration},
+			}
+			client := fake.NewSimpleClientset(policy)
+			profiles := webhookcel.NewProfiles()
+			c := &policyController{
+				definitionInfo: map[namespacedName]*definitionInfo{
+					{name: "policy"}: {lastReconciledValue: policy},
+				},
+				profiles:     profiles,
+				policyClient: client,
+				leadership:   fakeLeadership{leader: testCase.leader},
+			}
+			evaluateProfiled(t, profiles, "policy", 2, cheap, expensive)
+			evaluateProfiled(t, profiles, "deleted", 1, cheap)
+
+			c.publishExpressionProfiles(context.Background())
+
+			updated, err := client.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().Get(context.Background(), "policy", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fieldRefs []string
+			for _, profile := range updated.Status.ExpressionProfiles {
+				fieldRefs = append(fieldRefs, profile.FieldRef)
+				if profile.Evaluations != 1 {
+					t.Errorf("expected 1 evaluation of %s, got %d", profile.FieldRef, profile.Evaluations)
+				}
+			}
+			if !reflect.DeepEqual(fieldRefs, testCase.expected) {
+				t.Errorf("expected profiles of %v, got %v", testCase.expected, fieldRefs)
+			}
+			// Every replica forgets the profiles of deleted policies.
+			if top := profiles.Top("deleted", 1, maxExpressionProfiles); top != nil {
+				t.Errorf("expected the profiles of the deleted policy to be dropped, got %v", top)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+)
+
+// shadowOf returns the name of the policy the given definition is a shadow
+// of, or the empty string if it is not a shadow.
+func shadowOf(definition *v1alpha1.ValidatingAdmissionPolicy) string {
+	if definition == nil {
+		return ""
+	}
+	return definition.Annotations[v1alpha1.ShadowOfAnnotation]
+}
+
+// evaluateShadow evaluates a shadow policy against a request using the binding
+// of the policy it shadows, and reports whether the shadow would have reached
+// the same decision as the enforced policy. The result of the shadow never
+// affects the admission response.
+//
+// Shadows are only evaluated for the bindings the enforced policy was
+// evaluated for, since there is no enforced decision to compare with
+// otherwise: requests the enforced policy does not match, excepted bindings
+// and disabled policies are never evaluated by their shadows.
+func (c *celAdmissionController) evaluateShadow(
+	ctx context.Context,
+	a admission.Attributes,
+	o admission.ObjectInterfaces,
+	definition *v1alpha1.ValidatingAdmissionPolicy,
+	binding *v1alpha1.ValidatingAdmissionPolicyBinding,
+	param runtime.Object,
+	enforcedResult ValidateResult,
+	shadow shadowData,
+) {
+	shadowDefinition := shadow.lastReconciledValue
+	logger := klog.FromContext(ctx).WithValues(
+		"policy", definition.Name,
+		"shadow", shadowDefinition.Name,
+		"binding", binding.Name,
+	)
+
+	shadowResult, err := func() (ValidateResult, error) {
+		if shadow.configurationError != nil {
+			return ValidateResult{}, shadow.configurationError
+		}
+
+		matches, matchKind, err := c.policyController.matcher.DefinitionMatches(a, o, shadowDefinition)
+		if err != nil || !matches {
+			return ValidateResult{}, err
+		}
+
+		// Reuse the param of the enforced policy unless the shadow expects a
+		// different kind of param.
+		paramKind := shadowDefinition.Spec.ParamKind
+		paramRef := binding.Spec.ParamRef
+		if paramKind == nil || paramRef == nil {
+			param = nil
+		} else if !apiequality.Semantic.DeepEqual(paramKind, definition.Spec.ParamKind) {
+			param, err = c.getParam(shadow.paramController, paramKind, paramRef)
+			if err != nil {
+				return ValidateResult{}, err
+			}
+		}
+
+		versionedAttr, err := admission.NewVersionedAttributes(a, matchKind, o)
+		if err != nil {
+			return ValidateResult{}, err
+		}
+		// Shadows are evaluated with their own budget, which the budget of the
+		// request does not account for.
+		budget, err := c.currentCostBudgets().policyCostBudget(shadowDefinition)
+		if err != nil {
+			return ValidateResult{}, err
+		}
+		return shadow.shadowValidator.Validate(ctx, versionedAttr, param, budget), nil
+	}()
+	if err != nil {
+		logger.Error(err, "failed to evaluate shadow policy")
+		ShadowMetrics.ObserveError(ctx, definition.Name, shadowDefinition.Name)
+		return
+	}
+
+	enforcedDenied, enforcedMessage := deniedBy(enforcedResult)
+	shadowDenied, shadowMessage := deniedBy(shadowResult)
+	ShadowMetrics.ObserveEvaluation(ctx, definition.Name, shadowDefinition.Name)
+	if enforcedDenied == shadowDenied {
+		return
+	}
+
+	logger.Info("shadow policy disagrees with enforced policy",
+		"resource", a.GetResource().String(),
+		"namespace", a.GetNamespace(),
+		"name", a.GetName(),
+		"enforcedDenied", enforcedDenied,
+		"enforcedMessage", redaction.FromContext(ctx).String(enforcedMessage),
+		"shadowDenied", shadowDenied,
+		"shadowMessage", redaction.FromContext(ctx).String(shadowMessage),
+	)
+	ShadowMetrics.ObserveDisagreement(ctx, definition.Name, shadowDefinition.Name, binding.Name, enforcedDenied)
+}
+
+// deniedBy reports whether any decision in the result denies the request,
+// along with the message of the first denial.
+func deniedBy(result ValidateResult) (bool, string) {
+	for _, decision := range result.Decisions {
+		if decision.Action == ActionDeny {
+			return true, decision.Message
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,136 @@
+package validatingadmissionpolicy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+)
+
+// fakeMatcher matches the definitions and bindings whose name it holds.
+type fakeMatcher struct {
+	matches map[string]bool
+}
+
+func (m fakeMatcher) ValidateInitialization() error {
+	return nil
+}
+
+func (m fakeMatcher) DefinitionMatches(a admission.Attributes, o admission.ObjectInterfaces, definition *v1alpha1.ValidatingAdmissionPolicy) (bool, schema.GroupVersionKind, error) {
+	return m.matches[definition.Name], a.GetKind(), nil
+}
+
+func (m fakeMatcher) BindingMatches(a admission.Attributes, o admission.ObjectInterfaces, binding *v1alpha1.ValidatingAdmissionPolicyBinding) (bool, error) {
+	return m.matches[binding.Name], nil
+}
+
+// fakeValidator returns its result for every request.
+type fakeValidator struct {
+	result ValidateResult
+}
+
+func (v fakeValidator) Validate(ctx context.Context, versionedAttr *admission.VersionedAttributes, versionedParams runtime.Object, runtimeCELCostBudget int64) ValidateResult {
+	return v.result
+}
+
+func TestEvaluateShadow(t *testing.T) {
+	admitted := ValidateResult{Decisions: []PolicyDecision{{Action: ActionAdmit}}}
+	denied := ValidateResult{Decisions: []PolicyDecision{{Action: ActionDeny, Message: "denied"}}}
+	definition := &v1alpha1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy"}}
+	binding := &v1alpha1.ValidatingAdmissionPolicyBinding{ObjectMeta: metav1.ObjectMeta{Name: "binding"}}
+	shadowDefinition := &v1alpha1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:        "shadow",
+		Annotations: map[string]string{v1alpha1.ShadowOfAnnotation: "policy"},
+	}}
+	attributes := admission.NewAttributesRecord(nil, nil, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, "default", "example", schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "", admission.Create, nil, false, &user.DefaultInfo{Name: "alice"})
+
+	for _, testCase := range []struct {
+		name                  string
+		enforced              ValidateResult
+		shadow                ValidateResult
+		shadowMatches         bool
+		configurationError    error
+		expectedEvaluations   float64
+		expectedErrors        float64
+		expectedDisagreements map[string]float64
+	}{
+		{
+			name:                "agreement",
+			enforced:            denied,
+			shadow:              denied,
+			shadowMatches:       true,
+			expectedEvaluations: 1,
+		},
+		{
+			name:                  "shadow-denies",
+			enforced:              admitted,
+			shadow:                denied,
+			shadowMatches:         true,
+			expectedEvaluations:   1,
+			expectedDisagreements: map[string]float64{"allow": 1},
+		},
+		{
+			name:                  "shadow-admits",
+			enforced:              denied,
+			shadow:                admitted,
+			shadowMatches:         true,
+			expectedEvaluations:   1,
+			expectedDisagreements: map[string]float64{"deny": 1},
+		},
+		{
+			// A shadow which does not match admits the request.
+			name:                  "shadow-does-not-match",
+			enforced:              denied,
+			shadow:                denied,
+			expectedEvaluations:   1,
+			expectedDisagreements: map[string]float64{"deny": 1},
+		},
+		{
+			name:               "configuration-error",
+			enforced:           denied,
+			shadowMatches:      true,
+			configurationError: errors.New("invalid expression"),
+			expectedErrors:     1,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ShadowMetrics.Reset()
+			c := &celAdmissionController{policyController: &policyController{
+				matcher: fakeMatcher{matches: map[string]bool{"shadow": testCase.shadowMatches}},
+			}}
+			c.costBudgets.Store(DefaultCostBudgets)
+			shadow := shadowData{definitionInfo: definitionInfo{
+				lastReconciledValue: shadowDefinition,
+				configurationError:  testCase.configurationError,
+				shadowValidator:     fakeValidator{result: testCase.shadow},
+			}}
+
+			c.evaluateShadow(context.Background(), attributes, nil, definition, binding, nil, testCase.enforced, shadow)
+
+			expectCounter := func(name string, metric metrics.CounterMetric, expected float64) {
+				t.Helper()
+				value, err := testutil.GetCounterMetricValue(metric)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value != expected {
+					t.Errorf("expected %v %s, got %v", expected, name, value)
+				}
+			}
+			expectCounter("evaluations", ShadowMetrics.evaluations.WithLabelValues("policy", "shadow"), testCase.expectedEvaluations)
+			expectCounter("errors", ShadowMetrics.errors.WithLabelValues("policy", "shadow"), testCase.expectedErrors)
+			for _, decision := range []string{"allow", "deny"} {
+				expectCounter(decision+" disagreements", ShadowMetrics.disagreements.WithLabelValues("policy", "shadow", "binding", decision), testCase.expectedDisagreements[decision])
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package validatingadmissionpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/cel/openapi/resolver"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	"k8s.io/cel-admission-webhook/pkg/apis/admissionregistration.x-k8s.io/v1alpha1"
+	webhookcel "k8s.io/cel-admission-webhook/pkg/cel"
+)
+
+type fakeSchemaResolver map[schema.GroupVersionKind]*spec.Schema
+
+func (r fakeSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if s, ok := r[gvk]; ok {
+		return s, nil
+	}
+	return nil, resolver.ErrSchemaNotFound
+}
+
+func objectSchema(properties map[string]spec.Schema) *spec.Schema {
+	return &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}, Properties: properties}}
+}
+
+func TestCheckLookupTypes(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	metadata := *objectSchema(map[string]spec.Schema{"name": *spec.StringProperty()})
+
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(podGVK, meta.RESTScopeNamespace)
+	restMapper.Add(configMapGVK, meta.RESTScopeNamespace)
+
+	// Type checking never starts the informers, so the clients never connect.
+	config := &rest.Config{Host: "https://127.0.0.1:0"}
+	factory := informers.NewSharedInformerFactory(kubernetes.NewForConfigOrDie(config), 0)
+	dynamicFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamic.NewForConfigOrDie(config), 0)
+	checker := &TypeChecker{
+		schemaResolver: fakeSchemaResolver{
+			podGVK: objectSchema(map[string]spec.Schema{"metadata": metadata}),
+			configMapGVK: objectSchema(map[string]spec.Schema{
+				"metadata": metadata,
+				"data":     *spec.MapProperty(spec.StringProperty()),
+			}),
+		},
+		restMapper: restMapper,
+		libraries: []webhookcel.Library{webhookcel.NewLookupLibrary(factory, dynamicFactory, []schema.GroupVersionResource{
+			{Version: "v1", Resource: "configmaps"},
+			{Version: "v1", Resource: "services"},
+		})},
+	}
+	policy := &v1alpha1.ValidatingAdmissionPolicy{Spec: v1alpha1.ValidatingAdmissionPolicySpec{
+		MatchConstraints: &v1alpha1.MatchResources{ResourceRules: []v1alpha1.NamedRuleWithOperations{{
+			RuleWithOperations: v1alpha1.RuleWithOperations{Rule: v1alpha1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+			}},
+		}}},
+	}}
+
+	for _, testCase := range []struct {
+		expression string
+		warning    string
+	}{
+		{expression: `lookup("v1/configmaps", "default", object.metadata.name).data["key"] == "value"`},
+		{expression: `lookup("v1/configmaps", "default", object.metadata.name) != null`},
+		{expression: `list("v1/configmaps", "default", "").all(c, c.data.size() > 0)`},
+		{expression: `lookup("v1/configmaps", "default", object.metadata.name).dta["key"] == "value"`, warning: "undefined field 'dta'"},
+		{expression: `list("v1/configmaps", "default", "").exists(c, c.spec.enabled)`, warning: "undefined field 'spec'"},
+		{expression: `lookup("v1/configmaps", "default", object.metadata.name).data["key"] == 1`, warning: "no matching overload"},
+		// Without a schema, objects remain dyn.
+		{expression: `lookup("v1/services", "default", "example").anything == "value"`},
+		// Resources which are not given as constants cannot be typed.
+		{expression: `lookup(object.metadata.name, "default", "example").anything == "value"`},
+	} {
+		t.Run(testCase.expression, func(t *testing.T) {
+			warnings := checker.CheckExpressions([]string{testCase.expression}, false, policy)
+			if len(warnings) != 1 {
+				t.Fatalf("expected one result, got %v", warnings)
+			}
+			if len(testCase.warning) == 0 && len(warnings[0]) > 0 {
+				t.Errorf("unexpected warning: %s", warnings[0])
+			}
+			if !strings.Contains(warnings[0], testCase.warning) {
+				t.Errorf("expected warning containing %q, got %q", testCase.warning, warnings[0])
+			}
+		})
+	}
+}
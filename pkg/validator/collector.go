@@ -0,0 +1,76 @@
+package validator
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apiserver/pkg/admission"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
+)
+
+// collector holds the annotations and warnings of a validator until it
+// returns, so that those of validators evaluated in parallel are merged in
+// order, and those of a validator which timed out are dropped rather than
+// added while the response is written.
+type collector struct {
+	admission.Attributes
+
+	lock        sync.Mutex
+	closed      bool
+	annotations []annotation
+	warnings    []agentWarning
+}
+
+type annotation struct {
+	key, value string
+	level      auditinternal.Level
+}
+
+type agentWarning struct {
+	agent, text string
+}
+
+func (c *collector) AddAnnotation(key, value string) error {
+	return c.AddAnnotationWithLevel(key, value, auditinternal.LevelMetadata)
+}
+
+func (c *collector) AddAnnotationWithLevel(key, value string, level auditinternal.Level) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.closed {
+		c.annotations = append(c.annotations, annotation{key: key, value: value, level: level})
+	}
+	return nil
+}
+
+func (c *collector) AddWarning(agent, text string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.closed {
+		c.warnings = append(c.warnings, agentWarning{agent: agent, text: text})
+	}
+}
+
+// merge adds the annotations and warnings collected so far to the request.
+func (c *collector) merge(ctx context.Context, a admission.Attributes) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	for _, annotation := range c.annotations {
+		if err := a.AddAnnotationWithLevel(annotation.key, annotation.value, annotation.level); err != nil {
+			klog.ErrorS(err, "Failed to add the audit annotation of a validator", "key", annotation.key)
+		}
+	}
+	for _, w := range c.warnings {
+		warning.AddWarning(ctx, w.agent, w.text)
+	}
+}
+
+// discard drops the annotations and warnings of the validator.
+func (c *collector) discard() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+}
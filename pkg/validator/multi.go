@@ -2,16 +2,97 @@ package validator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
 
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
 )
 
+// FailurePolicy is how the failures of a validator are handled, as opposed to
+// its denials.
+type FailurePolicy string
+
+const (
+	// Fail denies the request when the validator fails.
+	Fail FailurePolicy = "Fail"
+	// Ignore admits the request as if the validator had not been evaluated.
+	Ignore FailurePolicy = "Ignore"
+)
+
+var validatorFailures = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Namespace:      "cel_admission_webhook",
+		Name:           "validator_failures_total",
+		Help:           "Failures of validators, such as timeouts and internal errors, labeled by validator and failure policy.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"validator", "failure_policy"},
+)
+
+func init() {
+	legacyregistry.MustRegister(validatorFailures)
+}
+
+// Validator is a validator of Multi.
+type Validator struct {
+	admission.ValidationInterface
+
+	// Name identifies the validator in failures and metrics.
+	Name string
+	// FailurePolicy applies when the validator fails rather than denies the
+	// request: it returns an error other than a 4xx status, panics or times
+	// out. Defaults to Fail.
+	FailurePolicy FailurePolicy
+	// Timeout bounds the evaluation of the validator. Zero means the
+	// deadline of the request only.
+	Timeout time.Duration
+}
+
+// Options configure Multi.
+type Options struct {
+	// Parallel evaluates the validators concurrently rather than in order.
+	Parallel bool
+}
+
+// New returns a validator evaluating every validator which handles a request,
+// and aggregating their denials into one. The annotations and warnings of the
+// validators which complete are merged in the order of the validators,
+// whether they are evaluated in parallel or not.
+func New(options Options, validators ...Validator) admission.ValidationInterface {
+	for i := range validators {
+		if len(validators[i].Name) == 0 {
+			validators[i].Name = fmt.Sprintf("validator-%d", i)
+		}
+		if len(validators[i].FailurePolicy) == 0 {
+			validators[i].FailurePolicy = Fail
+		}
+	}
+	return multi{options: options, validators: validators}
+}
+
+// NewMulti returns a validator evaluating the validators in order, which
+// denies requests when any of them fails.
 func NewMulti(validators ...admission.ValidationInterface) admission.ValidationInterface {
-	return multi{validators: validators}
+	var named []Validator
+	for _, v := range validators {
+		named = append(named, Validator{ValidationInterface: v})
+	}
+	return New(Options{}, named...)
 }
 
 type multi struct {
-	validators []admission.ValidationInterface
+	options    Options
+	validators []Validator
 }
 
 func (m multi) Handles(operation admission.Operation) bool {
@@ -24,17 +105,167 @@ func (m multi) Handles(operation admission.Operation) bool {
 }
 
 func (m multi) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	var errs []error
+	var evaluations []*evaluation
 	for _, v := range m.validators {
 		if !v.Handles(a.GetOperation()) {
 			continue
 		}
+		e := evaluate(ctx, v, a, o)
+		if !m.options.Parallel {
+			if err := e.complete(ctx, a); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		evaluations = append(evaluations, e)
+	}
+	for _, e := range evaluations {
+		if err := e.complete(ctx, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return aggregate(errs)
+}
+
+// evaluation is the evaluation of a validator, in its own goroutine so that
+// the request stops waiting for it once it times out.
+type evaluation struct {
+	validator Validator
+	ctx       context.Context
+	cancel    context.CancelFunc
+	collector *collector
+	result    chan error
+}
+
+func evaluate(ctx context.Context, v Validator, a admission.Attributes, o admission.ObjectInterfaces) *evaluation {
+	e := &evaluation{
+		validator: v,
+		collector: &collector{Attributes: a},
+		result:    make(chan error, 1),
+	}
+	if v.Timeout > 0 {
+		e.ctx, e.cancel = context.WithTimeout(ctx, v.Timeout)
+	} else {
+		e.ctx, e.cancel = context.WithCancel(ctx)
+	}
+	validateCtx := warning.WithWarningRecorder(e.ctx, e.collector)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				klog.ErrorS(nil, "Validator panicked", "validator", v.Name, "panic", r, "stack", string(debug.Stack()))
+				e.result <- &failure{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		e.result <- v.Validate(validateCtx, e.collector, o)
+	}()
+	return e
+}
 
-		err := v.Validate(ctx, a, o)
+// complete waits for the validator to return or time out, merges its
+// annotations and warnings if it returned, and returns its denial, or its
+// failure unless its failure policy ignores it.
+func (e *evaluation) complete(ctx context.Context, a admission.Attributes) error {
+	defer e.cancel()
+	var err error
+	select {
+	case err = <-e.result:
+		e.collector.merge(ctx, a)
+		if err != nil && e.ctx.Err() != nil {
+			// The validator most likely failed because it was interrupted.
+			err = &failure{err: err, timeout: true}
+		}
+	case <-e.ctx.Done():
+		e.collector.discard()
+		err = &failure{err: e.ctx.Err(), timeout: true}
+	}
+	if err == nil {
+		return nil
+	}
 
-		if err != nil {
+	var statusErr *k8serrors.StatusError
+	var f *failure
+	if !errors.As(err, &f) {
+		if errors.As(err, &statusErr) && statusErr.ErrStatus.Code < http.StatusInternalServerError {
+			// A denial.
 			return err
 		}
+		f = &failure{err: err}
+	}
+
+	v := e.validator
+	validatorFailures.WithLabelValues(v.Name, string(v.FailurePolicy)).Inc()
+	if v.FailurePolicy == Ignore {
+		klog.V(2).InfoS("Ignoring the failure of a validator", "validator", v.Name, "err", f.err)
+		return nil
+	}
+	if f.timeout {
+		return k8serrors.NewTimeoutError(fmt.Sprintf("validator %s did not complete in time: %v", v.Name, f.err), 0)
 	}
+	if errors.As(f.err, &statusErr) {
+		return statusErr
+	}
+	return k8serrors.NewInternalError(fmt.Errorf("validator %s failed: %w", v.Name, f.err))
+}
 
-	return nil
+// failure is an error of a validator other than a denial.
+type failure struct {
+	err     error
+	timeout bool
+}
+
+func (f *failure) Error() string {
+	return f.err.Error()
+}
+
+func (f *failure) Unwrap() error {
+	return f.err
+}
+
+// aggregate returns a single error for the errors of the validators. Several
+// errors are aggregated into a status with the code and reason of the first,
+// and a cause for each.
+func aggregate(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+
+	var statuses []metav1.Status
+	var messages []string
+	for _, err := range errs {
+		var statusErr *k8serrors.StatusError
+		if !errors.As(err, &statusErr) {
+			statusErr = k8serrors.NewInternalError(err)
+		}
+		statuses = append(statuses, statusErr.ErrStatus)
+		messages = append(messages, statusErr.ErrStatus.Message)
+	}
+
+	first := statuses[0]
+	status := metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    first.Code,
+		Reason:  first.Reason,
+		Message: fmt.Sprintf("%d validators denied the request: %s", len(errs), strings.Join(messages, "; ")),
+		Details: &metav1.StatusDetails{},
+	}
+	if first.Details != nil {
+		status.Details.Name = first.Details.Name
+		status.Details.Group = first.Details.Group
+		status.Details.Kind = first.Details.Kind
+	}
+	for _, s := range statuses {
+		if s.Details != nil && len(s.Details.Causes) > 0 {
+			status.Details.Causes = append(status.Details.Causes, s.Details.Causes...)
+			continue
+		}
+		status.Details.Causes = append(status.Details.Causes, metav1.StatusCause{
+			Type:    metav1.CauseType(s.Reason),
+			Message: s.Message,
+		})
+	}
+	return &k8serrors.StatusError{ErrStatus: status}
 }
@@ -0,0 +1,220 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/warning"
+)
+
+// fakeValidator adds its annotation and warning, if any, then waits for
+// delay or until release is closed, and returns err or panics.
+type fakeValidator struct {
+	annotation, warning string
+	delay               time.Duration
+	release             <-chan struct{}
+	err                 error
+	panic               bool
+}
+
+func (v fakeValidator) Handles(operation admission.Operation) bool {
+	return true
+}
+
+func (v fakeValidator) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	if len(v.annotation) > 0 {
+		if err := a.AddAnnotation(v.annotation, "value"); err != nil {
+			return err
+		}
+	}
+	if len(v.warning) > 0 {
+		warning.AddWarning(ctx, "", v.warning)
+	}
+	time.Sleep(v.delay)
+	if v.release != nil {
+		<-v.release
+	}
+	if v.panic {
+		panic("validator panicked")
+	}
+	return v.err
+}
+
+// recordingAttributes records the keys of the annotations added to the
+// request, in order.
+type recordingAttributes struct {
+	admission.Attributes
+	annotations []string
+}
+
+func (a *recordingAttributes) AddAnnotation(key, value string) error {
+	return a.AddAnnotationWithLevel(key, value, auditinternal.LevelMetadata)
+}
+
+func (a *recordingAttributes) AddAnnotationWithLevel(key, value string, level auditinternal.Level) error {
+	a.annotations = append(a.annotations, key)
+	return nil
+}
+
+// recordingWarnings records the warnings added to the request, in order.
+type recordingWarnings struct {
+	warnings []string
+}
+
+func (r *recordingWarnings) AddWarning(agent, text string) {
+	r.warnings = append(r.warnings, text)
+}
+
+func TestMulti(t *testing.T) {
+	denial := k8serrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "example", errors.New("denied by policy"))
+	internalError := k8serrors.NewInternalError(errors.New("backend unavailable"))
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	for _, testCase := range []struct {
+		name                string
+		options             Options
+		validators          []Validator
+		expectedErr         error
+		expectedCode        int32
+		expectedMessage     string
+		expectedCauses      int
+		expectedAnnotations []string
+		expectedWarnings    []string
+	}{
+		{
+			name:                "admitted",
+			validators:          []Validator{{ValidationInterface: fakeValidator{annotation: "a", warning: "w"}}},
+			expectedAnnotations: []string{"a"},
+			expectedWarnings:    []string{"w"},
+		},
+		{
+			name:                "denial",
+			validators:          []Validator{{ValidationInterface: fakeValidator{annotation: "a", warning: "w", err: denial}, FailurePolicy: Ignore}},
+			expectedErr:         denial,
+			expectedAnnotations: []string{"a"},
+			expectedWarnings:    []string{"w"},
+		},
+		{
+			name:            "internal-error-fail",
+			validators:      []Validator{{ValidationInterface: fakeValidator{err: internalError}, FailurePolicy: Fail}},
+			expectedCode:    500,
+			expectedMessage: "backend unavailable",
+		},
+		{
+			name:       "internal-error-ignore",
+			validators: []Validator{{ValidationInterface: fakeValidator{err: internalError}, FailurePolicy: Ignore}},
+		},
+		{
+			name:            "error-fail",
+			validators:      []Validator{{Name: "policies", ValidationInterface: fakeValidator{err: errors.New("broken")}}},
+			expectedCode:    500,
+			expectedMessage: "validator policies failed: broken",
+		},
+		{
+			name:            "panic-fail",
+			validators:      []Validator{{Name: "policies", ValidationInterface: fakeValidator{panic: true}}},
+			expectedCode:    500,
+			expectedMessage: "panic: validator panicked",
+		},
+		{
+			name:       "panic-ignore",
+			validators: []Validator{{ValidationInterface: fakeValidator{panic: true}, FailurePolicy: Ignore}},
+		},
+		{
+			name: "timeout-fail",
+			validators: []Validator{
+				{ValidationInterface: fakeValidator{annotation: "a", warning: "w"}},
+				{Name: "slow", ValidationInterface: fakeValidator{annotation: "b", warning: "x", release: release}, Timeout: 10 * time.Millisecond},
+			},
+			expectedCode:        504,
+			expectedMessage:     "validator slow did not complete in time",
+			expectedAnnotations: []string{"a"},
+			expectedWarnings:    []string{"w"},
+		},
+		{
+			name: "timeout-ignore",
+			validators: []Validator{
+				{ValidationInterface: fakeValidator{annotation: "a", warning: "w"}},
+				{ValidationInterface: fakeValidator{annotation: "b", warning: "x", release: release}, Timeout: 10 * time.Millisecond, FailurePolicy: Ignore},
+			},
+			expectedAnnotations: []string{"a"},
+			expectedWarnings:    []string{"w"},
+		},
+		{
+			name: "aggregated-denials",
+			validators: []Validator{
+				{ValidationInterface: fakeValidator{annotation: "a", warning: "w", err: denial}},
+				{ValidationInterface: fakeValidator{annotation: "b", warning: "x"}},
+				{ValidationInterface: fakeValidator{annotation: "c", warning: "y", err: internalError}},
+			},
+			expectedCode:        403,
+			expectedMessage:     "2 validators denied the request",
+			expectedCauses:      2,
+			expectedAnnotations: []string{"a", "b", "c"},
+			expectedWarnings:    []string{"w", "x", "y"},
+		},
+		{
+			// The first validator completes last, but its annotations and
+			// warnings still come first.
+			name:    "aggregated-denials-parallel",
+			options: Options{Parallel: true},
+			validators: []Validator{
+				{ValidationInterface: fakeValidator{annotation: "a", warning: "w", err: denial, delay: 20 * time.Millisecond}},
+				{ValidationInterface: fakeValidator{annotation: "b", warning: "x"}},
+				{ValidationInterface: fakeValidator{annotation: "c", warning: "y", err: internalError}},
+			},
+			expectedCode:        403,
+			expectedMessage:     "2 validators denied the request",
+			expectedCauses:      2,
+			expectedAnnotations: []string{"a", "b", "c"},
+			expectedWarnings:    []string{"w", "x", "y"},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			attributes := &recordingAttributes{Attributes: admission.NewAttributesRecord(nil, nil, schema.GroupVersionKind{}, "default", "example", schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "", admission.Create, nil, false, &user.DefaultInfo{Name: "alice"})}
+			warnings := &recordingWarnings{}
+			ctx := warning.WithWarningRecorder(context.Background(), warnings)
+
+			err := New(testCase.options, testCase.validators...).Validate(ctx, attributes, nil)
+			switch {
+			case testCase.expectedErr != nil:
+				if err != testCase.expectedErr {
+					t.Errorf("expected error %v, got %v", testCase.expectedErr, err)
+				}
+			case testCase.expectedCode == 0:
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			default:
+				var statusErr *k8serrors.StatusError
+				if !errors.As(err, &statusErr) {
+					t.Fatalf("expected a status error, got %v", err)
+				}
+				if code := statusErr.ErrStatus.Code; code != testCase.expectedCode {
+					t.Errorf("expected code %d, got %d", testCase.expectedCode, code)
+				}
+				if !strings.Contains(statusErr.ErrStatus.Message, testCase.expectedMessage) {
+					t.Errorf("expected message containing %q, got %q", testCase.expectedMessage, statusErr.ErrStatus.Message)
+				}
+				if testCase.expectedCauses > 0 && len(statusErr.ErrStatus.Details.Causes) != testCase.expectedCauses {
+					t.Errorf("expected %d causes, got %v", testCase.expectedCauses, statusErr.ErrStatus.Details.Causes)
+				}
+			}
+			if strings.Join(attributes.annotations, ",") != strings.Join(testCase.expectedAnnotations, ",") {
+				t.Errorf("expected annotations %v, got %v", testCase.expectedAnnotations, attributes.annotations)
+			}
+			if strings.Join(warnings.warnings, ",") != strings.Join(testCase.expectedWarnings, ",") {
+				t.Errorf("expected warnings %v, got %v", testCase.expectedWarnings, warnings.warnings)
+			}
+		})
+	}
+}
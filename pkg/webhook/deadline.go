@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// deadlineMarginFraction is the inverse of the share of the timeout of a
+// review reserved for the response to reach the API server in time.
+const deadlineMarginFraction = 10
+
+var deadlineExceededReviews = metrics.NewCounter(
+	&metrics.CounterOpts{
+		Namespace:      "cel_admission_webhook",
+		Name:           "deadline_exceeded_reviews_total",
+		Help:           "Admission reviews whose evaluation did not complete before their deadline.",
+		StabilityLevel: metrics.ALPHA,
+	},
+)
+
+func init() {
+	legacyregistry.MustRegister(deadlineExceededReviews)
+}
+
+// withDeadline bounds the handling of a review, including the time it waits
+// for capacity, by the time the API server waits for its response.
+func (wh *webhook) withDeadline(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		timeout := wh.requestTimeout(req)
+		if timeout <= 0 {
+			next(w, req)
+			return
+		}
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		next(w, req.WithContext(ctx))
+	}
+}
+
+// requestTimeout returns the time left to answer a review: the timeout the
+// API server sends in the timeout query parameter, bounded by RequestTimeout,
+// less a margin for the response to reach the API server.
+func (wh *webhook) requestTimeout(req *http.Request) time.Duration {
+	timeout := wh.options.RequestTimeout
+	if value := req.URL.Query().Get("timeout"); len(value) > 0 {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 && (timeout <= 0 || d < timeout) {
+			timeout = d
+		}
+	}
+	return timeout - timeout/deadlineMarginFraction
+}
+
+// deadlineExceeded returns the error answering a review whose evaluation was
+// interrupted by its deadline.
+func deadlineExceeded() error {
+	deadlineExceededReviews.Inc()
+	return k8serrors.NewTimeoutError("the evaluation of CEL admission policies did not complete before the deadline of the request", 0)
+}
@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	for _, testCase := range []struct {
+		name           string
+		requestTimeout time.Duration
+		query          string
+		expected       time.Duration
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "api-server",
+			query:    "?timeout=10s",
+			expected: 9 * time.Second,
+		},
+		{
+			name:           "request-timeout",
+			requestTimeout: 5 * time.Second,
+			expected:       4500 * time.Millisecond,
+		},
+		{
+			name:           "api-server-shorter",
+			requestTimeout: 30 * time.Second,
+			query:          "?timeout=10s",
+			expected:       9 * time.Second,
+		},
+		{
+			name:           "request-timeout-shorter",
+			requestTimeout: 5 * time.Second,
+			query:          "?timeout=10s",
+			expected:       4500 * time.Millisecond,
+		},
+		{
+			name:           "invalid",
+			requestTimeout: 5 * time.Second,
+			query:          "?timeout=ten",
+			expected:       4500 * time.Millisecond,
+		},
+		{
+			name:           "negative",
+			requestTimeout: 5 * time.Second,
+			query:          "?timeout=-1s",
+			expected:       4500 * time.Millisecond,
+		},
+		{
+			name:  "zero",
+			query: "?timeout=0s",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			wh := &webhook{options: Options{RequestTimeout: testCase.requestTimeout}}
+			req := httptest.NewRequest(http.MethodPost, "/validate"+testCase.query, nil)
+			if actual := wh.requestTimeout(req); actual != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+// deadlineValidator waits for the context of the review to be done if wait
+// is set, then returns err, or the error of the context.
+type deadlineValidator struct {
+	wait bool
+	err  error
+}
+
+func (v deadlineValidator) Handles(admission.Operation) bool { return true }
+
+func (v deadlineValidator) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	if v.wait {
+		<-ctx.Done()
+		if v.err == nil {
+			return ctx.Err()
+		}
+	}
+	return v.err
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	podKind := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podResource := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	body := review(podKind, podResource, pod("example:v2"), pod("example:v1"))
+
+	for _, testCase := range []struct {
+		name             string
+		validator        deadlineValidator
+		expectedAllowed  bool
+		expectedCode     int32
+		expectedExceeded bool
+	}{
+		{
+			name:            "admitted",
+			expectedAllowed: true,
+			expectedCode:    http.StatusAccepted,
+		},
+		{
+			name:         "denied",
+			validator:    deadlineValidator{err: errors.New("denied")},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:             "deadline-exceeded",
+			validator:        deadlineValidator{wait: true},
+			expectedCode:     http.StatusGatewayTimeout,
+			expectedExceeded: true,
+		},
+		{
+			// Any error of an interrupted evaluation is the deadline's.
+			name:             "failed-after-deadline",
+			validator:        deadlineValidator{wait: true, err: errors.New("evaluation cancelled")},
+			expectedCode:     http.StatusGatewayTimeout,
+			expectedExceeded: true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			wh := newTestWebhook(testCase.validator)
+			exceeded, err := testutil.GetCounterMetricValue(deadlineExceededReviews)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/validate?timeout=50ms", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			wh.withDeadline(wh.handleWebhookValidate)(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+			}
+			var response admissionv1.AdmissionReview
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Response.Allowed != testCase.expectedAllowed || response.Response.Result.Code != testCase.expectedCode {
+				t.Errorf("expected allowed %v with code %d, got %v with code %d", testCase.expectedAllowed, testCase.expectedCode, response.Response.Allowed, response.Response.Result.Code)
+			}
+			if testCase.expectedExceeded && response.Response.Result.Reason != metav1.StatusReasonTimeout {
+				t.Errorf("expected reason %s, got %s", metav1.StatusReasonTimeout, response.Response.Result.Reason)
+			}
+
+			expected := exceeded
+			if testCase.expectedExceeded {
+				expected++
+			}
+			if actual, _ := testutil.GetCounterMetricValue(deadlineExceededReviews); actual != expected {
+				t.Errorf("expected deadline_exceeded_reviews_total to be %v, got %v", expected, actual)
+			}
+		})
+	}
+}
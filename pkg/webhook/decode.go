@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/component-base/tracing"
+)
+
+// maxPooledBufferSize bounds the size of the buffers kept for reuse, so that
+// a few large reviews do not pin their memory.
+const maxPooledBufferSize = 4 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// admissionReview is an AdmissionReview whose objects are parsed along with
+// it, rather than kept as raw JSON and parsed again. Objects are unstructured
+// until a conversion to another version requires their type, which is what
+// the evaluation of CEL expressions needs anyway.
+type admissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *admissionRequest `json:"request,omitempty"`
+}
+
+type admissionRequest struct {
+	admissionv1.AdmissionRequest
+	// Object and OldObject shadow the raw objects of the AdmissionRequest.
+	Object    map[string]interface{} `json:"object,omitempty"`
+	OldObject map[string]interface{} `json:"oldObject,omitempty"`
+}
+
+// parseRequest extracts an AdmissionReview from an http.Request if possible
+func parseRequest(r *http.Request) (*admissionReview, error) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		return nil, fmt.Errorf("Content-Type: %q should be %q",
+			r.Header.Get("Content-Type"), "application/json")
+	}
+
+	body := getBuffer()
+	defer putBuffer(body)
+	if r.ContentLength > 0 && r.ContentLength <= maxPooledBufferSize {
+		body.Grow(int(r.ContentLength))
+	}
+	if _, err := body.ReadFrom(r.Body); err != nil {
+		return nil, fmt.Errorf("could not read admission review request: %w", err)
+	}
+
+	if body.Len() == 0 {
+		return nil, fmt.Errorf("admission request body is empty")
+	}
+
+	var a admissionReview
+
+	// Unlike encoding/json, integers of the objects are kept as int64, as
+	// in unstructured objects. Strings are copied out of the buffer.
+	if err := utiljson.Unmarshal(body.Bytes(), &a); err != nil {
+		return nil, fmt.Errorf("could not parse admission review request: %v", err)
+	}
+
+	if a.Request == nil {
+		return nil, fmt.Errorf("admission review can't be used: Request field is nil")
+	}
+
+	return &a, nil
+}
+
+// decodeObjects returns the object and the old object of a review. It returns
+// the HTTP status to answer with if they are not of the kind of the review.
+func decodeObjects(ctx context.Context, request *admissionRequest) (object, oldObject runtime.Object, status int, err error) {
+	_, span := tracing.Start(ctx, "Decode objects",
+		attribute.String("resource", request.Resource.String()),
+		attribute.String("operation", string(request.Operation)),
+		attribute.String("namespace", request.Namespace),
+		attribute.String("name", request.Name),
+		attribute.String("uid", string(request.UID)))
+	defer span.End(500 * time.Millisecond)
+
+	if oldObject, err = decodeObject(request.OldObject, request.Kind); err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	if object, err = decodeObject(request.Object, request.Kind); err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	return object, oldObject, 0, nil
+}
+
+func decodeObject(content map[string]interface{}, kind metav1.GroupVersionKind) (runtime.Object, error) {
+	if content == nil {
+		return nil, nil
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	if gvk := obj.GroupVersionKind(); gvk != schema.GroupVersionKind(kind) {
+		return nil, fmt.Errorf("unexpected GVK %v. Expected %v", gvk, kind)
+	}
+	return obj, nil
+}
+
+// writeResponse writes the AdmissionReview answering a review. It returns an
+// error, and writes nothing, if the AdmissionReview cannot be encoded.
+func writeResponse(w http.ResponseWriter, response *admissionv1.AdmissionReview) error {
+	out := getBuffer()
+	defer putBuffer(out)
+	if err := json.NewEncoder(out).Encode(response); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out.Bytes())
+	return nil
+}
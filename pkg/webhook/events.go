@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/admission"
+)
+
+const (
+	// ReasonAdmissionDenied is the reason of the Event recorded when a
+	// request of a controller is denied.
+	ReasonAdmissionDenied = "AdmissionDenied"
+
+	// ReasonAdmissionWarning is the reason of the Event recorded when a
+	// request of a controller is admitted with warnings.
+	ReasonAdmissionWarning = "AdmissionWarning"
+
+	// maxEventMessageLength bounds the messages of Events, which may quote
+	// several policies.
+	maxEventMessageLength = 1024
+)
+
+// recordEvents records an Event for the denial of a request, or for its
+// warnings, when it was made by a controller or a service account. Unlike
+// users, they never show the response, so the Event is the only hint of why
+// for example the Pods of a ReplicaSet are not created.
+//
+// The Event is recorded on the controller owning the object of the request, or
+// on its namespace. The recorder aggregates similar Events and rate limits
+// them per object.
+func (wh *webhook) recordEvents(attributes admission.Attributes, allowed bool, message string, warnings []string) {
+	if wh.recorder == nil || attributes == nil || (allowed && len(warnings) == 0) {
+		return
+	}
+	if userInfo := attributes.GetUserInfo(); userInfo == nil || !strings.HasPrefix(userInfo.GetName(), "system:") {
+		return
+	}
+	target := eventTarget(attributes)
+	if target == nil {
+		return
+	}
+
+	request := fmt.Sprintf("%s of %s %s", strings.ToLower(string(attributes.GetOperation())), attributes.GetResource().GroupResource(), requestName(attributes))
+	if !allowed {
+		wh.recorder.Event(target, corev1.EventTypeWarning, ReasonAdmissionDenied,
+			truncate(fmt.Sprintf("Denied %s by %s: %s", request, attributes.GetUserInfo().GetName(), message)))
+		return
+	}
+	wh.recorder.Event(target, corev1.EventTypeWarning, ReasonAdmissionWarning,
+		truncate(fmt.Sprintf("Admitted %s by %s with warnings: %s", request, attributes.GetUserInfo().GetName(), strings.Join(warnings, "; "))))
+}
+
+// eventTarget returns the controller owning the object of the request, or the
+// namespace of the request, or nil for cluster scoped objects without owner.
+func eventTarget(attributes admission.Attributes) *corev1.ObjectReference {
+	object := attributes.GetObject()
+	if object == nil {
+		object = attributes.GetOldObject()
+	}
+	if object != nil {
+		if accessor, err := meta.Accessor(object); err == nil {
+			if owner := metav1.GetControllerOfNoCopy(accessor); owner != nil {
+				return &corev1.ObjectReference{
+					APIVersion: owner.APIVersion,
+					Kind:       owner.Kind,
+					Namespace:  attributes.GetNamespace(),
+					Name:       owner.Name,
+					UID:        owner.UID,
+				}
+			}
+		}
+	}
+	if namespace := attributes.GetNamespace(); len(namespace) > 0 {
+		return &corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Namespace: namespace, Name: namespace}
+	}
+	return nil
+}
+
+// requestName returns the name of the object of the request, or its
+// generateName for objects created without a name.
+func requestName(attributes admission.Attributes) string {
+	if name := attributes.GetName(); len(name) > 0 {
+		return name
+	}
+	if object := attributes.GetObject(); object != nil {
+		if accessor, err := meta.Accessor(object); err == nil && len(accessor.GetGenerateName()) > 0 {
+			return accessor.GetGenerateName() + "*"
+		}
+	}
+	return "(unnamed)"
+}
+
+func truncate(message string) string {
+	if len(message) <= maxEventMessageLength {
+		return message
+	}
+	return strings.ToValidUTF8(message[:maxEventMessageLength-3], "") + "..."
+}
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/tools/record"
+)
+
+func ownedPod(generateName string) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:    "default",
+		GenerateName: generateName,
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "v1", Kind: "Service", Name: "not-the-controller", UID: "1"},
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d4f8", UID: "2", Controller: &controller},
+		},
+	}}
+}
+
+func podAttributes(namespace, name string, object, oldObject runtime.Object, operation admission.Operation, userName string) admission.Attributes {
+	return admission.NewAttributesRecord(object, oldObject, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, namespace, name, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "", operation, nil, false, &user.DefaultInfo{Name: userName})
+}
+
+func TestEventTarget(t *testing.T) {
+	for _, testCase := range []struct {
+		name       string
+		attributes admission.Attributes
+		expected   *corev1.ObjectReference
+	}{
+		{
+			name:       "owner",
+			attributes: podAttributes("default", "", ownedPod("web-5d4f8-"), nil, admission.Create, "system:serviceaccount:kube-system:replicaset-controller"),
+			expected:   &corev1.ObjectReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "default", Name: "web-5d4f8", UID: "2"},
+		},
+		{
+			name:       "owner-of-deleted-object",
+			attributes: podAttributes("default", "web-5d4f8-x", nil, ownedPod(""), admission.Delete, "system:serviceaccount:kube-system:replicaset-controller"),
+			expected:   &corev1.ObjectReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "default", Name: "web-5d4f8", UID: "2"},
+		},
+		{
+			name:       "namespace",
+			attributes: podAttributes("default", "example", &corev1.Pod{}, nil, admission.Create, "system:admin"),
+			expected:   &corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Namespace: "default", Name: "default"},
+		},
+		{
+			name:       "cluster-scoped",
+			attributes: admission.NewAttributesRecord(&corev1.Node{}, nil, schema.GroupVersionKind{}, "", "example", schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, "", admission.Create, nil, false, &user.DefaultInfo{Name: "system:admin"}),
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := eventTarget(testCase.attributes); !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRecordEvents(t *testing.T) {
+	controller := "system:serviceaccount:kube-system:replicaset-controller"
+	longMessage := strings.Repeat("policy denied the request; ", 100)
+
+	for _, testCase := range []struct {
+		name       string
+		attributes admission.Attributes
+		allowed    bool
+		message    string
+		warnings   []string
+		expected   string
+	}{
+		{
+			name:       "denied-on-owner",
+			attributes: podAttributes("default", "", ownedPod("web-5d4f8-"), nil, admission.Create, controller),
+			message:    "image must be signed",
+			expected:   "Warning AdmissionDenied Denied create of pods web-5d4f8-* by " + controller + ": image must be signed involvedObject{kind=ReplicaSet,apiVersion=apps/v1}",
+		},
+		{
+			name:       "warned-on-namespace",
+			attributes: podAttributes("default", "example", &corev1.Pod{}, nil, admission.Create, "system:admin"),
+			allowed:    true,
+			warnings:   []string{"image is deprecated", "no limits"},
+			expected:   "Warning AdmissionWarning Admitted create of pods example by system:admin with warnings: image is deprecated; no limits involvedObject{kind=Namespace,apiVersion=v1}",
+		},
+		{
+			name:       "allowed",
+			attributes: podAttributes("default", "example", &corev1.Pod{}, nil, admission.Create, controller),
+			allowed:    true,
+		},
+		{
+			// Users see the response, so no Event is recorded.
+			name:       "user",
+			attributes: podAttributes("default", "example", &corev1.Pod{}, nil, admission.Create, "alice"),
+			message:    "image must be signed",
+		},
+		{
+			name:       "cluster-scoped",
+			attributes: admission.NewAttributesRecord(&corev1.Node{}, nil, schema.GroupVersionKind{}, "", "example", schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, "", admission.Create, nil, false, &user.DefaultInfo{Name: controller}),
+			message:    "node must be labelled",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			recorder.IncludeObject = true
+			wh := &webhook{recorder: recorder}
+
+			wh.recordEvents(testCase.attributes, testCase.allowed, testCase.message, testCase.warnings)
+			select {
+			case event := <-recorder.Events:
+				if event != testCase.expected {
+					t.Errorf("expected event %q, got %q", testCase.expected, event)
+				}
+			default:
+				if len(testCase.expected) > 0 {
+					t.Errorf("expected event %q", testCase.expected)
+				}
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		wh := &webhook{recorder: recorder}
+
+		wh.recordEvents(podAttributes("default", "example", &corev1.Pod{}, nil, admission.Create, controller), false, longMessage, nil)
+		event := strings.TrimPrefix(<-recorder.Events, "Warning AdmissionDenied ")
+		if len(event) != maxEventMessageLength || !strings.HasSuffix(event, "...") {
+			t.Errorf("expected a message of %d characters ending with ..., got %d characters: %q", maxEventMessageLength, len(event), event)
+		}
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	// Multi-byte characters are not cut in the middle.
+	message := strings.Repeat("a", maxEventMessageLength-4) + "ééé"
+	actual := truncate(message)
+	if actual != strings.Repeat("a", maxEventMessageLength-4)+"..." {
+		t.Errorf("expected the message to be truncated before the multi-byte characters, got %q", actual[maxEventMessageLength-10:])
+	}
+	if short := "short message"; truncate(short) != short {
+		t.Errorf("expected %q not to be truncated, got %q", short, truncate(short))
+	}
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// OverloadPolicy is how the webhook answers the reviews it has no capacity
+// for.
+type OverloadPolicy string
+
+const (
+	// OverloadReject answers with 429 Too Many Requests, upon which the API
+	// server applies the failurePolicy of the webhook.
+	OverloadReject OverloadPolicy = "Reject"
+
+	// OverloadAllow admits the request without evaluating any policy, with
+	// a warning.
+	OverloadAllow OverloadPolicy = "Allow"
+)
+
+// overloadWarning is the warning of the requests admitted by OverloadAllow.
+const overloadWarning = "admitted without evaluating CEL admission policies: the webhook is overloaded"
+
+var (
+	inFlightReviews = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "inflight_reviews",
+			Help:           "Admission reviews being evaluated.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	queuedReviews = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "queued_reviews",
+			Help:           "Admission reviews waiting for the evaluation of others to complete.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	shedReviews = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "shed_reviews_total",
+			Help:           "Admission reviews answered without evaluation because the webhook was overloaded, labeled by overload policy.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"overload_policy"},
+	)
+	oversizedReviews = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Namespace:      "cel_admission_webhook",
+			Name:           "oversized_reviews_total",
+			Help:           "Admission reviews rejected because their body exceeded the maximum size.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(inFlightReviews, queuedReviews, shedReviews, oversizedReviews)
+}
+
+// limiter bounds the number of reviews evaluated concurrently. Reviews beyond
+// the limit wait in a bounded queue for a bounded time.
+type limiter struct {
+	// inFlight and queue hold a token per review being evaluated and waiting
+	// respectively. inFlight is nil if the concurrency is unlimited.
+	inFlight     chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+}
+
+func newLimiter(maxInFlight, maxQueued int, queueTimeout time.Duration) *limiter {
+	if maxInFlight <= 0 {
+		return &limiter{}
+	}
+	return &limiter{
+		inFlight:     make(chan struct{}, maxInFlight),
+		queue:        make(chan struct{}, maxQueued),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire waits for the capacity to evaluate a review, and returns the
+// function releasing it. It returns false if the queue is full, or if no
+// capacity was released within the queue timeout.
+func (l *limiter) acquire(ctx context.Context) (func(), bool) {
+	if l.inFlight == nil {
+		return func() {}, true
+	}
+	release := func() {
+		<-l.inFlight
+		inFlightReviews.Dec()
+	}
+
+	select {
+	case l.inFlight <- struct{}{}:
+		inFlightReviews.Inc()
+		return release, true
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return nil, false
+	}
+	queuedReviews.Inc()
+	defer func() {
+		<-l.queue
+		queuedReviews.Dec()
+	}()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.inFlight <- struct{}{}:
+		inFlightReviews.Inc()
+		return release, true
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// limit applies the concurrency limit and the overload policy to the reviews
+// handled by next.
+func (wh *webhook) limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		release, ok := wh.limiter.acquire(req.Context())
+		if ok {
+			defer release()
+			next(w, req)
+			return
+		}
+
+		policy := wh.options.OverloadPolicy
+		if policy != OverloadAllow {
+			policy = OverloadReject
+		}
+		shedReviews.WithLabelValues(string(policy)).Inc()
+		if policy == OverloadReject {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "the webhook is overloaded", http.StatusTooManyRequests)
+			return
+		}
+		wh.handleOverloaded(w, req)
+	}
+}
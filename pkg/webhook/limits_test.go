@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+// blockingValidator signals each review it starts to evaluate on started, then
+// waits for release to be closed.
+type blockingValidator struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (v *blockingValidator) Handles(admission.Operation) bool { return true }
+
+func (v *blockingValidator) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	v.started <- struct{}{}
+	<-v.release
+	return nil
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(1, 1, 50*time.Millisecond)
+	release, ok := l.acquire(context.Background())
+	if !ok {
+		t.Fatal("expected the first review to be evaluated")
+	}
+
+	// The queued review is evaluated once the first one completes.
+	acquired := make(chan bool)
+	go func() {
+		release, ok := l.acquire(context.Background())
+		if ok {
+			release()
+		}
+		acquired <- ok
+	}()
+	for len(l.queue) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The queue is full.
+	if _, ok := l.acquire(context.Background()); ok {
+		t.Error("expected a review beyond the queue to be rejected")
+	}
+	release()
+	if !<-acquired {
+		t.Error("expected the queued review to be evaluated")
+	}
+
+	// A review which waits for longer than the queue timeout is rejected.
+	release, _ = l.acquire(context.Background())
+	defer release()
+	start := time.Now()
+	if _, ok := l.acquire(context.Background()); ok {
+		t.Error("expected the queued review to time out")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the review to wait for the queue timeout, waited %v", elapsed)
+	}
+
+	// Without concurrency limit, every review is evaluated.
+	unlimited := newLimiter(0, 0, 0)
+	for i := 0; i < 10; i++ {
+		if _, ok := unlimited.acquire(context.Background()); !ok {
+			t.Fatal("expected an unlimited limiter to evaluate every review")
+		}
+	}
+}
+
+func TestLimitOverload(t *testing.T) {
+	podKind := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podResource := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	body := review(podKind, podResource, pod("example:v2"), pod("example:v1"))
+
+	for _, testCase := range []struct {
+		name             string
+		policy           OverloadPolicy
+		expectedCode     int
+		expectedWarnings []string
+	}{
+		{
+			name:         "reject",
+			policy:       OverloadReject,
+			expectedCode: http.StatusTooManyRequests,
+		},
+		{
+			name:         "default",
+			expectedCode: http.StatusTooManyRequests,
+		},
+		{
+			name:             "allow",
+			policy:           OverloadAllow,
+			expectedCode:     http.StatusOK,
+			expectedWarnings: []string{overloadWarning},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			validator := &blockingValidator{started: make(chan struct{}), release: make(chan struct{})}
+			wh := newTestWebhook(validator)
+			wh.options.OverloadPolicy = testCase.policy
+			wh.limiter = newLimiter(1, 0, time.Second)
+			handler := wh.limit(wh.handleWebhookValidate)
+
+			// The first review takes the only slot.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				serveReview(handler, body)
+			}()
+			<-validator.started
+			defer func() {
+				close(validator.release)
+				<-done
+			}()
+
+			label := string(testCase.policy)
+			if testCase.policy != OverloadAllow {
+				label = string(OverloadReject)
+			}
+			shed, err := testutil.GetCounterMetricValue(shedReviews.WithLabelValues(label))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := serveReview(handler, body)
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			}
+			if actual, _ := testutil.GetCounterMetricValue(shedReviews.WithLabelValues(label)); actual != shed+1 {
+				t.Errorf("expected shed_reviews_total to be incremented to %v, got %v", shed+1, actual)
+			}
+			if testCase.expectedCode != http.StatusOK {
+				if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1" {
+					t.Errorf("expected Retry-After 1, got %q", retryAfter)
+				}
+				return
+			}
+			var response admissionv1.AdmissionReview
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Response == nil || !response.Response.Allowed {
+				t.Fatalf("expected the review to be admitted, got %s", w.Body.String())
+			}
+			if !reflect.DeepEqual(response.Response.Warnings, testCase.expectedWarnings) {
+				t.Errorf("expected warnings %v, got %v", testCase.expectedWarnings, response.Response.Warnings)
+			}
+		})
+	}
+}
+
+func TestMaxRequestBodyBytes(t *testing.T) {
+	podKind := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podResource := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	body := review(podKind, podResource, pod("example:v2"), pod("example:v1"))
+
+	for _, testCase := range []struct {
+		name         string
+		maxBytes     int64
+		expectedCode int
+	}{
+		{
+			name:         "unlimited",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "within",
+			maxBytes:     int64(len(body)),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "oversized",
+			maxBytes:     int64(len(body)) - 1,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			wh := newTestWebhook(&celValidator{})
+			wh.options.MaxRequestBodyBytes = testCase.maxBytes
+			oversized, err := testutil.GetCounterMetricValue(oversizedReviews)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := serveReview(wh.handleWebhookValidate, body)
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			}
+			expectedOversized := oversized
+			if testCase.expectedCode == http.StatusRequestEntityTooLarge {
+				expectedOversized++
+				if !strings.Contains(w.Body.String(), "too large") {
+					t.Errorf("expected the body to be reported too large, got %q", w.Body.String())
+				}
+			}
+			if actual, _ := testutil.GetCounterMetricValue(oversizedReviews); actual != expectedOversized {
+				t.Errorf("expected oversized_reviews_total to be %v, got %v", expectedOversized, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apiserver/pkg/admission"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+)
+
+// annotatedAttributes records the audit annotations added during admission so
+// that they can be returned in the AdmissionResponse.
+//
+// The API server prefixes the audit annotations of a webhook with the name of
+// the webhook and "/", and drops keys which then contain more than one "/".
+// Keys are therefore qualified names without a prefix, rather than the
+// "<prefix>/<name>" keys of admission.Attributes.
+type annotatedAttributes struct {
+	admission.Attributes
+
+	lock        sync.Mutex
+	annotations map[string]string
+}
+
+func (a *annotatedAttributes) AddAnnotation(key, value string) error {
+	return a.AddAnnotationWithLevel(key, value, auditinternal.LevelMetadata)
+}
+
+// AddAnnotationWithLevel records an audit annotation. The level is ignored,
+// since the API server adds the annotations of webhooks at the Metadata level.
+func (a *annotatedAttributes) AddAnnotationWithLevel(key, value string, level auditinternal.Level) error {
+	if strings.Contains(key, "/") {
+		return fmt.Errorf("audit annotation key %q of a webhook must not have a prefix", key)
+	}
+	if msgs := validation.IsQualifiedName(key); len(msgs) != 0 {
+		return fmt.Errorf("invalid audit annotation key %q: %s", key, strings.Join(msgs, ", "))
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.annotations == nil {
+		a.annotations = map[string]string{}
+	}
+	if existing, ok := a.annotations[key]; ok && existing != value {
+		return fmt.Errorf("audit annotation %q is already set to %q", key, existing)
+	}
+	a.annotations[key] = value
+	return nil
+}
+
+// auditAnnotations returns the recorded audit annotations, with the
+// sensitive values of the request redacted.
+func (a *annotatedAttributes) auditAnnotations(redact *redaction.Redaction) map[string]string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for key, value := range a.annotations {
+		a.annotations[key] = redact.String(value)
+	}
+	return a.annotations
+}
+
+// warningRecorder collects the warnings added during admission so that they
+// can be returned in the AdmissionResponse.
+type warningRecorder struct {
+	lock     sync.Mutex
+	warnings []string
+}
+
+func (r *warningRecorder) AddWarning(agent, text string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, w := range r.warnings {
+		if w == text {
+			return
+		}
+	}
+	r.warnings = append(r.warnings, text)
+}
+
+func (r *warningRecorder) list() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.warnings
+}
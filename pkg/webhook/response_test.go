@@ -0,0 +1,27 @@
+package webhook
+
+import "testing"
+
+func TestAnnotatedAttributes(t *testing.T) {
+	for _, testCase := range []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		{name: "unprefixed", key: "enforcement_override", valid: true},
+		{name: "policy", key: "policy.example.com_image", valid: true},
+		{name: "prefixed", key: "validation.policy.admission.k8s.io/validation_failure"},
+		{name: "invalid", key: "-invalid"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			attributes := &annotatedAttributes{}
+			err := attributes.AddAnnotation(testCase.key, "value")
+			if testCase.valid != (err == nil) {
+				t.Fatalf("unexpected error for key %q: %v", testCase.key, err)
+			}
+			if annotations := attributes.auditAnnotations(nil); testCase.valid != (annotations[testCase.key] == "value") {
+				t.Errorf("unexpected audit annotations %v", annotations)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package webhook
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,22 +11,36 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	admissionv1 "k8s.io/api/admission/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/admission"
 	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/tracing"
 	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/redaction"
 )
 
 var logger klog.Logger = klog.LoggerWithName(klog.Background(), "webhook")
 
+// HealthCheck reports the health of a component of the webhook on its
+// health endpoint.
+type HealthCheck interface {
+	Name() string
+	// Check describes the state of the component, and returns an error if
+	// it is unhealthy.
+	Check() (string, error)
+}
+
 type Interface interface {
 
 	// Runs the webhook server until the passed context is cancelled, or it
@@ -40,29 +53,83 @@ type Interface interface {
 	Run(ctx context.Context) error
 }
 
-func New(addr string, certFile, keyFile string, scheme *runtime.Scheme, validator admission.ValidationInterface) Interface {
-	codecs := serializer.NewCodecFactory(scheme)
+// Options configure the HTTPS server of the webhook.
+type Options struct {
+	// Addr is the address to listen on.
+	Addr string
+	// CertFile and KeyFile are the paths to the TLS certificate and key.
+	CertFile, KeyFile string
+	// CertPollInterval is the interval at which the certificate and key are
+	// checked for changes, upon which the server restarts.
+	CertPollInterval time.Duration
+	// ShutdownTimeout bounds the graceful shutdown of the previous server
+	// when it restarts.
+	ShutdownTimeout time.Duration
+
+	// RequestTimeout bounds the evaluation of a review, along with the
+	// timeout sent by the API server. Zero means the timeout of the API
+	// server only.
+	RequestTimeout time.Duration
+
+	// ReadHeaderTimeout, ReadTimeout and WriteTimeout bound the reading of
+	// requests and the writing of responses. Zero means no timeout.
+	ReadHeaderTimeout, ReadTimeout, WriteTimeout time.Duration
+	// MaxRequestBodyBytes is the maximum size of the body of a review. Zero
+	// means unlimited.
+	MaxRequestBodyBytes int64
+
+	// MaxInFlight is the maximum number of reviews evaluated concurrently.
+	// Zero means unlimited.
+	MaxInFlight int
+	// MaxQueued is the maximum number of reviews waiting for up to
+	// QueueTimeout for the evaluation of others to complete.
+	MaxQueued    int
+	QueueTimeout time.Duration
+	// OverloadPolicy is how the reviews beyond the queue are answered.
+	// Defaults to OverloadReject.
+	OverloadPolicy OverloadPolicy
+
+	// TracerProvider exports the spans of the evaluation of reviews, as
+	// children of the spans of the API server propagated in the W3C trace
+	// context headers of the reviews. Nil disables tracing.
+	TracerProvider oteltrace.TracerProvider
+}
+
+// New returns the webhook server. The recorder, if not nil, records Events
+// for the denials and warnings of the requests of controllers.
+func New(options Options, objectInterfaces admission.ObjectInterfaces, validator admission.ValidationInterface, redactor *redaction.Redactor, recorder record.EventRecorder, healthChecks ...HealthCheck) Interface {
 	return &webhook{
-		objectInferfaces: admission.NewObjectInterfacesFromScheme(scheme),
-		decoder:          codecs.UniversalDeserializer(),
+		objectInferfaces: objectInterfaces,
 		validator:        validator,
-		addr:             addr,
-		certFile:         certFile,
-		keyFile:          keyFile,
+		redactor:         redactor,
+		recorder:         recorder,
+		healthChecks:     healthChecks,
+		options:          options,
+		limiter:          newLimiter(options.MaxInFlight, options.MaxQueued, options.QueueTimeout),
 	}
 }
 
 type webhook struct {
-	lock              sync.Mutex
-	port              int
-	validator         admission.ValidationInterface
-	objectInferfaces  admission.ObjectInterfaces
-	decoder           runtime.Decoder
-	addr              string
-	certFile, keyFile string
+	lock             sync.Mutex
+	port             int
+	validator        admission.ValidationInterface
+	redactor         *redaction.Redactor
+	recorder         record.EventRecorder
+	healthChecks     []HealthCheck
+	objectInferfaces admission.ObjectInterfaces
+	options          Options
+	// limiter is shared by the successive servers, which overlap while the
+	// previous one shuts down.
+	limiter *limiter
+}
+
+// contextualObjectInterfaces are admission.ObjectInterfaces which can bind the
+// calls they make, such as those to conversion webhooks, to a context.
+type contextualObjectInterfaces interface {
+	WithContext(ctx context.Context) admission.ObjectInterfaces
 }
 
-func notifyChanges(ctx context.Context, paths ...string) <-chan struct{} {
+func notifyChanges(ctx context.Context, interval time.Duration, paths ...string) <-chan struct{} {
 
 	type info struct {
 		modTime time.Time
@@ -94,7 +161,7 @@ func notifyChanges(ctx context.Context, paths ...string) <-chan struct{} {
 				// context cancelled, stop watching
 				return
 
-			case <-time.After(2 * time.Second):
+			case <-time.After(interval):
 				newInfos := getInfos()
 				if reflect.DeepEqual(lastInfos, newInfos) {
 					continue
@@ -127,10 +194,15 @@ func (wh *webhook) Run(ctx context.Context) error {
 	launchServer := func() (*http.Server, <-chan error) {
 		mux := http.NewServeMux()
 		mux.HandleFunc("/health", wh.handleHealth)
-		mux.HandleFunc("/validate", wh.handleWebhookValidate)
-		srv := &http.Server{}
+		mux.Handle("/metrics", legacyregistry.Handler())
+		mux.Handle("/validate", wh.validateHandler())
+		srv := &http.Server{
+			ReadHeaderTimeout: wh.options.ReadHeaderTimeout,
+			ReadTimeout:       wh.options.ReadTimeout,
+			WriteTimeout:      wh.options.WriteTimeout,
+		}
 		srv.Handler = mux
-		srv.Addr = wh.addr
+		srv.Addr = wh.options.Addr
 
 		errChan := make(chan error)
 
@@ -139,7 +211,7 @@ func (wh *webhook) Run(ctx context.Context) error {
 			defer wg.Done()
 			defer close(errChan)
 
-			err := srv.ListenAndServeTLS(wh.certFile, wh.keyFile)
+			err := srv.ListenAndServeTLS(wh.options.CertFile, wh.options.KeyFile)
 			errChan <- err
 			// ListenAndServeTLS always returns non-nil error
 		}()
@@ -150,7 +222,7 @@ func (wh *webhook) Run(ctx context.Context) error {
 	watchCtx, cancelWatches := context.WithCancel(ctx)
 	defer cancelWatches()
 
-	keyWatch := notifyChanges(watchCtx, wh.certFile, wh.keyFile)
+	keyWatch := notifyChanges(watchCtx, wh.options.CertPollInterval, wh.options.CertFile, wh.options.KeyFile)
 
 	currentServer, currentErrorChannel := launchServer()
 loop:
@@ -190,7 +262,7 @@ loop:
 
 				//!TOOD: add shutdown timeout, requests to a webhook should
 				// not be long-lived
-				shutdownCtx, shutdownCancel := context.WithTimeout(watchCtx, 5*time.Second)
+				shutdownCtx, shutdownCancel := context.WithTimeout(watchCtx, wh.options.ShutdownTimeout)
 				defer shutdownCancel()
 
 				q.Shutdown(shutdownCtx)
@@ -201,14 +273,83 @@ loop:
 	return serverError
 }
 
+// validateHandler returns the handler of the reviews, traced if a
+// TracerProvider is set.
+func (wh *webhook) validateHandler() http.Handler {
+	var validate http.Handler = wh.withDeadline(wh.limit(wh.handleWebhookValidate))
+	if wh.options.TracerProvider != nil {
+		validate = tracing.WithTracing(validate, wh.options.TracerProvider, "validate")
+	}
+	return validate
+}
+
 func (wh *webhook) handleHealth(w http.ResponseWriter, req *http.Request) {
-	fmt.Fprint(w, "OK")
+	var report bytes.Buffer
+	healthy := true
+	for _, check := range wh.healthChecks {
+		state, err := check.Check()
+		if err != nil {
+			healthy = false
+			fmt.Fprintf(&report, "[-]%s failed: %v\n", check.Name(), err)
+			continue
+		}
+		fmt.Fprintf(&report, "[+]%s ok: %s\n", check.Name(), state)
+	}
+	if !healthy {
+		w.WriteHeader(http.StatusInternalServerError)
+		report.WriteString("unhealthy")
+	} else {
+		report.WriteString("OK")
+	}
+	w.Write(report.Bytes())
 }
 
-func (wh *webhook) handleWebhookValidate(w http.ResponseWriter, req *http.Request) {
+// readReview reads the AdmissionReview of the request, within the maximum
+// size of its body. It answers the request and returns nil if it is invalid.
+func (wh *webhook) readReview(w http.ResponseWriter, req *http.Request) *admissionReview {
+	_, span := tracing.Start(req.Context(), "Parse AdmissionReview")
+	defer span.End(500 * time.Millisecond)
+
+	if wh.options.MaxRequestBodyBytes > 0 {
+		req.Body = http.MaxBytesReader(w, req.Body, wh.options.MaxRequestBodyBytes)
+	}
 	parsed, err := parseRequest(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			oversizedReviews.Inc()
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return nil
+	}
+	return parsed
+}
+
+// handleOverloaded admits the request without evaluating it, with a warning.
+func (wh *webhook) handleOverloaded(w http.ResponseWriter, req *http.Request) {
+	parsed := wh.readReview(w, req)
+	if parsed == nil {
+		return
+	}
+	response := reviewResponse(parsed.Request.UID, nil)
+	response.Response.Warnings = []string{overloadWarning}
+	if err := writeResponse(w, response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("review admitted without evaluation, the webhook is overloaded", "resource", parsed.Request.Resource.String(), "namespace", parsed.Request.Namespace, "name", parsed.Request.Name, "uid", parsed.Request.UID)
+}
+
+func (wh *webhook) handleWebhookValidate(w http.ResponseWriter, req *http.Request) {
+	// The steps of reviews slower than the threshold are logged.
+	ctx, span := tracing.Start(req.Context(), "Review")
+	defer span.End(500 * time.Millisecond)
+	req = req.WithContext(ctx)
+
+	parsed := wh.readReview(w, req)
+	if parsed == nil {
 		return
 	}
 
@@ -229,60 +370,16 @@ func (wh *webhook) handleWebhookValidate(w http.ResponseWriter, req *http.Reques
 		logger.Error(err, "review response", "uid", parsed.Request.UID, "status", status)
 	}
 
-	err = nil
+	var err error
+	attributes := &annotatedAttributes{}
+	warnings := &warningRecorder{}
+	var redact *redaction.Redaction
 
 	if wh.validator.Handles(admission.Operation(parsed.Request.Operation)) {
-		var object runtime.Object
-		var oldObject runtime.Object
-
-		if len(parsed.Request.OldObject.Raw) > 0 {
-			obj, gvk, err := wh.decoder.Decode(parsed.Request.OldObject.Raw, nil, nil)
-			switch {
-			case gvk == nil || *gvk != schema.GroupVersionKind(parsed.Request.Kind):
-				// GVK case first. If object type is unknown it is parsed to
-				// unstructured, but
-				failure(fmt.Errorf("unexpected GVK %v. Expected %v", gvk, parsed.Request.Kind), http.StatusBadRequest)
-				return
-			case err != nil && runtime.IsNotRegisteredError(err):
-				var oldUnstructured unstructured.Unstructured
-				err = json.Unmarshal(parsed.Request.OldObject.Raw, &oldUnstructured)
-				if err != nil {
-					failure(err, http.StatusInternalServerError)
-					return
-				}
-
-				oldObject = &oldUnstructured
-			case err != nil:
-				failure(err, http.StatusBadRequest)
-				return
-			default:
-				oldObject = obj
-			}
-		}
-
-		if len(parsed.Request.Object.Raw) > 0 {
-			obj, gvk, err := wh.decoder.Decode(parsed.Request.Object.Raw, nil, nil)
-			switch {
-			case gvk == nil || *gvk != schema.GroupVersionKind(parsed.Request.Kind):
-				// GVK case first. If object type is unknown it is parsed to
-				// unstructured, but
-				failure(fmt.Errorf("unexpected GVK %v. Expected %v", gvk, parsed.Request.Kind), http.StatusBadRequest)
-				return
-			case err != nil && runtime.IsNotRegisteredError(err):
-				var objUnstructured unstructured.Unstructured
-				err = json.Unmarshal(parsed.Request.Object.Raw, &objUnstructured)
-				if err != nil {
-					failure(err, http.StatusInternalServerError)
-					return
-				}
-
-				object = &objUnstructured
-			case err != nil:
-				failure(err, http.StatusBadRequest)
-				return
-			default:
-				object = obj
-			}
+		object, oldObject, status, decodeErr := decodeObjects(req.Context(), parsed.Request)
+		if decodeErr != nil {
+			failure(decodeErr, status)
+			return
 		}
 
 		// Parse into native types if possible
@@ -304,7 +401,7 @@ func (wh *webhook) handleWebhookValidate(w http.ResponseWriter, req *http.Reques
 
 		//!TODO: Parse options as v1.CreateOptions, v1.DeleteOptions, or v1.PatchOptions
 
-		attrs := admission.NewAttributesRecord(
+		attributes.Attributes = admission.NewAttributesRecord(
 			object,
 			oldObject,
 			schema.GroupVersionKind(parsed.Request.Kind),
@@ -326,22 +423,48 @@ func (wh *webhook) handleWebhookValidate(w http.ResponseWriter, req *http.Reques
 				Extra:  convertExtra(parsed.Request.UserInfo.Extra),
 			})
 
-		err = wh.validator.Validate(context.TODO(), attrs, wh.objectInferfaces)
+		// Sensitive values of the objects never leave the process, even when
+		// policies echo them.
+		redact = wh.redactor.ForRequest(attributes.GetResource().GroupResource(), object, oldObject)
+
+		ctx := warning.WithWarningRecorder(req.Context(), warnings)
+		ctx = redaction.WithRedaction(ctx, redact)
+		objectInterfaces := wh.objectInferfaces
+		if contextual, ok := objectInterfaces.(contextualObjectInterfaces); ok {
+			// Conversions of the objects end with the review.
+			objectInterfaces = contextual.WithContext(ctx)
+		}
+		err = wh.validator.Validate(ctx, attributes, objectInterfaces)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Policies failed because their evaluation was interrupted,
+			// rather than because of the request.
+			err = deadlineExceeded()
+		}
 	}
 
 	response := reviewResponse(
 		parsed.Request.UID,
 		err,
 	)
+	response.Response.Result.Message = redact.String(response.Response.Result.Message)
+	if details := response.Response.Result.Details; details != nil {
+		for i := range details.Causes {
+			details.Causes[i].Message = redact.String(details.Causes[i].Message)
+		}
+	}
+	response.Response.AuditAnnotations = attributes.auditAnnotations(redact)
+	response.Response.Warnings = redact.Strings(warnings.list())
+	if attributes.Attributes != nil {
+		wh.recordEvents(attributes, response.Response.Allowed, response.Response.Result.Message, response.Response.Warnings)
+	}
 
-	out, err := json.Marshal(response)
+	_, encodeSpan := tracing.Start(req.Context(), "Encode AdmissionReview", attribute.Bool("allowed", response.Response.Allowed))
+	err = writeResponse(w, response)
+	encodeSpan.End(500 * time.Millisecond)
 	if err != nil {
 		failure(err, http.StatusInternalServerError)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(out)
 	logger.Info(
 		"review response",
 		"resource",
@@ -373,11 +496,14 @@ func reviewResponse(uid types.UID, err error) *admissionv1.AdmissionReview {
 		message = err.Error()
 	}
 
+	// The details carry a cause for each of the denials of a request.
+	var details *metav1.StatusDetails
 	var statusErr *k8serrors.StatusError
 	if ok := errors.As(err, &statusErr); ok {
 		reason = statusErr.ErrStatus.Reason
 		message = statusErr.ErrStatus.Message
 		status = statusErr.ErrStatus.Code
+		details = statusErr.ErrStatus.Details.DeepCopy()
 	}
 
 	return &admissionv1.AdmissionReview{
@@ -392,35 +518,8 @@ func reviewResponse(uid types.UID, err error) *admissionv1.AdmissionReview {
 				Code:    status,
 				Message: message,
 				Reason:  reason,
+				Details: details,
 			},
 		},
 	}
 }
-
-// parseRequest extracts an AdmissionReview from an http.Request if possible
-func parseRequest(r *http.Request) (*admissionv1.AdmissionReview, error) {
-	if r.Header.Get("Content-Type") != "application/json" {
-		return nil, fmt.Errorf("Content-Type: %q should be %q",
-			r.Header.Get("Content-Type"), "application/json")
-	}
-
-	bodybuf := new(bytes.Buffer)
-	bodybuf.ReadFrom(r.Body)
-	body := bodybuf.Bytes()
-
-	if len(body) == 0 {
-		return nil, fmt.Errorf("admission request body is empty")
-	}
-
-	var a admissionv1.AdmissionReview
-
-	if err := json.Unmarshal(body, &a); err != nil {
-		return nil, fmt.Errorf("could not parse admission review request: %v", err)
-	}
-
-	if a.Request == nil {
-		return nil, fmt.Errorf("admission review can't be used: Request field is nil")
-	}
-
-	return &a, nil
-}
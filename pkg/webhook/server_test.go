@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cel-admission-webhook/pkg/redaction"
+)
+
+// celValidator converts the objects of requests to unstructured content, as
+// the evaluation of CEL expressions does.
+type celValidator struct {
+	objects []map[string]interface{}
+}
+
+func (v *celValidator) Handles(admission.Operation) bool { return true }
+
+func (v *celValidator) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	v.objects = v.objects[:0]
+	for _, obj := range []runtime.Object{a.GetObject(), a.GetOldObject()} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		v.objects = append(v.objects, content)
+	}
+	return nil
+}
+
+func newTestWebhook(validator admission.ValidationInterface) *webhook {
+	redactor, err := redaction.NewRedactor(redaction.DefaultRules...)
+	if err != nil {
+		panic(err)
+	}
+	return New(Options{}, nil, validator, redactor, nil).(*webhook)
+}
+
+func review(kind metav1.GroupVersionKind, resource metav1.GroupVersionResource, object, oldObject runtime.Object) []byte {
+	raw := func(obj runtime.Object) runtime.RawExtension {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			panic(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "705ab4f5-6393-11e8-b7cc-42010a800002",
+			Kind:      kind,
+			Resource:  resource,
+			Namespace: "default",
+			Name:      "example",
+			Operation: admissionv1.Update,
+			Object:    raw(object),
+			OldObject: raw(oldObject),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+func pod(image string) *corev1.Pod {
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default", Labels: map[string]string{"app": "example"}},
+	}
+	for i := 0; i < 3; i++ {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:  fmt.Sprintf("container-%d", i),
+			Image: image,
+			Env:   []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "PORT", Value: "8080"}},
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		})
+	}
+	return pod
+}
+
+// widget returns a custom resource with the given number of entries, of
+// about 100 bytes each.
+func widget(entries int) *unstructured.Unstructured {
+	items := make([]interface{}, entries)
+	for i := range items {
+		items[i] = map[string]interface{}{
+			"name":     fmt.Sprintf("entry-%d", i),
+			"value":    fmt.Sprintf("value-of-the-entry-%d", i),
+			"replicas": int64(i),
+			"enabled":  i%2 == 0,
+		}
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "example", "namespace": "default"},
+		"spec":       map[string]interface{}{"entries": items},
+	}}
+}
+
+func serveReview(h http.HandlerFunc, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func TestHandleWebhookValidate(t *testing.T) {
+	podKind := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podResource := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	validator := &celValidator{}
+	wh := newTestWebhook(validator)
+	w := serveReview(wh.handleWebhookValidate, review(podKind, podResource, pod("example:v2"), pod("example:v1")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	var response admissionv1.AdmissionReview
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Response == nil || !response.Response.Allowed || response.Response.UID != "705ab4f5-6393-11e8-b7cc-42010a800002" {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+
+	// The objects are decoded as the unstructured content of the typed objects.
+	for i, expected := range []*corev1.Pod{pod("example:v2"), pod("example:v1")} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equality.Semantic.DeepEqual(content, validator.objects[i]) {
+			t.Errorf("unexpected object %d: %v", i, validator.objects[i])
+		}
+	}
+
+	// The objects must be of the kind of the review.
+	w = serveReview(wh.handleWebhookValidate, review(metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, podResource, pod("example:v2"), pod("example:v1")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func BenchmarkHandleWebhookValidate(b *testing.B) {
+	klog.LogToStderr(false)
+	klog.SetOutput(io.Discard)
+	defer klog.LogToStderr(true)
+
+	podKind := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podResource := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	widgetKind := metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	widgetResource := metav1.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+	for _, benchmark := range []struct {
+		name string
+		body []byte
+	}{
+		{name: "pod", body: review(podKind, podResource, pod("example:v2"), pod("example:v1"))},
+		{name: "custom-resource-10KiB", body: review(widgetKind, widgetResource, widget(100), widget(99))},
+		{name: "custom-resource-1MiB", body: review(widgetKind, widgetResource, widget(10000), widget(9999))},
+	} {
+		b.Run(benchmark.name, func(b *testing.B) {
+			wh := newTestWebhook(&celValidator{})
+			b.SetBytes(int64(len(benchmark.body)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if w := serveReview(wh.handleWebhookValidate, benchmark.body); w.Code != http.StatusOK {
+					b.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
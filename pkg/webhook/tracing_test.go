@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/component-base/tracing"
+)
+
+// memoryExporter keeps the spans it exports in memory.
+type memoryExporter struct {
+	lock  sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *memoryExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// tracedValidator starts a span for the evaluation of the review.
+type tracedValidator struct{}
+
+func (tracedValidator) Handles(admission.Operation) bool { return true }
+
+func (tracedValidator) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	_, span := tracing.Start(ctx, "Evaluate policy")
+	span.End(0)
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	exporter := &memoryExporter{}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	wh := newTestWebhook(tracedValidator{})
+	wh.options.TracerProvider = provider
+
+	podKind := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podResource := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(review(podKind, podResource, pod("example:v2"), pod("example:v1"))))
+	req.Header.Set("Content-Type", "application/json")
+	// The span of the API server which sent the review.
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	wh.validateHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.spans {
+		spans[span.Name()] = span
+		if traceID := span.SpanContext().TraceID().String(); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected span %q to belong to the trace of the API server, got %s", span.Name(), traceID)
+		}
+	}
+
+	server, ok := spans["validate"]
+	if !ok {
+		t.Fatalf("expected a span of the HTTP request, got %v", spanNames(exporter.spans))
+	}
+	if parent := server.Parent().SpanID().String(); parent != "00f067aa0ba902b7" {
+		t.Errorf("expected the span of the HTTP request to be a child of the span of the API server, got %s", parent)
+	}
+	reviewSpan, ok := spans["Review"]
+	if !ok {
+		t.Fatalf("expected a Review span, got %v", spanNames(exporter.spans))
+	}
+	if reviewSpan.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("expected the Review span to be a child of the span of the HTTP request")
+	}
+
+	var children []string
+	for _, span := range exporter.spans {
+		if span.Parent().SpanID() == reviewSpan.SpanContext().SpanID() {
+			children = append(children, span.Name())
+		}
+	}
+	sort.Strings(children)
+	expected := []string{"Decode objects", "Encode AdmissionReview", "Evaluate policy", "Parse AdmissionReview"}
+	if strings.Join(children, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the children of the Review span to be %v, got %v", expected, children)
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}